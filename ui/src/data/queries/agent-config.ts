@@ -10,9 +10,19 @@ export type AgentConfig = {
   demo_mode_enabled: boolean;
 };
 
+// The value of credentials that the backend redacts. Saving a config with
+// redacted credentials keeps those of the saved config.
+export const RedactedCredential = "********";
+
+// Returns the agent config with its credentials redacted.
 export const getAgentConfig = async (ddClient: v1.DockerDesktopClient): Promise<AgentConfig> =>
   (await ddClient.extension.vm?.service?.get("/agents/config")) as AgentConfig;
 
+// Returns the agent config including its credentials. Only call this when the
+// user asks to see them.
+export const revealAgentConfig = async (ddClient: v1.DockerDesktopClient): Promise<AgentConfig> =>
+  (await ddClient.extension.vm?.service?.post("/agents/config/reveal", {})) as AgentConfig;
+
 export const createAgentConfig = async (
   ddClient: v1.DockerDesktopClient,
   config: AgentConfig
@@ -33,7 +43,13 @@ export const createAgentConfig = async (
     return value;
   });
 
-  return (await ddClient.extension.vm?.service?.post("/agents/config", data)) as AgentConfig;
+  // The backend records which client made each change in the config history.
+  return (await ddClient.extension.vm?.service?.request({
+    url: "/agents/config",
+    method: "POST",
+    headers: { "X-Akita-Change-Source": "ui" },
+    data,
+  })) as AgentConfig;
 };
 
 export const deleteAgentConfig = async (ddClient: v1.DockerDesktopClient) => {
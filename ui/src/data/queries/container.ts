@@ -54,39 +54,35 @@ export const useContainers = (predicate?: (ContainerInfo) => boolean): Container
   return containers;
 };
 
-export const getAkitaContainer = async (client: v1.DockerDesktopClient): Promise<ContainerInfo> => {
-  let inspectResult;
-  try {
-    inspectResult = await client.docker.cli.exec("inspect", [
-      "--type",
-      "container",
-      AgentContainerName,
-    ]);
-  } catch (error) {
-    console.info(
-      "Error from inspect, probably didn't find container, which happens when it has not yet started. This is likely not an issue.",
-      error
-    );
-    return undefined;
-  }
+// The status of the agent container, as reported by the backend.
+type AgentStatus = {
+  config_id: string;
+  container_id?: string;
+  state: ContainerState;
+  network_mode?: string;
+  started_at?: string;
+};
+
+const toContainerInfo = (status: AgentStatus): ContainerInfo | undefined => {
+  if (!status?.container_id || status.state === ContainerState.NONE) return undefined;
 
-  const inspectJson: Array<any> = JSON.parse(inspectResult.stdout);
-  if (inspectJson.length !== 1) {
-    throw new Error(
-      "Unexpectedly got more than one container named 'akita-docker-extension-agent'"
-    );
-  }
-  const containerInfo = inspectJson[0];
   return {
-    Id: containerInfo.Id,
-    Image: containerInfo.Image,
-    Command: (containerInfo.Config?.Cmd || []).join(" "),
-    Names: [containerInfo.Name],
-    State: containerInfo.State?.Status,
-    Labels: containerInfo?.Config?.Labels,
+    Id: status.container_id,
+    Image: AgentImageName,
+    Command: "apidump",
+    Names: [AgentContainerName],
+    State: status.state,
+    Labels: {},
   };
 };
 
+// The backend owns the lifecycle of the agent container, so the UI only asks it
+// for the status of the container and to start or stop it.
+export const getAkitaContainer = async (
+  client: v1.DockerDesktopClient
+): Promise<ContainerInfo | undefined> =>
+  toContainerInfo((await client.extension.vm?.service?.get("/agents/status")) as AgentStatus);
+
 export const startAgentWithRetry = async (
   client: v1.DockerDesktopClient,
   config: AgentConfig,
@@ -97,61 +93,39 @@ export const startAgentWithRetry = async (
     return undefined;
   });
 
-  // If the container already exists, return it.
-  if (container) return container;
+  // If the container is already running, return it.
+  if (container?.State === ContainerState.RUNNING) return container;
 
-  return retryPromise(() => startAkitaAgent(client, config), maxRetries, 1000).catch((err) =>
-    Promise.reject(err)
-  );
+  return retryPromise(() => startAkitaAgent(client, config), maxRetries, 1000);
 };
 
-/*
-TODO: In the future, we should use Docker's Go SDK to start the container instead of using the Extension API.
-This is because the Extension API does not support creating viewable containers without a user enabling viewable system containers in Docker Desktop.
-Also, the GO SDK will allow us to manage the container lifecycle even when the UI is not in focus.
-Ticket: https://app.asana.com/0/1203271196692292/1203520436365754
- */
 const startAkitaAgent = async (
   client: v1.DockerDesktopClient,
   config?: AgentConfig
 ): Promise<ContainerInfo> => {
   if (!config) return;
 
-  const container = await getAkitaContainer(client);
-  if (container) {
-    console.log("Akita agent already running. container:", container);
-    return container;
-  }
+  const status = (await client.extension.vm?.service?.request({
+    url: "/agents/start",
+    method: "POST",
+    headers: { "X-Akita-Change-Source": "ui" },
+    data: {},
+  })) as AgentStatus;
 
-  // Pull the latest image of Akita CLI
-  await client.docker.cli.exec("pull", ["public.ecr.aws/akitasoftware/akita-cli:latest"]);
-
-  // Start the Akita agent
-  const networkArg = config.target_container ? `container:${config.target_container}` : "host";
-  const runArgs = [
-    "--rm",
-    `--network ${networkArg}`,
-    `--name ${AgentContainerName}`,
-    `-e AKITA_API_KEY_ID=${config.api_key}`,
-    `-e AKITA_API_KEY_SECRET=${config.api_secret}`,
-    `${AgentImageName} apidump`,
-    `--project ${config.project_name}`,
-  ];
-  if (config.target_port) {
-    runArgs.push(`--filter "port ${config.target_port}"`);
+  const container = toContainerInfo(status);
+  if (!container) {
+    throw new Error("Akita agent container was not started");
   }
 
-  console.log("Starting Akita agent with args:", runArgs);
-
-  await client.docker.cli.exec("run", runArgs);
-
-  // Poll for agent container info using the `docker ps` command
-  return retryPromise(() => getAkitaContainer(client), 3, 2000).catch((err) => Promise.reject(err));
+  return container;
 };
 
-export const removeAkitaContainer = async (client: v1.DockerDesktopClient) => {
-  const container = await getAkitaContainer(client);
-  if (container) {
-    await client.docker.cli.exec("rm", ["-f", container.Id]);
-  }
+// Disables the agent config and removes the agent container.
+export const stopAkitaAgent = async (client: v1.DockerDesktopClient) => {
+  await client.extension.vm?.service?.request({
+    url: "/agents/stop",
+    method: "POST",
+    headers: { "X-Akita-Change-Source": "ui" },
+    data: {},
+  });
 };
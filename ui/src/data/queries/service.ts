@@ -1,4 +1,4 @@
-import { AkitaURL, addAuthHeader } from "./utils";
+import { v1 } from "@docker/extension-api-client-types";
 
 export interface Service {
   id: string;
@@ -12,22 +12,17 @@ export type DeploymentInfo = {
   name: string;
 };
 
-export interface ServiceResponse {
-  services: Service[];
-  status: number;
-  ok: boolean;
-}
-
-export const getServices = async (apiKey: string, apiSecret: string): Promise<ServiceResponse> => {
-  const headers = new Headers();
-  addAuthHeader(headers, apiKey, apiSecret);
+// The backend calls the Akita API with the credentials of the saved agent config.
+export const getServices = async (ddClient: v1.DockerDesktopClient): Promise<Service[]> =>
+  ((await ddClient.extension.vm?.service?.get("/agents/services")) as Service[]) ?? [];
 
-  const response = await fetch(`${AkitaURL}/v1/services`, { headers });
-  const body = await response.json();
-
-  return {
-    services: response.ok ? (body as Service[]) : [],
-    status: response.status,
-    ok: response.ok,
-  };
-};
+// Lists the services of the given credentials, e.g. to check them before they are saved.
+export const getServicesForCredentials = async (
+  ddClient: v1.DockerDesktopClient,
+  apiKey: string,
+  apiSecret: string
+): Promise<Service[]> =>
+  ((await ddClient.extension.vm?.service?.post("/credentials/services", {
+    api_key: apiKey,
+    api_secret: apiSecret,
+  })) as Service[]) ?? [];
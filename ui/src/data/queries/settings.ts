@@ -0,0 +1,26 @@
+import { v1 } from "@docker/extension-api-client-types";
+
+export type TelemetryConsent = "undecided" | "granted" | "denied";
+
+export type TelemetrySettings = {
+  consent: TelemetryConsent;
+  decided_at?: string;
+};
+
+// Analytics are only sent once the user has granted consent.
+export const getTelemetrySettings = async (
+  ddClient: v1.DockerDesktopClient
+): Promise<TelemetrySettings> =>
+  (await ddClient.extension.vm?.service?.get("/settings/telemetry")) as TelemetrySettings;
+
+// Withdrawing consent purges the analytics events that haven't been sent yet.
+export const updateTelemetrySettings = async (
+  ddClient: v1.DockerDesktopClient,
+  consent: Exclude<TelemetryConsent, "undecided">
+): Promise<TelemetrySettings> =>
+  (await ddClient.extension.vm?.service?.request({
+    url: "/settings/telemetry",
+    method: "PUT",
+    headers: {},
+    data: JSON.stringify({ consent }),
+  })) as TelemetrySettings;
@@ -1,31 +1,12 @@
-import { AkitaURL, addAuthHeader } from "./utils";
+import { v1 } from "@docker/extension-api-client-types";
 
 export interface User {
   organization_id: string;
   name: string;
-  id: string;
   email: string;
   created_at: Date;
 }
 
-export interface UserResponse {
-  user?: User;
-  status: number;
-  message?: string;
-  ok: boolean;
-}
-
-export const getAkitaUser = async (apiKey: string, apiSecret: string): Promise<UserResponse> => {
-  const headers = new Headers();
-  addAuthHeader(headers, apiKey, apiSecret);
-
-  const response = await fetch(`${AkitaURL}/v1/user`, { headers });
-  const body = await response.json();
-
-  return {
-    user: response.ok ? (body as User) : undefined,
-    message: body.message,
-    status: response.status,
-    ok: response.ok,
-  };
-};
+// The backend calls the Akita API with the credentials of the saved agent config.
+export const getAkitaUser = async (ddClient: v1.DockerDesktopClient): Promise<User> =>
+  (await ddClient.extension.vm?.service?.get("/agents/user")) as User;
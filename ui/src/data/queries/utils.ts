@@ -1,8 +1,2 @@
-import { encode } from "base-64";
-
-export const AkitaURL = "https://api.akita.software";
-
-export const addAuthHeader = (headers: Headers, apiKey: string, apiSecret: string) => {
-  const authToken = encode(`${apiKey}:${apiSecret}`);
-  headers.append("Authorization", `Basic ${authToken}`);
-};
+// Returns true if the given error is a response of the backend with the given status.
+export const hasStatusCode = (e: any, statusCode: number): boolean => e?.statusCode === statusCode;
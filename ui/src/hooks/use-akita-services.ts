@@ -11,14 +11,8 @@ export const useAkitaServices = (pollInterval: number, config?: AgentConfig): Se
     if (!config) return;
 
     const fetchServices = () => {
-      getServices(config.api_key, config.api_secret)
-        .then((response) => {
-          if (response.ok) {
-            setServices(response.services);
-          } else {
-            ddClient.desktopUI.toast.error(`Failed to fetch services: ${response.status}`);
-          }
-        })
+      getServices(ddClient)
+        .then(setServices)
         .catch((e) => ddClient.desktopUI.toast.error(`Failed to fetch services: ${e.message}`));
     };
 
@@ -1,6 +1,7 @@
 import { useCallback, useEffect, useState } from "react";
 import { postAnalyticsEvent } from "../data/queries/event";
 import { User, getAkitaUser } from "../data/queries/user";
+import { hasStatusCode } from "../data/queries/utils";
 import { useAgentConfig } from "./use-agent-config";
 import { useDockerDesktopClient } from "./use-docker-desktop-client";
 
@@ -16,25 +17,18 @@ export const useAkitaUser: () => {
 
   useEffect(() => {
     if (config) {
-      getAkitaUser(config.api_key, config.api_secret)
-        .then((response) => {
-          if (response.ok) {
-            setUser(response.user);
-            return;
-          }
-
-          if (response.status === 401) {
+      getAkitaUser(ddClient)
+        .then(setUser)
+        .catch((e) => {
+          if (hasStatusCode(e, 401)) {
             setIsUnauthorized(true);
             return;
           }
 
-          return Promise.reject(
-            new Error(`Unexpected response: ${response.status}. message: ${response.message}`)
-          );
-        })
-        .catch((e) => console.error(e));
+          console.error(e);
+        });
     }
-  }, [config]);
+  }, [ddClient, config]);
 
   const sendAnalyticsEvent = useCallback(
     (eventName: string, properties?: Record<string, any>) => {
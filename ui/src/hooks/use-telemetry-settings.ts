@@ -0,0 +1,35 @@
+import { useCallback, useEffect, useState } from "react";
+import {
+  TelemetryConsent,
+  TelemetrySettings,
+  getTelemetrySettings,
+  updateTelemetrySettings,
+} from "../data/queries/settings";
+import { useDockerDesktopClient } from "./use-docker-desktop-client";
+
+export const useTelemetrySettings: () => {
+  telemetry?: TelemetrySettings;
+  updateConsent: (consent: Exclude<TelemetryConsent, "undecided">) => void;
+} = () => {
+  const ddClient = useDockerDesktopClient();
+  const [telemetry, setTelemetry] = useState<TelemetrySettings | undefined>(undefined);
+
+  useEffect(() => {
+    getTelemetrySettings(ddClient)
+      .then(setTelemetry)
+      .catch((e) => console.error(e));
+  }, [ddClient]);
+
+  const updateConsent = useCallback(
+    (consent: Exclude<TelemetryConsent, "undecided">) => {
+      updateTelemetrySettings(ddClient, consent)
+        .then(setTelemetry)
+        .catch((e) =>
+          ddClient.desktopUI.toast.error(`Failed to save usage analytics settings: ${e.message}`)
+        );
+    },
+    [ddClient]
+  );
+
+  return { telemetry, updateConsent };
+};
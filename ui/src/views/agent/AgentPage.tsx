@@ -2,7 +2,7 @@ import { Stack } from "@mui/material";
 import React, { useEffect, useRef } from "react";
 import { useNavigate } from "react-router-dom";
 import { AgentConfig, createAgentConfig, deleteAgentConfig } from "../../data/queries/agent-config";
-import { removeAkitaContainer } from "../../data/queries/container";
+import { stopAkitaAgent } from "../../data/queries/container";
 import { useAkitaAgent } from "../../hooks/use-akita-agent";
 import { useAkitaServices } from "../../hooks/use-akita-services";
 import { useAkitaUser } from "../../hooks/use-akita-user";
@@ -26,7 +26,7 @@ export const AgentPage = () => {
   useEffect(() => {
     if (isUnauthorized) {
       deleteAgentConfig(ddClient)
-        .then(() => removeAkitaContainer(ddClient))
+        .then(() => stopAkitaAgent(ddClient))
         .then(() =>
           ddClient.desktopUI.toast.error("Akita API key is invalid. Please re-authenticate.")
         )
@@ -62,8 +62,7 @@ export const AgentPage = () => {
 
   const handleFailure = (err: any) => {
     sendAnalyticsEvent("Agent Failed to Start", { errorMessage: err?.message });
-    createAgentConfig(ddClient, { ...config, enabled: false })
-      .then(() => removeAkitaContainer(ddClient))
+    stopAkitaAgent(ddClient)
       .then(() => ddClient.desktopUI.toast.error("Failed to start Akita Agent."))
       .then(() => navigate("/"))
       .catch(() =>
@@ -74,8 +73,8 @@ export const AgentPage = () => {
   };
 
   const handleConfigChange = (config: AgentConfig) => {
+    // The backend recreates the agent container when its config changes.
     createAgentConfig(ddClient, config)
-      .then(() => removeAkitaContainer(ddClient))
       .then(() => restartAgent())
       .then(() => navigate("/"))
       .catch(handleFailure);
@@ -11,8 +11,8 @@ import {
 } from "@mui/material";
 import React, { useEffect, useState } from "react";
 import { useNavigate } from "react-router-dom";
-import { AgentConfig, createAgentConfig } from "../../../data/queries/agent-config";
-import { removeAkitaContainer } from "../../../data/queries/container";
+import { AgentConfig } from "../../../data/queries/agent-config";
+import { stopAkitaAgent } from "../../../data/queries/container";
 import { useDockerDesktopClient } from "../../../hooks/use-docker-desktop-client";
 import { BaseHeader } from "../../shared/components/BaseHeader";
 
@@ -40,8 +40,7 @@ export const AgentHeader = ({
 
   const onStopClicked = () => {
     onSendAnalyticsEvent("Stopped Agent");
-    createAgentConfig(ddClient, { ...agentConfig, enabled: false, demo_mode_enabled: false })
-      .then(() => removeAkitaContainer(ddClient))
+    stopAkitaAgent(ddClient)
       .then(() => navigate("/"))
       .catch((e) => {
         ddClient.desktopUI.toast.error(`Failed to stop Akita container: ${e.message}`);
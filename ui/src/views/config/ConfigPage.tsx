@@ -15,8 +15,14 @@ import React, { useEffect, useState } from "react";
 import { useNavigate } from "react-router-dom";
 import darkAkitaLogo from "../../assets/img/akita_logo_dark.svg";
 import lightAkitaLogo from "../../assets/img/akita_logo_light.svg";
-import { AgentConfig, createAgentConfig } from "../../data/queries/agent-config";
-import { getServices } from "../../data/queries/service";
+import {
+  AgentConfig,
+  RedactedCredential,
+  createAgentConfig,
+  revealAgentConfig,
+} from "../../data/queries/agent-config";
+import { Service, getServices, getServicesForCredentials } from "../../data/queries/service";
+import { hasStatusCode } from "../../data/queries/utils";
 import { useAgentConfig } from "../../hooks/use-agent-config";
 import { useDockerDesktopClient } from "../../hooks/use-docker-desktop-client";
 import { BaseHeader } from "../shared/components/BaseHeader";
@@ -57,6 +63,9 @@ const initialConfigInputState: ConfigInputState = {
 const isConfigInputStateValid = (state: ConfigInputState) =>
   state.apiKey !== "" && state.apiSecret !== "" && state.projectName !== "";
 
+const hasRedactedCredentials = (state: ConfigInputState) =>
+  state.apiKey === RedactedCredential || state.apiSecret === RedactedCredential;
+
 const mapInputToAgentConfig = (input: ConfigInputState): AgentConfig => ({
   api_key: input.apiKey,
   api_secret: input.apiSecret,
@@ -76,6 +85,7 @@ export const ConfigPage = () => {
   const [isInvalidProjectName, setIsInvalidProjectName] = useState(false);
 
   // If the agent config has already been set, pre-populate the form with the existing values.
+  // Its credentials are redacted until the user asks to see them.
   useEffect(() => {
     if (agentConfig) {
       setConfigInput({
@@ -88,30 +98,25 @@ export const ConfigPage = () => {
     }
   }, [agentConfig]);
 
-  // TODO: Validation doesn't work in dev mode because of CORS. We should probably add an env var to account for this.
-  // As a hacky workaround, you can comment out any checks in this function and just return true.
+  // The backend checks the credentials against the Akita API. Redacted
+  // credentials are those of the saved config.
   const validateSubmission = async () => {
-    const serviceResponse = await getServices(configInput.apiKey, configInput.apiSecret).catch(
-      (err) => {
-        ddClient.desktopUI.toast.error(`Failed to fetch Akita projects: ${err.message}`);
-        return undefined;
+    let services: Service[];
+    try {
+      services = hasRedactedCredentials(configInput)
+        ? await getServices(ddClient)
+        : await getServicesForCredentials(ddClient, configInput.apiKey, configInput.apiSecret);
+    } catch (e: any) {
+      if (hasStatusCode(e, 401)) {
+        setIsInvalidAPICredentials(true);
+        ddClient.desktopUI.toast.error("Invalid API credentials");
+      } else {
+        ddClient.desktopUI.toast.error(`Failed to fetch Akita projects: ${e.message}`);
       }
-    );
-
-    if (!serviceResponse) return false;
-
-    if (serviceResponse.status === 401) {
-      setIsInvalidAPICredentials(true);
-      ddClient.desktopUI.toast.error("Invalid API credentials");
       return false;
     }
 
-    if (!serviceResponse.ok) {
-      ddClient.desktopUI.toast.error(`Failed to fetch Akita projects: ${serviceResponse.status}`);
-      return false;
-    }
-
-    if (!serviceResponse.services.some((service) => service.name === configInput.projectName)) {
+    if (!services.some((service) => service.name === configInput.projectName)) {
       setIsInvalidProjectName(true);
       ddClient.desktopUI.toast.error(`Project ${configInput.projectName} does not exist`);
       return false;
@@ -142,16 +147,35 @@ export const ConfigPage = () => {
   };
 
   const handleInputChange = (event: React.ChangeEvent<HTMLInputElement>) => {
-    if (event.target.name === "apiKey" || event.target.name === "apiSecret") {
+    const { name, value } = event.target;
+    if (name === "apiKey" || name === "apiSecret") {
       setIsInvalidAPICredentials(false);
+      // Editing a redacted credential replaces both, so that the key and secret
+      // that are checked belong together.
+      if (hasRedactedCredentials(configInput)) {
+        setConfigInput({
+          ...configInput,
+          apiKey: "",
+          apiSecret: "",
+          [name]: value.replace(RedactedCredential, ""),
+        });
+        return;
+      }
     }
-    if (event.target.name === "projectName") {
+    if (name === "projectName") {
       setIsInvalidProjectName(false);
     }
-    const { name, value } = event.target;
     setConfigInput({ ...configInput, [name]: value });
   };
 
+  const handleRevealClick = () => {
+    revealAgentConfig(ddClient)
+      .then((config) =>
+        setConfigInput({ ...configInput, apiKey: config.api_key, apiSecret: config.api_secret })
+      )
+      .catch((e) => ddClient.desktopUI.toast.error(`Failed to reveal credentials: ${e.message}`));
+  };
+
   const handleSignupClick = () => {
     ddClient.host.openExternal(
       "https://app.akita.software/login?sign_up&docker_desktop&utm_source=docker&utm_medium=link&utm_campaign=beta_from_docker"
@@ -211,6 +235,11 @@ export const ConfigPage = () => {
                     value={configInput.apiSecret}
                     onChange={handleInputChange}
                   />
+                  {hasRedactedCredentials(configInput) && (
+                    <Link onClick={handleRevealClick} sx={{ cursor: "pointer" }}>
+                      Show API key and secret
+                    </Link>
+                  )}
                   <Button
                     disabled={!isSubmitEnabled}
                     variant="contained"
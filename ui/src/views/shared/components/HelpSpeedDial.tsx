@@ -1,10 +1,13 @@
 import ArticleOutlinedIcon from "@mui/icons-material/ArticleOutlined";
 import ForumOutlinedIcon from "@mui/icons-material/ForumOutlined";
 import HelpOutlineIcon from "@mui/icons-material/HelpOutlined";
+import QueryStatsOutlinedIcon from "@mui/icons-material/QueryStatsOutlined";
 import { SpeedDial, SpeedDialAction, Theme } from "@mui/material";
 import { SxProps } from "@mui/system";
-import React, { MouseEvent, ReactNode } from "react";
+import React, { MouseEvent, ReactNode, useEffect, useState } from "react";
 import { useDockerDesktopClient } from "../../../hooks/use-docker-desktop-client";
+import { useTelemetrySettings } from "../../../hooks/use-telemetry-settings";
+import { TelemetryDialog } from "./TelemetryDialog";
 
 export interface HelpSpeedDialProps {
   sx?: SxProps<Theme>;
@@ -18,6 +21,15 @@ interface Action {
 
 export const HelpSpeedDial = ({ sx }: HelpSpeedDialProps) => {
   const ddClient = useDockerDesktopClient();
+  const { telemetry, updateConsent } = useTelemetrySettings();
+  const [isTelemetryDialogOpen, setIsTelemetryDialogOpen] = useState(false);
+
+  // Ask for consent to usage analytics until the user decides.
+  useEffect(() => {
+    if (telemetry?.consent === "undecided") {
+      setIsTelemetryDialogOpen(true);
+    }
+  }, [telemetry]);
 
   const actions: Action[] = [
     {
@@ -34,6 +46,11 @@ export const HelpSpeedDial = ({ sx }: HelpSpeedDialProps) => {
         ddClient.host.openExternal("https://docs.akita.software/docs/docker-extension");
       },
     },
+    {
+      icon: <QueryStatsOutlinedIcon />,
+      name: "Usage Analytics",
+      operation: () => setIsTelemetryDialogOpen(true),
+    },
   ];
 
   const handleActionClick = (event: MouseEvent<HTMLDivElement>, action: Action) => {
@@ -52,6 +69,12 @@ export const HelpSpeedDial = ({ sx }: HelpSpeedDialProps) => {
           />
         ))}
       </SpeedDial>
+      <TelemetryDialog
+        isOpen={isTelemetryDialogOpen}
+        telemetry={telemetry}
+        onDecide={updateConsent}
+        onCloseDialog={() => setIsTelemetryDialogOpen(false)}
+      />
     </>
   );
 };
@@ -0,0 +1,67 @@
+import {
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogContentText,
+  DialogTitle,
+} from "@mui/material";
+import React from "react";
+import { TelemetryConsent, TelemetrySettings } from "../../../data/queries/settings";
+
+interface TelemetryDialogProps {
+  isOpen: boolean;
+  telemetry?: TelemetrySettings;
+  onDecide: (consent: Exclude<TelemetryConsent, "undecided">) => void;
+  onCloseDialog: () => void;
+}
+
+const describeConsent = (telemetry?: TelemetrySettings) => {
+  switch (telemetry?.consent) {
+    case "granted":
+      return "You currently share usage analytics.";
+    case "denied":
+      return "You currently don't share usage analytics.";
+    default:
+      return "Nothing is shared until you decide.";
+  }
+};
+
+// Asks the user whether usage analytics may be shared, and lets them change
+// their decision later.
+export const TelemetryDialog = ({
+  isOpen,
+  telemetry,
+  onDecide,
+  onCloseDialog,
+}: TelemetryDialogProps) => {
+  const handleDecision = (consent: Exclude<TelemetryConsent, "undecided">) => {
+    onDecide(consent);
+    onCloseDialog();
+  };
+
+  return (
+    <Dialog open={isOpen} onClose={onCloseDialog} fullWidth={true}>
+      <DialogTitle>Usage Analytics</DialogTitle>
+      <DialogContent>
+        <DialogContentText>
+          Help us improve the Akita extension by sharing which features you use, such as starting
+          the agent or toggling demo mode. Your email is never shared, and emails, IP addresses and
+          container names are removed from the events.
+        </DialogContentText>
+        <DialogContentText marginTop={2}>
+          {describeConsent(telemetry)} Withdrawing your consent deletes the events that haven't
+          been sent yet.
+        </DialogContentText>
+      </DialogContent>
+      <DialogActions>
+        <Button variant={"outlined"} onClick={() => handleDecision("denied")}>
+          Don&apos;t Share
+        </Button>
+        <Button variant={"contained"} color={"primary"} onClick={() => handleDecision("granted")}>
+          Share Usage Analytics
+        </Button>
+      </DialogActions>
+    </Dialog>
+  );
+};
@@ -5,20 +5,48 @@ import (
 	"akita/domain/agent"
 	"akita/domain/container"
 	"akita/domain/demo"
+	"akita/domain/event"
+	"akita/domain/health"
 	"akita/domain/host"
+	"akita/domain/service"
+	"akita/domain/settings"
 	"akita/domain/user"
-	"github.com/akitasoftware/akita-libs/analytics"
+	"github.com/sirupsen/logrus"
 )
 
 type (
 	// Represents all interactions that can be performed within the application.
 	Interactors struct {
 		*interactor.RetrieveAgentConfig
+		*interactor.ListAgentConfigs
+		*interactor.RevealAgentConfig
+		*interactor.ValidateAgentConfig
 		*interactor.SaveAgentConfig
 		*interactor.RemoveAgentConfig
+		*interactor.RetrieveAgentConfigHistory
+		*interactor.RollbackAgentConfig
 		*interactor.RecordUserAnalytics
+		*interactor.DeliverAnalyticsEvents
+		*interactor.RetrieveAnalyticsQueueStatus
+		*interactor.ListRecordedAnalyticsEvents
+		*interactor.RetrieveTelemetrySettings
+		*interactor.SaveTelemetrySettings
 		*interactor.SaveHostDetails
 		*interactor.SendDemoTraffic
+		*interactor.StartAgent
+		*interactor.StopAgent
+		*interactor.RetrieveAgentStatus
+		*interactor.ReconcileAgent
+		*interactor.RetrieveAgentDecisions
+		*interactor.WatchTargetContainer
+		*interactor.ListContainers
+		*interactor.VerifyCredentials
+		*interactor.RetrieveAkitaUser
+		*interactor.ListAkitaServices
+		*interactor.RetrieveAkitaAPIHealth
+		*interactor.RetrieveWorkerHealth
+		*interactor.RetrieveLiveness
+		*interactor.RetrieveReadiness
 	}
 	// Entry point for application logic and use case interactions.
 	App struct {
@@ -28,26 +56,86 @@ type (
 
 func New(
 	agentRepo agent.Repository,
+	agentContainerRepo agent.ContainerRepository,
+	decisionRepo agent.DecisionRepository,
 	hostRepo host.Repository,
 	containerRepo container.Repository,
+	containerWatcher container.Watcher,
 	userRepo user.Repository,
+	serviceRepo service.Repository,
 	demoRepo demo.DemoRepository,
-	analyticsClient analytics.Client,
+	settingsRepo settings.Repository,
+	outboxRepo event.OutboxRepository,
+	recordedEventRepo event.RecordedEventRepository,
+	analyticsSender event.Sender,
+	analyticsDeliveryPolicy interactor.AnalyticsDeliveryPolicy,
+	workerMonitor health.WorkerMonitor,
+	dependencyCheckers []health.Checker,
+	logger *logrus.Logger,
 ) *App {
-	retrieveAgentInteractor := interactor.NewRetrieveAgentConfigInteractor(agentRepo, containerRepo, userRepo)
+	reconcileAgentInteractor := interactor.NewReconcileAgentInteractor(
+		agentRepo,
+		agentContainerRepo,
+		decisionRepo,
+		containerRepo,
+		userRepo,
+		logger,
+	)
+	listAgentConfigsInteractor := interactor.NewListAgentConfigsInteractor(agentRepo, reconcileAgentInteractor)
+	validateAgentConfigInteractor := interactor.NewValidateAgentConfigInteractor(agentRepo, containerRepo, userRepo)
+	saveAgentConfigInteractor := interactor.NewSaveAgentConfigInteractor(agentRepo, userRepo, validateAgentConfigInteractor)
 	return &App{
 		Interactors: Interactors{
-			RetrieveAgentConfig: retrieveAgentInteractor,
-			SaveAgentConfig:     interactor.NewSaveAgentConfigInteractor(agentRepo, containerRepo, userRepo),
-			RemoveAgentConfig:   interactor.NewRemoveAgentConfigInteractor(agentRepo),
+			RetrieveAgentConfig:        interactor.NewRetrieveAgentConfigInteractor(agentRepo, reconcileAgentInteractor),
+			ListAgentConfigs:           listAgentConfigsInteractor,
+			RevealAgentConfig:          interactor.NewRevealAgentConfigInteractor(agentRepo),
+			ValidateAgentConfig:        validateAgentConfigInteractor,
+			SaveAgentConfig:            saveAgentConfigInteractor,
+			RemoveAgentConfig:          interactor.NewRemoveAgentConfigInteractor(agentRepo, userRepo),
+			RetrieveAgentConfigHistory: interactor.NewRetrieveAgentConfigHistoryInteractor(agentRepo),
+			RollbackAgentConfig: interactor.NewRollbackAgentConfigInteractor(
+				agentRepo,
+				saveAgentConfigInteractor,
+			),
 			RecordUserAnalytics: interactor.NewRecordUserAnalyticsInteractor(
-				analyticsClient,
+				outboxRepo,
 				hostRepo,
 				userRepo,
 				agentRepo,
+				settingsRepo,
+			),
+			DeliverAnalyticsEvents: interactor.NewDeliverAnalyticsEventsInteractor(
+				outboxRepo,
+				settingsRepo,
+				analyticsSender,
+				analyticsDeliveryPolicy,
+				logger,
+			),
+			RetrieveAnalyticsQueueStatus: interactor.NewRetrieveAnalyticsQueueStatusInteractor(outboxRepo),
+			ListRecordedAnalyticsEvents:  interactor.NewListRecordedAnalyticsEventsInteractor(recordedEventRepo),
+			RetrieveTelemetrySettings:    interactor.NewRetrieveTelemetrySettingsInteractor(settingsRepo),
+			SaveTelemetrySettings:        interactor.NewSaveTelemetrySettingsInteractor(settingsRepo, outboxRepo, logger),
+			SaveHostDetails:              interactor.NewSaveHostDetailsInteractor(hostRepo),
+			SendDemoTraffic:              interactor.NewSendDemoTrafficInteractor(agentRepo, demoRepo),
+			StartAgent:                   interactor.NewStartAgentInteractor(agentRepo, agentContainerRepo, containerRepo),
+			StopAgent:                    interactor.NewStopAgentInteractor(agentRepo, agentContainerRepo),
+			RetrieveAgentStatus:          interactor.NewRetrieveAgentStatusInteractor(agentContainerRepo),
+			ReconcileAgent:               reconcileAgentInteractor,
+			RetrieveAgentDecisions:       interactor.NewRetrieveAgentDecisionsInteractor(decisionRepo),
+			WatchTargetContainer: interactor.NewWatchTargetContainerInteractor(
+				agentRepo,
+				containerWatcher,
+				reconcileAgentInteractor,
+				logger,
 			),
-			SaveHostDetails: interactor.NewSaveHostDetailsInteractor(hostRepo),
-			SendDemoTraffic: interactor.NewSendDemoTrafficInteractor(retrieveAgentInteractor, demoRepo),
+			ListContainers:         interactor.NewListContainersInteractor(containerRepo),
+			VerifyCredentials:      interactor.NewVerifyCredentialsInteractor(userRepo),
+			RetrieveAkitaUser:      interactor.NewRetrieveAkitaUserInteractor(agentRepo, userRepo),
+			ListAkitaServices:      interactor.NewListAkitaServicesInteractor(agentRepo, serviceRepo),
+			RetrieveAkitaAPIHealth: interactor.NewRetrieveAkitaAPIHealthInteractor(userRepo),
+			RetrieveWorkerHealth:   interactor.NewRetrieveWorkerHealthInteractor(workerMonitor),
+			RetrieveLiveness:       interactor.NewRetrieveLivenessInteractor(),
+			RetrieveReadiness:      interactor.NewRetrieveReadinessInteractor(dependencyCheckers),
 		},
 	}
 }
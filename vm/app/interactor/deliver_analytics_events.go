@@ -0,0 +1,163 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"akita/domain/settings"
+	"akita/metrics"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// Configures the delivery of queued analytics events.
+type AnalyticsDeliveryPolicy struct {
+	// The most events delivered by a single run, which are sent as one batch.
+	BatchSize int
+	// Events that were raised longer ago are dropped instead of delivered.
+	MaxAge time.Duration
+	// The backoff after the first failed delivery of an event. It doubles with each failure.
+	RetryWaitTime time.Duration
+	// The longest backoff between deliveries of an event.
+	RetryMaxWaitTime time.Duration
+}
+
+type DeliverAnalyticsEvents struct {
+	outboxRepo   event.OutboxRepository
+	settingsRepo settings.Repository
+	sender       event.Sender
+	policy       AnalyticsDeliveryPolicy
+	logger       *logrus.Logger
+}
+
+func NewDeliverAnalyticsEventsInteractor(
+	outboxRepo event.OutboxRepository,
+	settingsRepo settings.Repository,
+	sender event.Sender,
+	policy AnalyticsDeliveryPolicy,
+	logger *logrus.Logger,
+) *DeliverAnalyticsEvents {
+	return &DeliverAnalyticsEvents{
+		outboxRepo:   outboxRepo,
+		settingsRepo: settingsRepo,
+		sender:       sender,
+		policy:       policy,
+		logger:       logger,
+	}
+}
+
+// Delivers a batch of the queued analytics events that are due, oldest first.
+// Events are only removed from the outbox once the batch has been accepted;
+// if it fails, its events are retried with backoff by later runs. Events
+// older than the maximum age, or discarded because sending is disabled or the
+// user no longer consents to it, are dropped. Runs that delivered, retried or dropped events are recorded as the
+// last delivery.
+func (d DeliverAnalyticsEvents) Handle(ctx context.Context) (*event.DeliveryReport, error) {
+	queuedEvents, err := d.outboxRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	report := &event.DeliveryReport{StartedAt: now}
+
+	var batch []*event.QueuedEvent
+	for _, queuedEvent := range queuedEvents {
+		if len(batch) >= d.policy.BatchSize {
+			break
+		}
+
+		if now.Sub(queuedEvent.EnqueuedAt) > d.policy.MaxAge {
+			if err := d.outboxRepo.Remove(ctx, queuedEvent.ID); err != nil {
+				return nil, err
+			}
+			report.Dropped++
+			continue
+		}
+
+		if queuedEvent.NextAttemptAt.After(now) {
+			continue
+		}
+
+		batch = append(batch, queuedEvent)
+	}
+
+	if len(batch) > 0 {
+		if err := d.deliver(ctx, batch, now, report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	metrics.AnalyticsEvents.WithLabelValues("delivered").Add(float64(report.Delivered))
+	metrics.AnalyticsEvents.WithLabelValues("failed").Add(float64(report.Failed))
+	metrics.AnalyticsEvents.WithLabelValues("dropped").Add(float64(report.Dropped))
+
+	if report.Delivered+report.Failed+report.Dropped > 0 {
+		if err := d.outboxRepo.RecordDelivery(ctx, report); err != nil {
+			d.logger.WithContext(ctx).Warnf("Failed to record analytics delivery: %s", err)
+		}
+	}
+
+	return report, nil
+}
+
+// Sends the batch and updates the outbox and the report with the outcome.
+func (d DeliverAnalyticsEvents) deliver(
+	ctx context.Context,
+	batch []*event.QueuedEvent,
+	now time.Time,
+	report *event.DeliveryReport,
+) error {
+	sendErr := d.send(ctx, batch)
+	if sendErr != nil && !errors.Is(sendErr, event.ErrSendingDisabled) {
+		for _, queuedEvent := range batch {
+			queuedEvent.Attempts++
+			queuedEvent.LastError = sendErr.Error()
+			queuedEvent.NextAttemptAt = now.Add(d.backoff(queuedEvent.Attempts))
+			if err := d.outboxRepo.Update(ctx, queuedEvent); err != nil {
+				return err
+			}
+		}
+
+		report.Failed += len(batch)
+		report.LastError = sendErr.Error()
+		return nil
+	}
+
+	for _, queuedEvent := range batch {
+		if err := d.outboxRepo.Remove(ctx, queuedEvent.ID); err != nil {
+			return err
+		}
+	}
+
+	if sendErr != nil {
+		report.Dropped += len(batch)
+	} else {
+		report.Delivered += len(batch)
+	}
+	return nil
+}
+
+// Sends the batch, unless the user withdrew their consent after its events
+// were queued, in which case event.ErrSendingDisabled is returned.
+func (d DeliverAnalyticsEvents) send(ctx context.Context, batch []*event.QueuedEvent) error {
+	telemetry, err := d.settingsRepo.GetTelemetry(ctx)
+	if err != nil {
+		return err
+	}
+	if !telemetry.IsAllowed() {
+		return event.ErrSendingDisabled
+	}
+
+	return d.sender.Send(ctx, batch)
+}
+
+// Returns how long to wait before delivering an event that failed the given number of times.
+func (d DeliverAnalyticsEvents) backoff(attempts int) time.Duration {
+	wait := d.policy.RetryWaitTime << (attempts - 1)
+	if wait <= 0 || wait > d.policy.RetryMaxWaitTime {
+		return d.policy.RetryMaxWaitTime
+	}
+	return wait
+}
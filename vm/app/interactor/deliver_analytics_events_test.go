@@ -0,0 +1,139 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"akita/domain/settings"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A sender that records the batches it is given and fails with err.
+type fakeSender struct {
+	err     error
+	batches [][]*event.QueuedEvent
+}
+
+func (f *fakeSender) Send(_ context.Context, queuedEvents []*event.QueuedEvent) error {
+	f.batches = append(f.batches, queuedEvents)
+	return f.err
+}
+
+func TestDeliverAnalyticsEvents_Handle(t *testing.T) {
+	policy := AnalyticsDeliveryPolicy{
+		BatchSize:        2,
+		MaxAge:           time.Hour,
+		RetryWaitTime:    time.Second,
+		RetryMaxWaitTime: time.Minute,
+	}
+
+	now := time.Now().UTC()
+	due := func(id string) *event.QueuedEvent {
+		return &event.QueuedEvent{ID: id, EnqueuedAt: now.Add(-time.Minute), NextAttemptAt: now.Add(-time.Second)}
+	}
+	notDue := &event.QueuedEvent{ID: "not-due", EnqueuedAt: now.Add(-time.Minute), NextAttemptAt: now.Add(time.Hour)}
+	expired := &event.QueuedEvent{ID: "expired", EnqueuedAt: now.Add(-2 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		queued []*event.QueuedEvent
+		// Granted unless set.
+		consent       settings.Consent
+		sendErr       error
+		wantBatches   [][]string
+		wantRemaining []string
+		wantReport    event.DeliveryReport
+	}{
+		{
+			name:          "delivers one batch of due events",
+			queued:        []*event.QueuedEvent{due("a"), notDue, due("b"), due("c")},
+			wantBatches:   [][]string{{"a", "b"}},
+			wantRemaining: []string{"not-due", "c"},
+			wantReport:    event.DeliveryReport{Delivered: 2},
+		},
+		{
+			name:          "keeps events of a failed batch",
+			queued:        []*event.QueuedEvent{due("a"), due("b")},
+			sendErr:       errors.New("503 Service Unavailable"),
+			wantBatches:   [][]string{{"a", "b"}},
+			wantRemaining: []string{"a", "b"},
+			wantReport:    event.DeliveryReport{Failed: 2, LastError: "503 Service Unavailable"},
+		},
+		{
+			name:          "drops events while sending is disabled",
+			queued:        []*event.QueuedEvent{due("a")},
+			sendErr:       event.ErrSendingDisabled,
+			wantBatches:   [][]string{{"a"}},
+			wantRemaining: []string{},
+			wantReport:    event.DeliveryReport{Dropped: 1},
+		},
+		{
+			name:          "drops events without sending them once consent is withdrawn",
+			queued:        []*event.QueuedEvent{due("a")},
+			consent:       settings.ConsentDenied,
+			wantRemaining: []string{},
+			wantReport:    event.DeliveryReport{Dropped: 1},
+		},
+		{
+			name:          "drops expired events without sending them",
+			queued:        []*event.QueuedEvent{expired, notDue},
+			wantRemaining: []string{"not-due"},
+			wantReport:    event.DeliveryReport{Dropped: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outboxRepo := &fakeOutboxRepo{events: tt.queued}
+			sender := &fakeSender{err: tt.sendErr}
+			consent := tt.consent
+			if consent == "" {
+				consent = settings.ConsentGranted
+			}
+			settingsRepo := &fakeSettingsRepo{telemetry: &settings.Telemetry{Consent: consent}}
+			interactor := NewDeliverAnalyticsEventsInteractor(outboxRepo, settingsRepo, sender, policy, newTestLogger())
+
+			report, err := interactor.Handle(context.Background())
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if got := eventIDBatches(sender.batches); !reflect.DeepEqual(got, tt.wantBatches) {
+				t.Errorf("sent batches %v, want %v", got, tt.wantBatches)
+			}
+			if got := eventIDs(outboxRepo.events); !reflect.DeepEqual(got, tt.wantRemaining) {
+				t.Errorf("remaining events %v, want %v", got, tt.wantRemaining)
+			}
+			if report.Delivered != tt.wantReport.Delivered ||
+				report.Failed != tt.wantReport.Failed ||
+				report.Dropped != tt.wantReport.Dropped ||
+				report.LastError != tt.wantReport.LastError {
+				t.Errorf("report = %+v, want %+v", *report, tt.wantReport)
+			}
+
+			for _, queuedEvent := range outboxRepo.events {
+				if tt.sendErr != nil && queuedEvent.ID != "not-due" && !queuedEvent.NextAttemptAt.After(time.Now()) {
+					t.Errorf("event %s is due again immediately after a failed delivery", queuedEvent.ID)
+				}
+			}
+		})
+	}
+}
+
+func eventIDs(queuedEvents []*event.QueuedEvent) []string {
+	ids := []string{}
+	for _, queuedEvent := range queuedEvents {
+		ids = append(ids, queuedEvent.ID)
+	}
+	return ids
+}
+
+func eventIDBatches(batches [][]*event.QueuedEvent) [][]string {
+	var result [][]string
+	for _, batch := range batches {
+		result = append(result, eventIDs(batch))
+	}
+	return result
+}
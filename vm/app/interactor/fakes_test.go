@@ -0,0 +1,198 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/event"
+	"akita/domain/failure"
+	"akita/domain/host"
+	"akita/domain/service"
+	"akita/domain/settings"
+	"akita/domain/user"
+	"context"
+	"github.com/akitasoftware/go-utils/optionals"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sort"
+)
+
+// Fakes of the repositories used by the interactors. Methods that a test
+// doesn't use panic through the embedded nil interface.
+
+type fakeAgentRepo struct {
+	agent.Repository
+	configs map[string]*agent.Config
+}
+
+func (f *fakeAgentRepo) ListConfigs(context.Context) ([]*agent.Config, error) {
+	configs := make([]*agent.Config, 0, len(f.configs))
+	for _, config := range f.configs {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ID < configs[j].ID })
+	return configs, nil
+}
+
+func (f *fakeAgentRepo) GetConfig(_ context.Context, id string) (*agent.Config, error) {
+	config, ok := f.configs[id]
+	if !ok {
+		return nil, failure.NotFoundf("no agent config found with id %s", id)
+	}
+	return config, nil
+}
+
+func (f *fakeAgentRepo) SaveConfig(_ context.Context, config *agent.Config, _ agent.SaveOptions) error {
+	f.configs[config.ID] = config
+	return nil
+}
+
+type fakeUserRepo struct {
+	user.Repository
+	// Users keyed by API key.
+	users map[string]*user.User
+	// The errors returned instead of a user, keyed by API key.
+	errs   map[string]error
+	events []*user.Event
+}
+
+func (f *fakeUserRepo) GetUser(_ context.Context, credentials user.Credentials) (*user.User, error) {
+	if err, ok := f.errs[credentials.APIKey]; ok {
+		return nil, err
+	}
+	found, ok := f.users[credentials.APIKey]
+	if !ok {
+		return nil, failure.Unauthorizedf("invalid credentials")
+	}
+	return found, nil
+}
+
+func (f *fakeUserRepo) EnqueueUserEvent(_ context.Context, userEvent *user.Event) error {
+	f.events = append(f.events, userEvent)
+	return nil
+}
+
+type fakeOutboxRepo struct {
+	event.OutboxRepository
+	events       []*event.QueuedEvent
+	lastDelivery *event.DeliveryReport
+}
+
+func (f *fakeOutboxRepo) Enqueue(_ context.Context, queuedEvent *event.QueuedEvent) error {
+	f.events = append(f.events, queuedEvent)
+	return nil
+}
+
+func (f *fakeOutboxRepo) List(context.Context) ([]*event.QueuedEvent, error) {
+	return append([]*event.QueuedEvent{}, f.events...), nil
+}
+
+func (f *fakeOutboxRepo) Update(context.Context, *event.QueuedEvent) error {
+	return nil
+}
+
+func (f *fakeOutboxRepo) Remove(_ context.Context, id string) error {
+	for i, queuedEvent := range f.events {
+		if queuedEvent.ID == id {
+			f.events = append(f.events[:i], f.events[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeOutboxRepo) Purge(context.Context) (int, error) {
+	purged := len(f.events)
+	f.events = nil
+	return purged, nil
+}
+
+func (f *fakeOutboxRepo) RecordDelivery(_ context.Context, report *event.DeliveryReport) error {
+	f.lastDelivery = report
+	return nil
+}
+
+type fakeHostRepo struct {
+	host.Repository
+}
+
+func (fakeHostRepo) GetTargetPlatform(context.Context) (*host.TargetPlatform, error) {
+	return &host.TargetPlatform{OS: "linux", Arch: "amd64"}, nil
+}
+
+type fakeSettingsRepo struct {
+	settings.Repository
+	telemetry *settings.Telemetry
+}
+
+func (f *fakeSettingsRepo) GetTelemetry(context.Context) (*settings.Telemetry, error) {
+	return f.telemetry, nil
+}
+
+func (f *fakeSettingsRepo) SaveTelemetry(_ context.Context, telemetry *settings.Telemetry) error {
+	f.telemetry = telemetry
+	return nil
+}
+
+type fakeContainerRepo struct {
+	container.Repository
+	containers []*container.Container
+	// The error returned when looking up containers, e.g. if the daemon is unreachable.
+	err error
+}
+
+func (f *fakeContainerRepo) List(context.Context, container.Filter) ([]*container.Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeContainerRepo) Exists(
+	_ context.Context,
+	id string,
+	requiredStatus optionals.Optional[container.Status],
+) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, listedContainer := range f.containers {
+		if listedContainer.ID != id {
+			continue
+		}
+		status, ok := requiredStatus.Get()
+		return !ok || listedContainer.State == status, nil
+	}
+	return false, nil
+}
+
+// Resolves selectors by container name.
+func (f *fakeContainerRepo) Resolve(_ context.Context, selector container.Selector) (*container.Container, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, listedContainer := range f.containers {
+		for _, name := range listedContainer.Names {
+			if name == selector.Name && listedContainer.State == container.StatusRunning {
+				return listedContainer, nil
+			}
+		}
+	}
+	return nil, failure.NotFoundf("no running container matches %s", selector)
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+type fakeServiceRepo struct {
+	service.Repository
+	// Services keyed by API key.
+	services map[string][]*service.Service
+}
+
+func (f *fakeServiceRepo) ListServices(_ context.Context, credentials user.Credentials) ([]*service.Service, error) {
+	services, ok := f.services[credentials.APIKey]
+	if !ok {
+		return nil, failure.Unauthorizedf("invalid credentials")
+	}
+	return services, nil
+}
@@ -0,0 +1,37 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"context"
+)
+
+type ListAgentConfigs struct {
+	agentRepo      agent.Repository
+	reconcileAgent *ReconcileAgent
+}
+
+func NewListAgentConfigsInteractor(
+	agentRepository agent.Repository,
+	reconcileAgent *ReconcileAgent,
+) *ListAgentConfigs {
+	return &ListAgentConfigs{
+		agentRepo:      agentRepository,
+		reconcileAgent: reconcileAgent,
+	}
+}
+
+// Retrieves all agent configurations and fixes them if necessary.
+func (l ListAgentConfigs) Handle(ctx context.Context) ([]*agent.Config, error) {
+	agentConfigs, err := l.agentRepo.ListConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, agentConfig := range agentConfigs {
+		if err := l.reconcileAgent.checkTarget(ctx, agentConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return agentConfigs, nil
+}
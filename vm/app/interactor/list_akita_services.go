@@ -0,0 +1,58 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/service"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+)
+
+type ListAkitaServices struct {
+	agentRepo   agent.Repository
+	serviceRepo service.Repository
+}
+
+func NewListAkitaServicesInteractor(
+	agentRepository agent.Repository,
+	serviceRepository service.Repository,
+) *ListAkitaServices {
+	return &ListAkitaServices{
+		agentRepo:   agentRepository,
+		serviceRepo: serviceRepository,
+	}
+}
+
+type ListAkitaServicesOptions struct {
+	// The credentials of the user whose services are listed, e.g. before they
+	// are saved. If not provided, those of the agent config are used.
+	Credentials optionals.Optional[user.Credentials]
+	// The ID of the agent config whose credentials are used. Defaults to the
+	// default config, which the UI manages.
+	ConfigID optionals.Optional[string]
+}
+
+// Lists the Akita services of a user, so that clients never call the Akita API
+// with credentials themselves.
+func (l ListAkitaServices) Handle(ctx context.Context, options ListAkitaServicesOptions) ([]*service.Service, error) {
+	credentials, ok := options.Credentials.Get()
+	if ok {
+		if err := credentials.Validate(); err != nil {
+			return nil, err
+		}
+	} else {
+		configID := options.ConfigID.GetOrDefault(agent.DefaultConfigID)
+		agentConfig, err := l.agentRepo.GetConfig(ctx, configID)
+		if err != nil {
+			if errors.Is(err, failure.ErrNotFound) {
+				return nil, failure.NotFoundf("no agent config %s found", configID)
+			}
+			return nil, err
+		}
+		credentials = agentConfig.Credentials()
+	}
+
+	return l.serviceRepo.ListServices(ctx, credentials)
+}
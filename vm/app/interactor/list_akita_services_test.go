@@ -0,0 +1,88 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/service"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+	"reflect"
+	"testing"
+)
+
+func TestListAkitaServices_Handle(t *testing.T) {
+	agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{
+		agent.DefaultConfigID: {ID: agent.DefaultConfigID, APIKey: "default-key", APISecret: "secret"},
+		"other":               {ID: "other", APIKey: "other-key", APISecret: "secret"},
+		"revoked":             {ID: "revoked", APIKey: "revoked-key", APISecret: "secret"},
+	}}
+	serviceRepo := &fakeServiceRepo{services: map[string][]*service.Service{
+		"default-key": {{ID: "svc_default", Name: "default-project"}},
+		"other-key":   {{ID: "svc_other", Name: "other-project"}},
+		"given-key":   {{ID: "svc_given", Name: "given-project"}},
+	}}
+
+	tests := []struct {
+		name      string
+		options   ListAkitaServicesOptions
+		wantNames []string
+		wantErr   error
+	}{
+		{name: "default config", wantNames: []string{"default-project"}},
+		{
+			name:      "given config",
+			options:   ListAkitaServicesOptions{ConfigID: optionals.Some("other")},
+			wantNames: []string{"other-project"},
+		},
+		{
+			name: "given credentials",
+			options: ListAkitaServicesOptions{
+				Credentials: optionals.Some(user.Credentials{APIKey: "given-key", APISecret: "secret"}),
+				ConfigID:    optionals.Some("other"),
+			},
+			wantNames: []string{"given-project"},
+		},
+		{
+			name:    "incomplete credentials",
+			options: ListAkitaServicesOptions{Credentials: optionals.Some(user.Credentials{APIKey: "given-key"})},
+			wantErr: failure.ErrInvalid,
+		},
+		{
+			name:    "missing config",
+			options: ListAkitaServicesOptions{ConfigID: optionals.Some("missing")},
+			wantErr: failure.ErrNotFound,
+		},
+		{
+			name:    "rejected credentials",
+			options: ListAkitaServicesOptions{ConfigID: optionals.Some("revoked")},
+			wantErr: failure.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interactor := NewListAkitaServicesInteractor(agentRepo, serviceRepo)
+
+			services, err := interactor.Handle(context.Background(), tt.options)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Handle() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			var names []string
+			for _, listedService := range services {
+				names = append(names, listedService.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("Handle() services = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package interactor
+
+import (
+	"akita/domain/container"
+	"context"
+)
+
+type ListContainers struct {
+	containerRepo container.Repository
+}
+
+func NewListContainersInteractor(containerRepository container.Repository) *ListContainers {
+	return &ListContainers{
+		containerRepo: containerRepository,
+	}
+}
+
+// Lists the containers that match the given filter as candidate agent targets.
+func (l ListContainers) Handle(ctx context.Context, filter container.Filter) ([]*container.Container, error) {
+	return l.containerRepo.List(ctx, filter)
+}
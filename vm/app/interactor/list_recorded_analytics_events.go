@@ -0,0 +1,32 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"akita/domain/failure"
+	"context"
+)
+
+// The most recorded analytics events that can be listed at once.
+const maxRecordedEventLimit = 1000
+
+type ListRecordedAnalyticsEvents struct {
+	recordedEventRepo event.RecordedEventRepository
+}
+
+func NewListRecordedAnalyticsEventsInteractor(
+	recordedEventRepository event.RecordedEventRepository,
+) *ListRecordedAnalyticsEvents {
+	return &ListRecordedAnalyticsEvents{
+		recordedEventRepo: recordedEventRepository,
+	}
+}
+
+// Lists the analytics events most recently recorded by the local analytics
+// sink, newest first, exactly as they would have been sent to Segment.
+func (l ListRecordedAnalyticsEvents) Handle(ctx context.Context, limit int) ([]*event.RecordedEvent, error) {
+	if limit < 1 || limit > maxRecordedEventLimit {
+		return nil, failure.Invalidf("limit must be between 1 and %d", maxRecordedEventLimit)
+	}
+
+	return l.recordedEventRepo.ListRecordedEvents(ctx, limit)
+}
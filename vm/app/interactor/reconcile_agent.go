@@ -0,0 +1,291 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"akita/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/akitasoftware/go-utils/optionals"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+type ReconcileAgent struct {
+	agentRepo          agent.Repository
+	agentContainerRepo agent.ContainerRepository
+	decisionRepo       agent.DecisionRepository
+	containerRepo      container.Repository
+	userRepo           user.Repository
+	// Serializes reconciliations so that periodic and on-demand runs don't race each other.
+	mu     *sync.Mutex
+	logger *logrus.Logger
+}
+
+func NewReconcileAgentInteractor(
+	agentRepository agent.Repository,
+	agentContainerRepository agent.ContainerRepository,
+	decisionRepository agent.DecisionRepository,
+	containerRepository container.Repository,
+	userRepository user.Repository,
+	logger *logrus.Logger,
+) *ReconcileAgent {
+	return &ReconcileAgent{
+		agentRepo:          agentRepository,
+		agentContainerRepo: agentContainerRepository,
+		decisionRepo:       decisionRepository,
+		containerRepo:      containerRepository,
+		userRepo:           userRepository,
+		mu:                 &sync.Mutex{},
+		logger:             logger,
+	}
+}
+
+type ReconcileAgentOptions struct {
+	// If provided, only the agent of the config with the given ID is reconciled.
+	ConfigID optionals.Optional[string]
+	// If provided, a running agent container is recreated even if it is up to
+	// date, and the given reason is recorded with the decision.
+	RecreateReason optionals.Optional[string]
+}
+
+// Compares the saved agent configurations with the state of the agent and
+// target containers, and starts, stops or recreates the agent containers so
+// that they match. Agent containers whose configuration has been removed are
+// stopped. Every action taken is recorded as a decision.
+func (r ReconcileAgent) Handle(ctx context.Context, options ReconcileAgentOptions) ([]*agent.Decision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agentConfigs, err := r.agentRepo.ListConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := r.agentContainerRepo.ListStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configsByID := map[string]*agent.Config{}
+	for _, agentConfig := range agentConfigs {
+		configsByID[agentConfig.ID] = agentConfig
+	}
+
+	var configIDs []string
+	if configID, ok := options.ConfigID.Get(); ok {
+		configIDs = []string{configID}
+	} else {
+		for _, agentConfig := range agentConfigs {
+			configIDs = append(configIDs, agentConfig.ID)
+		}
+		for configID := range statuses {
+			if _, ok := configsByID[configID]; !ok {
+				configIDs = append(configIDs, configID)
+			}
+		}
+	}
+
+	decisions := []*agent.Decision{}
+	var firstErr error
+	for _, configID := range configIDs {
+		status, ok := statuses[configID]
+		if !ok {
+			status = agent.NewAbsentStatus(configID)
+		}
+
+		decision, err := r.reconcile(ctx, configID, configsByID[configID], status, options)
+		if decision != nil {
+			decisions = append(decisions, decision)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return decisions, firstErr
+}
+
+// Reconciles the agent container of a single config. The config is nil if it
+// has been removed.
+func (r ReconcileAgent) reconcile(
+	ctx context.Context,
+	configID string,
+	agentConfig *agent.Config,
+	status *agent.Status,
+	options ReconcileAgentOptions,
+) (*agent.Decision, error) {
+	stop := func() error {
+		return r.agentContainerRepo.Stop(ctx, configID)
+	}
+
+	if agentConfig == nil {
+		if status.State == container.StatusNone {
+			return agent.NewDecision(configID, agent.ActionNone, "agent configuration does not exist"), nil
+		}
+		return r.apply(ctx, configID, agent.ActionStop, "agent configuration was removed", stop)
+	}
+
+	if !agentConfig.IsEnabled {
+		if status.State == container.StatusNone {
+			return agent.NewDecision(configID, agent.ActionNone, "agent is disabled"), nil
+		}
+		return r.apply(ctx, configID, agent.ActionStop, "agent is disabled", stop)
+	}
+
+	if decision, err := r.disableIfTargetMissing(ctx, agentConfig); decision != nil || err != nil {
+		return decision, err
+	}
+
+	resolvedConfig, err := resolveTarget(ctx, r.containerRepo, agentConfig)
+	if err != nil {
+		if !errors.Is(err, failure.ErrNotFound) {
+			return nil, err
+		}
+
+		// The agent follows the selected container across recreations, so it
+		// is only stopped until a matching container is running again.
+		reason := fmt.Sprintf("no running container matches %s", agentConfig.TargetSelector)
+		if status.State == container.StatusNone {
+			return agent.NewDecision(configID, agent.ActionNone, reason), nil
+		}
+		return r.apply(ctx, configID, agent.ActionStop, reason, stop)
+	}
+
+	start := func() error {
+		_, err := r.agentContainerRepo.Start(ctx, resolvedConfig)
+		return err
+	}
+
+	switch {
+	case status.State == container.StatusNone:
+		return r.apply(ctx, configID, agent.ActionStart, "agent container does not exist", start)
+	case !status.IsRunning():
+		return r.apply(ctx, configID, agent.ActionRestart, fmt.Sprintf("agent container is %s", status.State), start)
+	case status.ConfigDigest != r.agentContainerRepo.Digest(resolvedConfig):
+		return r.apply(
+			ctx,
+			configID,
+			agent.ActionRecreate,
+			"agent configuration or target container has changed",
+			start,
+		)
+	}
+
+	if reason, ok := options.RecreateReason.Get(); ok {
+		return r.apply(ctx, configID, agent.ActionRecreate, reason, start)
+	}
+
+	return agent.NewDecision(configID, agent.ActionNone, "agent container is up to date"), nil
+}
+
+// Disables the agent if its target container is missing, without reconciling
+// the agent container otherwise.
+func (r ReconcileAgent) checkTarget(ctx context.Context, agentConfig *agent.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.disableIfTargetMissing(ctx, agentConfig)
+	return err
+}
+
+// Disables the agent and stops its container if the agent targets a container
+// that no longer exists or isn't running.
+// Returns nil if the agent doesn't target a container or the target is running.
+func (r ReconcileAgent) disableIfTargetMissing(ctx context.Context, agentConfig *agent.Config) (*agent.Decision, error) {
+	if agentConfig.TargetContainer == nil {
+		return nil, nil
+	}
+
+	containerExists, err := r.containerRepo.Exists(
+		ctx,
+		*agentConfig.TargetContainer,
+		optionals.Some(container.StatusRunning),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if containerExists {
+		return nil, nil
+	}
+
+	const reason = "Targeted container no longer exists or is not running"
+
+	err = r.userRepo.EnqueueUserEvent(
+		ctx,
+		user.NewEvent(
+			agentConfig.Credentials(),
+			"Agent Automatically Disabled",
+			map[string]any{
+				"reason": reason,
+			},
+		),
+	)
+	if err != nil {
+		r.logger.WithContext(ctx).Debugf("Failed to enqueue user event: %s", err)
+	}
+
+	return r.apply(ctx, agentConfig.ID, agent.ActionDisable, reason, func() error {
+		// Clear the target container and disable the agent.
+		agentConfig.TargetContainer = nil
+		agentConfig.IsEnabled = false
+		agentConfig.IsDemoModeEnabled = false
+
+		if err := r.agentRepo.SaveConfig(ctx, agentConfig, agent.SaveOptions{Source: agent.ChangeSourceReconciler}); err != nil {
+			return err
+		}
+
+		return r.agentContainerRepo.Stop(ctx, agentConfig.ID)
+	})
+}
+
+// Performs the given action and records the decision along with its outcome.
+func (r ReconcileAgent) apply(
+	ctx context.Context,
+	configID string,
+	action agent.Action,
+	reason string,
+	perform func() error,
+) (*agent.Decision, error) {
+	decision := agent.NewDecision(configID, action, reason)
+
+	err := perform()
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	metrics.ReconcilerActions.WithLabelValues(string(action), metrics.Outcome(err)).Inc()
+
+	if recordErr := r.decisionRepo.RecordDecision(ctx, decision); recordErr != nil {
+		r.logger.WithContext(ctx).Warnf("Failed to record reconciliation decision: %s", recordErr)
+	}
+
+	if err != nil {
+		return decision, fmt.Errorf("failed to %s agent %s: %w", action, configID, err)
+	}
+
+	return decision, nil
+}
+
+// Returns a copy of the agent config that targets the running container
+// matched by its target selector. Configs without a selector are returned as is.
+// If no running container matches, a failure.ErrNotFound error is returned.
+func resolveTarget(
+	ctx context.Context,
+	containerRepo container.Repository,
+	agentConfig *agent.Config,
+) (*agent.Config, error) {
+	if agentConfig.TargetSelector == nil {
+		return agentConfig, nil
+	}
+
+	target, err := containerRepo.Resolve(ctx, *agentConfig.TargetSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	return agentConfig.WithTargetContainer(target.ID), nil
+}
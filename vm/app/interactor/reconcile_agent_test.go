@@ -0,0 +1,276 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+	"reflect"
+	"testing"
+)
+
+// An agent container repository whose containers are labelled with the target
+// container of their config as digest.
+type fakeAgentContainerRepo struct {
+	agent.ContainerRepository
+	statuses map[string]*agent.Status
+	startErr error
+	// The configs that agent containers were started from, and the IDs of
+	// the configs whose agent containers were stopped.
+	started []*agent.Config
+	stopped []string
+}
+
+func (f *fakeAgentContainerRepo) ListStatuses(context.Context) (map[string]*agent.Status, error) {
+	return f.statuses, nil
+}
+
+func (f *fakeAgentContainerRepo) Start(_ context.Context, agentConfig *agent.Config) (*agent.Status, error) {
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	f.started = append(f.started, agentConfig)
+	return &agent.Status{ConfigID: agentConfig.ID, State: container.StatusRunning}, nil
+}
+
+func (f *fakeAgentContainerRepo) Stop(_ context.Context, configID string) error {
+	f.stopped = append(f.stopped, configID)
+	return nil
+}
+
+func (f *fakeAgentContainerRepo) Digest(agentConfig *agent.Config) string {
+	if agentConfig.TargetContainer == nil {
+		return "any"
+	}
+	return *agentConfig.TargetContainer
+}
+
+type fakeDecisionRepo struct {
+	agent.DecisionRepository
+	decisions []*agent.Decision
+}
+
+func (f *fakeDecisionRepo) RecordDecision(_ context.Context, decision *agent.Decision) error {
+	f.decisions = append(f.decisions, decision)
+	return nil
+}
+
+func TestReconcileAgent_Handle(t *testing.T) {
+	target := "target-id"
+	runningTarget := &container.Container{ID: target, Names: []string{"api"}, State: container.StatusRunning}
+	enabledConfig := func() *agent.Config {
+		return &agent.Config{ID: "default", APIKey: "key", APISecret: "secret", IsEnabled: true}
+	}
+	running := func(digest string) *agent.Status {
+		return &agent.Status{ConfigID: "default", State: container.StatusRunning, ConfigDigest: digest}
+	}
+
+	tests := []struct {
+		name       string
+		config     *agent.Config
+		status     *agent.Status
+		containers []*container.Container
+		options    ReconcileAgentOptions
+		startErr   error
+		wantAction agent.Action
+		// The target container of the started agent, if one was started.
+		wantStartedTarget optionals.Optional[string]
+		wantStopped       bool
+		wantDisabled      bool
+		wantErr           bool
+	}{
+		{name: "no config nor container", wantAction: agent.ActionNone},
+		{name: "config removed", status: running("any"), wantAction: agent.ActionStop, wantStopped: true},
+		{
+			name:       "disabled without container",
+			config:     &agent.Config{ID: "default"},
+			wantAction: agent.ActionNone,
+		},
+		{
+			name:        "disabled with running container",
+			config:      &agent.Config{ID: "default"},
+			status:      running("any"),
+			wantAction:  agent.ActionStop,
+			wantStopped: true,
+		},
+		{
+			name:              "enabled without container",
+			config:            enabledConfig(),
+			wantAction:        agent.ActionStart,
+			wantStartedTarget: optionals.Some(""),
+		},
+		{
+			name:              "enabled with exited container",
+			config:            enabledConfig(),
+			status:            &agent.Status{ConfigID: "default", State: container.StatusExited},
+			wantAction:        agent.ActionRestart,
+			wantStartedTarget: optionals.Some(""),
+		},
+		{
+			name:       "up to date",
+			config:     enabledConfig(),
+			status:     running("any"),
+			wantAction: agent.ActionNone,
+		},
+		{
+			name:              "out of date",
+			config:            enabledConfig(),
+			status:            running("outdated"),
+			wantAction:        agent.ActionRecreate,
+			wantStartedTarget: optionals.Some(""),
+		},
+		{
+			name:              "recreation requested",
+			config:            enabledConfig(),
+			status:            running("any"),
+			options:           ReconcileAgentOptions{RecreateReason: optionals.Some("credentials rotated")},
+			wantAction:        agent.ActionRecreate,
+			wantStartedTarget: optionals.Some(""),
+		},
+		{
+			name: "target container gone",
+			config: func() *agent.Config {
+				config := enabledConfig()
+				config.TargetContainer = &target
+				return config
+			}(),
+			status:       running(target),
+			wantAction:   agent.ActionDisable,
+			wantStopped:  true,
+			wantDisabled: true,
+		},
+		{
+			name: "selected container running",
+			config: func() *agent.Config {
+				config := enabledConfig()
+				config.TargetSelector = &container.Selector{Name: "api"}
+				return config
+			}(),
+			containers:        []*container.Container{runningTarget},
+			wantAction:        agent.ActionStart,
+			wantStartedTarget: optionals.Some(target),
+		},
+		{
+			name: "selected container gone",
+			config: func() *agent.Config {
+				config := enabledConfig()
+				config.TargetSelector = &container.Selector{Name: "api"}
+				return config
+			}(),
+			status:      running(target),
+			wantAction:  agent.ActionStop,
+			wantStopped: true,
+		},
+		{
+			name:       "failed start",
+			config:     enabledConfig(),
+			startErr:   errors.New("image not found"),
+			wantAction: agent.ActionStart,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{}}
+			if tt.config != nil {
+				agentRepo.configs[tt.config.ID] = tt.config
+			}
+			agentContainerRepo := &fakeAgentContainerRepo{statuses: map[string]*agent.Status{}, startErr: tt.startErr}
+			if tt.status != nil {
+				agentContainerRepo.statuses[tt.status.ConfigID] = tt.status
+			}
+			decisionRepo := &fakeDecisionRepo{}
+			userRepo := &fakeUserRepo{}
+			interactor := NewReconcileAgentInteractor(
+				agentRepo,
+				agentContainerRepo,
+				decisionRepo,
+				&fakeContainerRepo{containers: tt.containers},
+				userRepo,
+				newTestLogger(),
+			)
+
+			options := tt.options
+			options.ConfigID = optionals.Some("default")
+			decisions, err := interactor.Handle(context.Background(), options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(decisions) != 1 || decisions[0].Action != tt.wantAction {
+				t.Fatalf("Handle() decisions = %+v, want a single %s", decisions, tt.wantAction)
+			}
+			if tt.wantErr && decisions[0].Error == "" {
+				t.Error("failed decision has no error")
+			}
+			// Only actions are recorded.
+			if wantRecorded := tt.wantAction != agent.ActionNone; (len(decisionRepo.decisions) == 1) != wantRecorded {
+				t.Errorf("recorded decisions = %+v, want recorded %v", decisionRepo.decisions, wantRecorded)
+			}
+
+			var startedTargets []string
+			for _, started := range agentContainerRepo.started {
+				startedTarget := ""
+				if started.TargetContainer != nil {
+					startedTarget = *started.TargetContainer
+				}
+				startedTargets = append(startedTargets, startedTarget)
+			}
+			var wantStartedTargets []string
+			if wantTarget, ok := tt.wantStartedTarget.Get(); ok {
+				wantStartedTargets = []string{wantTarget}
+			}
+			if !reflect.DeepEqual(startedTargets, wantStartedTargets) {
+				t.Errorf("started agents targeting %v, want %v", startedTargets, wantStartedTargets)
+			}
+			if stopped := len(agentContainerRepo.stopped) == 1; stopped != tt.wantStopped {
+				t.Errorf("stopped agents %v, want stopped %v", agentContainerRepo.stopped, tt.wantStopped)
+			}
+
+			if tt.wantDisabled {
+				saved := agentRepo.configs["default"]
+				if saved.IsEnabled || saved.TargetContainer != nil {
+					t.Errorf("saved config = %+v, want it disabled without a target", saved)
+				}
+				if len(userRepo.events) != 1 || userRepo.events[0].Name != "Agent Automatically Disabled" {
+					t.Errorf("user events = %+v, want the agent reported as disabled", userRepo.events)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileAgent_HandleAll(t *testing.T) {
+	agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{
+		"api":    {ID: "api", IsEnabled: true},
+		"worker": {ID: "worker"},
+	}}
+	agentContainerRepo := &fakeAgentContainerRepo{statuses: map[string]*agent.Status{
+		"worker":  {ConfigID: "worker", State: container.StatusRunning, ConfigDigest: "any"},
+		"removed": {ConfigID: "removed", State: container.StatusRunning, ConfigDigest: "any"},
+	}}
+	interactor := NewReconcileAgentInteractor(
+		agentRepo,
+		agentContainerRepo,
+		&fakeDecisionRepo{},
+		&fakeContainerRepo{},
+		&fakeUserRepo{},
+		newTestLogger(),
+	)
+
+	decisions, err := interactor.Handle(context.Background(), ReconcileAgentOptions{})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	got := map[string]agent.Action{}
+	for _, decision := range decisions {
+		got[decision.ConfigID] = decision.Action
+	}
+	want := map[string]agent.Action{"api": agent.ActionStart, "worker": agent.ActionStop, "removed": agent.ActionStop}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Handle() actions = %v, want %v", got, want)
+	}
+}
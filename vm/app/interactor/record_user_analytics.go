@@ -2,32 +2,37 @@ package interactor
 
 import (
 	"akita/domain/agent"
+	"akita/domain/event"
+	"akita/domain/failure"
 	"akita/domain/host"
+	"akita/domain/settings"
 	"akita/domain/user"
 	"context"
-	"fmt"
-	"github.com/akitasoftware/akita-libs/analytics"
+	"errors"
 	"github.com/akitasoftware/go-utils/optionals"
 )
 
 type RecordUserAnalytics struct {
-	analyticsClient analytics.Client
-	hostRepo        host.Repository
-	userRepo        user.Repository
-	agentRepo       agent.Repository
+	outboxRepo   event.OutboxRepository
+	hostRepo     host.Repository
+	userRepo     user.Repository
+	agentRepo    agent.Repository
+	settingsRepo settings.Repository
 }
 
 func NewRecordUserAnalyticsInteractor(
-	analyticsClient analytics.Client,
+	outboxRepo event.OutboxRepository,
 	hostRepo host.Repository,
 	userRepo user.Repository,
 	agentRepo agent.Repository,
+	settingsRepo settings.Repository,
 ) *RecordUserAnalytics {
 	return &RecordUserAnalytics{
-		analyticsClient: analyticsClient,
-		hostRepo:        hostRepo,
-		userRepo:        userRepo,
-		agentRepo:       agentRepo,
+		outboxRepo:   outboxRepo,
+		hostRepo:     hostRepo,
+		userRepo:     userRepo,
+		agentRepo:    agentRepo,
+		settingsRepo: settingsRepo,
 	}
 }
 
@@ -36,6 +41,9 @@ type RecordUserAnalyticsOptions struct {
 	TargetPlatform optionals.Optional[*host.TargetPlatform]
 	// The user's email. If not provided, the data will be fetched from the Akita API
 	UserEmail optionals.Optional[string]
+	// The ID of the agent config whose credentials identify the user when no
+	// email is provided. Defaults to the default config, which the UI manages.
+	ConfigID optionals.Optional[string]
 }
 
 func (r RecordUserAnalytics) Handle(
@@ -44,7 +52,14 @@ func (r RecordUserAnalytics) Handle(
 	properties map[string]any,
 	options RecordUserAnalyticsOptions,
 ) error {
-	var err error
+	// Nothing is recorded unless the user consented to it.
+	telemetry, err := r.settingsRepo.GetTelemetry(ctx)
+	if err != nil {
+		return err
+	}
+	if !telemetry.IsAllowed() {
+		return nil
+	}
 
 	targetPlatform, ok := options.TargetPlatform.Get()
 	if !ok {
@@ -57,25 +72,30 @@ func (r RecordUserAnalytics) Handle(
 
 	distinctID, ok := options.UserEmail.Get()
 	if !ok {
-		// Fetch the user's email from the Akita API.
-		agentConfig, err := r.agentRepo.GetConfig(ctx)
+		// Fetch the user's email from the Akita API. Configs may hold the
+		// credentials of different users, so the user is resolved from the
+		// config the event is about.
+		configID := options.ConfigID.GetOrDefault(agent.DefaultConfigID)
+		agentConfig, err := r.agentRepo.GetConfig(ctx, configID)
 		if err != nil {
+			if errors.Is(err, failure.ErrNotFound) {
+				return failure.NotFoundf("no agent config %s found to identify the user", configID)
+			}
 			return err
 		}
-		userResult, err := r.userRepo.GetUser(agentConfig.Credentials())
+		userResult, err := r.userRepo.GetUser(ctx, agentConfig.Credentials())
 		if err != nil {
 			return err
 		}
 		distinctID = userResult.Email
 	}
 
+	if properties == nil {
+		properties = map[string]any{}
+	}
 	properties["target-os"] = targetPlatform.OS
 	properties["target-arch"] = targetPlatform.Arch
 
-	err = r.analyticsClient.TrackEvent(analytics.NewEvent(distinctID, eventName, properties))
-	if err != nil {
-		return fmt.Errorf("failed to record analytics event: %w", err)
-	}
-
-	return nil
+	// The outbox scrubs the event, and its worker delivers it, so that it isn't lost while offline.
+	return r.outboxRepo.Enqueue(ctx, event.NewQueuedEvent(distinctID, eventName, properties))
 }
@@ -0,0 +1,101 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/settings"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+	"testing"
+)
+
+func TestRecordUserAnalytics_Handle(t *testing.T) {
+	agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{
+		agent.DefaultConfigID: {ID: agent.DefaultConfigID, APIKey: "default-key"},
+		"a-other":             {ID: "a-other", APIKey: "other-key"},
+	}}
+	userRepo := &fakeUserRepo{users: map[string]*user.User{
+		"default-key": {Email: "default@example.com"},
+		"other-key":   {Email: "other@example.com"},
+	}}
+
+	tests := []struct {
+		name           string
+		consent        settings.Consent
+		options        RecordUserAnalyticsOptions
+		wantDistinctID string
+		wantErr        error
+		wantNoEvent    bool
+	}{
+		{
+			name:           "user of the default config",
+			consent:        settings.ConsentGranted,
+			wantDistinctID: "default@example.com",
+		},
+		{
+			name:           "user of the given config",
+			consent:        settings.ConsentGranted,
+			options:        RecordUserAnalyticsOptions{ConfigID: optionals.Some("a-other")},
+			wantDistinctID: "other@example.com",
+		},
+		{
+			name:           "given email",
+			consent:        settings.ConsentGranted,
+			options:        RecordUserAnalyticsOptions{UserEmail: optionals.Some("given@example.com")},
+			wantDistinctID: "given@example.com",
+		},
+		{
+			name:    "missing config",
+			consent: settings.ConsentGranted,
+			options: RecordUserAnalyticsOptions{ConfigID: optionals.Some("missing")},
+			wantErr: failure.ErrNotFound,
+		},
+		{
+			name:        "consent not granted",
+			consent:     settings.ConsentUndecided,
+			wantNoEvent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outboxRepo := &fakeOutboxRepo{}
+			interactor := NewRecordUserAnalyticsInteractor(
+				outboxRepo,
+				fakeHostRepo{},
+				userRepo,
+				agentRepo,
+				&fakeSettingsRepo{telemetry: &settings.Telemetry{Consent: tt.consent}},
+			)
+
+			err := interactor.Handle(context.Background(), "Viewed Agent Page", nil, tt.options)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Handle() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if tt.wantNoEvent {
+				if len(outboxRepo.events) != 0 {
+					t.Fatalf("Handle() queued %d events, want none", len(outboxRepo.events))
+				}
+				return
+			}
+			if len(outboxRepo.events) != 1 {
+				t.Fatalf("Handle() queued %d events, want 1", len(outboxRepo.events))
+			}
+			if got := outboxRepo.events[0].DistinctID; got != tt.wantDistinctID {
+				t.Errorf("distinct ID = %q, want %q", got, tt.wantDistinctID)
+			}
+			if got := outboxRepo.events[0].Properties["target-os"]; got != "linux" {
+				t.Errorf("target-os = %v, want linux", got)
+			}
+		})
+	}
+}
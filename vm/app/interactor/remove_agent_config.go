@@ -2,20 +2,40 @@ package interactor
 
 import (
 	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/user"
 	"context"
+	"errors"
 )
 
 type RemoveAgentConfig struct {
 	agentRepo agent.Repository
+	userRepo  user.Repository
 }
 
-func NewRemoveAgentConfigInteractor(agentRepo agent.Repository) *RemoveAgentConfig {
+func NewRemoveAgentConfigInteractor(agentRepo agent.Repository, userRepo user.Repository) *RemoveAgentConfig {
 	return &RemoveAgentConfig{
 		agentRepo: agentRepo,
+		userRepo:  userRepo,
 	}
 }
 
-// Removes the agent configuration.
-func (r RemoveAgentConfig) Handle(ctx context.Context) error {
-	return r.agentRepo.DeleteConfig(ctx)
+// Removes the agent configuration with the given ID.
+// Its agent container is stopped by the next reconciliation.
+func (r RemoveAgentConfig) Handle(ctx context.Context, id string) error {
+	agentConfig, err := r.agentRepo.GetConfig(ctx, id)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := r.agentRepo.DeleteConfig(ctx, id); err != nil {
+		return err
+	}
+
+	r.userRepo.InvalidateUser(agentConfig.Credentials())
+
+	return nil
 }
@@ -0,0 +1,21 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"context"
+)
+
+type RetrieveAgentConfigHistory struct {
+	agentRepo agent.Repository
+}
+
+func NewRetrieveAgentConfigHistoryInteractor(agentRepository agent.Repository) *RetrieveAgentConfigHistory {
+	return &RetrieveAgentConfigHistory{
+		agentRepo: agentRepository,
+	}
+}
+
+// Retrieves the revisions of the agent configuration with the given ID, newest first.
+func (r RetrieveAgentConfigHistory) Handle(ctx context.Context, id string) ([]*agent.Revision, error) {
+	return r.agentRepo.ListRevisions(ctx, id)
+}
@@ -0,0 +1,29 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"context"
+)
+
+// The maximum number of reconciliation decisions that can be retrieved at once.
+const maxDecisionLimit = 100
+
+type RetrieveAgentDecisions struct {
+	decisionRepo agent.DecisionRepository
+}
+
+func NewRetrieveAgentDecisionsInteractor(decisionRepository agent.DecisionRepository) *RetrieveAgentDecisions {
+	return &RetrieveAgentDecisions{
+		decisionRepo: decisionRepository,
+	}
+}
+
+// Retrieves the most recent reconciliation decisions, newest first.
+func (r RetrieveAgentDecisions) Handle(ctx context.Context, limit int) ([]*agent.Decision, error) {
+	if limit <= 0 || limit > maxDecisionLimit {
+		return nil, failure.Invalidf("limit must be between 1 and %d", maxDecisionLimit)
+	}
+
+	return r.decisionRepo.ListDecisions(ctx, limit)
+}
@@ -0,0 +1,32 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"context"
+	"errors"
+)
+
+type RetrieveAgentStatus struct {
+	agentContainerRepo agent.ContainerRepository
+}
+
+func NewRetrieveAgentStatusInteractor(agentContainerRepository agent.ContainerRepository) *RetrieveAgentStatus {
+	return &RetrieveAgentStatus{
+		agentContainerRepo: agentContainerRepository,
+	}
+}
+
+// Retrieves the status of the agent container of the given config.
+// If no agent container exists, a status with container.StatusNone is returned.
+func (r RetrieveAgentStatus) Handle(ctx context.Context, configID string) (*agent.Status, error) {
+	status, err := r.agentContainerRepo.GetStatus(ctx, configID)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return agent.NewAbsentStatus(configID), nil
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
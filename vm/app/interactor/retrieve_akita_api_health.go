@@ -0,0 +1,20 @@
+package interactor
+
+import (
+	"akita/domain/user"
+)
+
+type RetrieveAkitaAPIHealth struct {
+	userRepo user.Repository
+}
+
+func NewRetrieveAkitaAPIHealthInteractor(userRepository user.Repository) *RetrieveAkitaAPIHealth {
+	return &RetrieveAkitaAPIHealth{
+		userRepo: userRepository,
+	}
+}
+
+// Retrieves the health of the Akita API as observed by the extension's calls to it.
+func (r RetrieveAkitaAPIHealth) Handle() *user.APIHealth {
+	return r.userRepo.Health()
+}
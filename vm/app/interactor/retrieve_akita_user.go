@@ -0,0 +1,35 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"context"
+	"errors"
+)
+
+type RetrieveAkitaUser struct {
+	agentRepo agent.Repository
+	userRepo  user.Repository
+}
+
+func NewRetrieveAkitaUserInteractor(agentRepository agent.Repository, userRepository user.Repository) *RetrieveAkitaUser {
+	return &RetrieveAkitaUser{
+		agentRepo: agentRepository,
+		userRepo:  userRepository,
+	}
+}
+
+// Retrieves the Akita user that the credentials of the agent config with the
+// given ID belong to.
+func (r RetrieveAkitaUser) Handle(ctx context.Context, configID string) (*user.User, error) {
+	agentConfig, err := r.agentRepo.GetConfig(ctx, configID)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.NotFoundf("no agent config %s found", configID)
+		}
+		return nil, err
+	}
+
+	return r.userRepo.GetUser(ctx, agentConfig.Credentials())
+}
@@ -0,0 +1,51 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetrieveAkitaUser_Handle(t *testing.T) {
+	agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{
+		agent.DefaultConfigID: {ID: agent.DefaultConfigID, APIKey: "default-key", APISecret: "secret"},
+		"revoked":             {ID: "revoked", APIKey: "revoked-key", APISecret: "secret"},
+	}}
+	userRepo := &fakeUserRepo{users: map[string]*user.User{
+		"default-key": {Email: "default@example.com"},
+	}}
+
+	tests := []struct {
+		name      string
+		configID  string
+		wantEmail string
+		wantErr   error
+	}{
+		{name: "default config", configID: agent.DefaultConfigID, wantEmail: "default@example.com"},
+		{name: "missing config", configID: "missing", wantErr: failure.ErrNotFound},
+		{name: "rejected credentials", configID: "revoked", wantErr: failure.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interactor := NewRetrieveAkitaUserInteractor(agentRepo, userRepo)
+
+			akitaUser, err := interactor.Handle(context.Background(), tt.configID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Handle() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if akitaUser.Email != tt.wantEmail {
+				t.Errorf("Handle() email = %s, want %s", akitaUser.Email, tt.wantEmail)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"context"
+)
+
+type RetrieveAnalyticsQueueStatus struct {
+	outboxRepo event.OutboxRepository
+}
+
+func NewRetrieveAnalyticsQueueStatusInteractor(outboxRepo event.OutboxRepository) *RetrieveAnalyticsQueueStatus {
+	return &RetrieveAnalyticsQueueStatus{
+		outboxRepo: outboxRepo,
+	}
+}
+
+// Retrieves the number of analytics events waiting to be delivered and the
+// outcome of the last delivery.
+func (r RetrieveAnalyticsQueueStatus) Handle(ctx context.Context) (*event.QueueStatus, error) {
+	queuedEvents, err := r.outboxRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lastDelivery, err := r.outboxRepo.LastDelivery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &event.QueueStatus{Depth: len(queuedEvents), LastDelivery: lastDelivery}
+	if len(queuedEvents) > 0 {
+		status.OldestEnqueuedAt = &queuedEvents[0].EnqueuedAt
+	}
+
+	return status, nil
+}
@@ -0,0 +1,24 @@
+package interactor
+
+import (
+	"akita/domain/health"
+	"time"
+)
+
+type RetrieveLiveness struct {
+	startedAt time.Time
+}
+
+func NewRetrieveLivenessInteractor() *RetrieveLiveness {
+	return &RetrieveLiveness{startedAt: time.Now().UTC()}
+}
+
+// Reports that the extension is alive. Dependencies aren't checked, so that
+// an unreachable dependency doesn't get the extension restarted.
+func (r RetrieveLiveness) Handle() *health.Liveness {
+	return &health.Liveness{
+		Status:        health.StatusUp,
+		StartedAt:     r.startedAt,
+		UptimeSeconds: time.Since(r.startedAt).Seconds(),
+	}
+}
@@ -0,0 +1,91 @@
+package interactor
+
+import (
+	"akita/domain/health"
+	"context"
+	"sync"
+	"time"
+)
+
+// How long each dependency check may take before the dependency is considered unreachable.
+const dependencyCheckTimeout = 3 * time.Second
+
+type RetrieveReadiness struct {
+	checkers []health.Checker
+	mu       *sync.Mutex
+	// The last failure of each dependency, keyed by checker name.
+	lastFailures map[string]dependencyFailure
+}
+
+type dependencyFailure struct {
+	err string
+	at  time.Time
+}
+
+func NewRetrieveReadinessInteractor(checkers []health.Checker) *RetrieveReadiness {
+	return &RetrieveReadiness{
+		checkers:     checkers,
+		mu:           &sync.Mutex{},
+		lastFailures: map[string]dependencyFailure{},
+	}
+}
+
+// Checks all dependencies concurrently and reports whether the extension is
+// ready. It isn't if any required dependency is unreachable.
+func (r RetrieveReadiness) Handle(ctx context.Context) *health.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+
+	results := make([]*health.CheckResult, len(r.checkers))
+	var wg sync.WaitGroup
+	for i, checker := range r.checkers {
+		wg.Add(1)
+		go func(i int, checker health.Checker) {
+			defer wg.Done()
+			results[i] = r.check(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	status := health.StatusUp
+	for _, result := range results {
+		if result.Status == health.StatusUp {
+			continue
+		}
+		if result.Required {
+			status = health.StatusDown
+		} else if status == health.StatusUp {
+			status = health.StatusDegraded
+		}
+	}
+
+	return &health.Readiness{Status: status, Checks: results}
+}
+
+func (r RetrieveReadiness) check(ctx context.Context, checker health.Checker) *health.CheckResult {
+	startedAt := time.Now()
+	err := checker.Check(ctx)
+	checkedAt := time.Now().UTC()
+
+	result := &health.CheckResult{
+		Name:      checker.Name(),
+		Required:  checker.Required(),
+		Status:    health.StatusUp,
+		LatencyMS: float64(time.Since(startedAt).Microseconds()) / 1000,
+		CheckedAt: checkedAt,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		result.Status = health.StatusDown
+		r.lastFailures[checker.Name()] = dependencyFailure{err: err.Error(), at: checkedAt}
+	}
+	if lastFailure, ok := r.lastFailures[checker.Name()]; ok {
+		result.LastError = lastFailure.err
+		result.LastErrorAt = &lastFailure.at
+	}
+
+	return result
+}
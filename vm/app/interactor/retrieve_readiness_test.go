@@ -0,0 +1,115 @@
+package interactor
+
+import (
+	"akita/domain/health"
+	"context"
+	"errors"
+	"testing"
+)
+
+// A checker of a dependency that fails with err.
+type fakeChecker struct {
+	name     string
+	required bool
+	err      error
+}
+
+func (f *fakeChecker) Name() string {
+	return f.name
+}
+
+func (f *fakeChecker) Required() bool {
+	return f.required
+}
+
+func (f *fakeChecker) Check(context.Context) error {
+	return f.err
+}
+
+func TestRetrieveReadiness_Handle(t *testing.T) {
+	unreachable := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		checkers   []health.Checker
+		wantStatus health.Status
+	}{
+		{
+			name: "all dependencies up",
+			checkers: []health.Checker{
+				&fakeChecker{name: "store", required: true},
+				&fakeChecker{name: "akita-api"},
+			},
+			wantStatus: health.StatusUp,
+		},
+		{
+			name: "optional dependency down",
+			checkers: []health.Checker{
+				&fakeChecker{name: "store", required: true},
+				&fakeChecker{name: "akita-api", err: unreachable},
+			},
+			wantStatus: health.StatusDegraded,
+		},
+		{
+			name: "required dependency down",
+			checkers: []health.Checker{
+				&fakeChecker{name: "store", required: true, err: unreachable},
+				&fakeChecker{name: "akita-api"},
+			},
+			wantStatus: health.StatusDown,
+		},
+		{
+			name: "required and optional dependencies down",
+			checkers: []health.Checker{
+				&fakeChecker{name: "akita-api", err: unreachable},
+				&fakeChecker{name: "store", required: true, err: unreachable},
+			},
+			wantStatus: health.StatusDown,
+		},
+		{name: "no dependencies", wantStatus: health.StatusUp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness := NewRetrieveReadinessInteractor(tt.checkers).Handle(context.Background())
+
+			if readiness.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", readiness.Status, tt.wantStatus)
+			}
+			if len(readiness.Checks) != len(tt.checkers) {
+				t.Fatalf("got %d checks, want %d", len(readiness.Checks), len(tt.checkers))
+			}
+			// Checks are reported in the order of the checkers.
+			for i, checker := range tt.checkers {
+				check := readiness.Checks[i]
+				wantStatus := health.StatusUp
+				if checker.Check(context.Background()) != nil {
+					wantStatus = health.StatusDown
+				}
+				if check.Name != checker.Name() || check.Required != checker.Required() || check.Status != wantStatus {
+					t.Errorf("Checks[%d] = %+v, want %s to be %s", i, check, checker.Name(), wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestRetrieveReadiness_KeepsLastError(t *testing.T) {
+	checker := &fakeChecker{name: "store", required: true, err: errors.New("disk full")}
+	interactor := NewRetrieveReadinessInteractor([]health.Checker{checker})
+
+	failed := interactor.Handle(context.Background()).Checks[0]
+	if failed.LastError != "disk full" || failed.LastErrorAt == nil {
+		t.Fatalf("failed check = %+v, want its error", failed)
+	}
+
+	// The error is kept once the dependency recovers.
+	checker.err = nil
+	recovered := interactor.Handle(context.Background())
+	if recovered.Status != health.StatusUp {
+		t.Errorf("Status = %s, want %s", recovered.Status, health.StatusUp)
+	}
+	if check := recovered.Checks[0]; check.LastError != "disk full" || !check.LastErrorAt.Equal(*failed.LastErrorAt) {
+		t.Errorf("recovered check = %+v, want the error of the failed check", check)
+	}
+}
@@ -0,0 +1,18 @@
+package interactor
+
+import (
+	"akita/domain/settings"
+	"context"
+)
+
+type RetrieveTelemetrySettings struct {
+	settingsRepo settings.Repository
+}
+
+func NewRetrieveTelemetrySettingsInteractor(settingsRepository settings.Repository) *RetrieveTelemetrySettings {
+	return &RetrieveTelemetrySettings{settingsRepo: settingsRepository}
+}
+
+func (r RetrieveTelemetrySettings) Handle(ctx context.Context) (*settings.Telemetry, error) {
+	return r.settingsRepo.GetTelemetry(ctx)
+}
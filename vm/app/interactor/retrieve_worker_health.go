@@ -0,0 +1,24 @@
+package interactor
+
+import "akita/domain/health"
+
+type RetrieveWorkerHealth struct {
+	workerMonitor health.WorkerMonitor
+}
+
+func NewRetrieveWorkerHealthInteractor(workerMonitor health.WorkerMonitor) *RetrieveWorkerHealth {
+	return &RetrieveWorkerHealth{workerMonitor: workerMonitor}
+}
+
+// Reports the status of the background workers. They are healthy if all of
+// them are running.
+func (r RetrieveWorkerHealth) Handle() *health.WorkersHealth {
+	statuses := r.workerMonitor.WorkerStatuses()
+
+	healthy := true
+	for _, status := range statuses {
+		healthy = healthy && status.State == health.WorkerRunning
+	}
+
+	return &health.WorkersHealth{Healthy: healthy, Workers: statuses}
+}
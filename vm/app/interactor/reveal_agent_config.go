@@ -0,0 +1,22 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"context"
+)
+
+type RevealAgentConfig struct {
+	agentRepo agent.Repository
+}
+
+func NewRevealAgentConfigInteractor(agentRepository agent.Repository) *RevealAgentConfig {
+	return &RevealAgentConfig{
+		agentRepo: agentRepository,
+	}
+}
+
+// Retrieves the agent configuration with the given ID including its
+// credentials, which are otherwise redacted by the API.
+func (r RevealAgentConfig) Handle(ctx context.Context, id string) (*agent.Config, error) {
+	return r.agentRepo.GetConfig(ctx, id)
+}
@@ -0,0 +1,48 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"context"
+	"github.com/akitasoftware/go-utils/optionals"
+)
+
+type RollbackAgentConfig struct {
+	agentRepo       agent.Repository
+	saveAgentConfig *SaveAgentConfig
+}
+
+func NewRollbackAgentConfigInteractor(
+	agentRepository agent.Repository,
+	saveAgentConfig *SaveAgentConfig,
+) *RollbackAgentConfig {
+	return &RollbackAgentConfig{
+		agentRepo:       agentRepository,
+		saveAgentConfig: saveAgentConfig,
+	}
+}
+
+// Restores the given revision of the agent configuration with the given ID.
+// The restored configuration is validated like any other saved configuration,
+// and the rollback is recorded as a new revision.
+func (r RollbackAgentConfig) Handle(
+	ctx context.Context,
+	id string,
+	version int,
+	source agent.ChangeSource,
+) (*agent.Config, error) {
+	revision, err := r.agentRepo.GetRevision(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	agentConfig := revision.Config
+	err = r.saveAgentConfig.Handle(ctx, agentConfig, agent.SaveOptions{
+		Source:          source,
+		RestoredVersion: optionals.Some(version),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return agentConfig, nil
+}
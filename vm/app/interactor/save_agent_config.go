@@ -2,58 +2,60 @@ package interactor
 
 import (
 	"akita/domain/agent"
-	"akita/domain/container"
 	"akita/domain/failure"
 	"akita/domain/user"
 	"context"
-	"github.com/akitasoftware/go-utils/optionals"
+	"errors"
 )
 
 type SaveAgentConfig struct {
-	agentRepo     agent.Repository
-	containerRepo container.Repository
-	userRepo      user.Repository
+	agentRepo           agent.Repository
+	userRepo            user.Repository
+	validateAgentConfig *ValidateAgentConfig
 }
 
 func NewSaveAgentConfigInteractor(
 	agentRepository agent.Repository,
-	containerRepository container.Repository,
 	userRepository user.Repository,
+	validateAgentConfig *ValidateAgentConfig,
 ) *SaveAgentConfig {
 	return &SaveAgentConfig{
-		agentRepo:     agentRepository,
-		containerRepo: containerRepository,
-		userRepo:      userRepository,
+		agentRepo:           agentRepository,
+		userRepo:            userRepository,
+		validateAgentConfig: validateAgentConfig,
 	}
 }
 
-// Saves the agent configuration.
-func (s SaveAgentConfig) Handle(ctx context.Context, config *agent.Config) error {
-	if config.Validate() != nil {
-		return failure.Invalidf("invalid agent configuration")
+// Saves the agent configuration and records the change in its history. If the
+// configuration is invalid, the returned error reports every violation.
+func (s SaveAgentConfig) Handle(ctx context.Context, config *agent.Config, options agent.SaveOptions) error {
+	previousConfig, err := s.agentRepo.GetConfig(ctx, config.ID)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return err
 	}
 
-	// Check that the user exists.
-	if _, err := s.userRepo.GetUser(config.Credentials()); err != nil {
+	report, unverifiedErr, err := s.validateAgentConfig.validate(ctx, config)
+	if err != nil {
 		return err
 	}
 
-	if config.TargetContainer == nil {
-		return s.agentRepo.SaveConfig(ctx, config)
+	if !report.Valid {
+		return agent.InvalidConfigError(report.Violations)
 	}
 
-	containerExists, err := s.containerRepo.Exists(
-		ctx,
-		*config.TargetContainer,
-		optionals.Some(container.StatusRunning),
-	)
-	if err != nil {
+	// A config is only saved once every check could be made.
+	if unverifiedErr != nil {
+		return unverifiedErr
+	}
+
+	if err := s.agentRepo.SaveConfig(ctx, config, options); err != nil {
 		return err
 	}
 
-	if !containerExists {
-		return failure.Unprocessablef("container %s does not exist or is not running", *config.TargetContainer)
+	// The user of replaced credentials is no longer needed.
+	if previousConfig != nil && previousConfig.Credentials() != config.Credentials() {
+		s.userRepo.InvalidateUser(previousConfig.Credentials())
 	}
 
-	return s.agentRepo.SaveConfig(ctx, config)
+	return nil
 }
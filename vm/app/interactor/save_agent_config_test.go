@@ -0,0 +1,112 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSaveAgentConfig_Handle(t *testing.T) {
+	exited := "exited-container"
+	running := "running-container"
+	newConfig := func(apiKey string, targetContainer *string) *agent.Config {
+		return &agent.Config{
+			ID:              "new",
+			APIKey:          apiKey,
+			APISecret:       "secret",
+			ProjectName:     "project",
+			TargetContainer: targetContainer,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   *agent.Config
+		wantKind error
+		wantCode failure.Code
+	}{
+		{name: "valid config", config: newConfig("valid-key", &running)},
+		{
+			name: "credentials required",
+			config: &agent.Config{
+				ID:          "new",
+				APIKey:      agent.RedactedCredential,
+				APISecret:   agent.RedactedCredential,
+				ProjectName: "project",
+			},
+			wantKind: failure.ErrInvalid,
+			wantCode: failure.CodeCredentialsRequired,
+		},
+		{
+			name:     "rejected credentials",
+			config:   newConfig("revoked-key", nil),
+			wantKind: failure.ErrUnauthorized,
+			wantCode: failure.CodeInvalidCredentials,
+		},
+		{
+			name:     "unverified credentials",
+			config:   newConfig("unavailable-key", nil),
+			wantKind: failure.ErrUnavailable,
+			wantCode: failure.CodeAkitaAPIUnavailable,
+		},
+		{
+			name:     "target not running",
+			config:   newConfig("valid-key", &exited),
+			wantKind: failure.ErrUnprocessable,
+			wantCode: failure.CodeTargetNotRunning,
+		},
+		{
+			name:     "several violations",
+			config:   &agent.Config{ID: "new", APIKey: "valid-key", APISecret: "secret", TargetContainer: &exited},
+			wantKind: failure.ErrInvalid,
+			wantCode: failure.CodeInvalidAgentConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{}}
+			userRepo := &fakeUserRepo{
+				users: map[string]*user.User{"valid-key": {Email: "user@example.com"}},
+				errs: map[string]error{
+					"unavailable-key": failure.Unavailablef("the Akita API is down").
+						WithCode(failure.CodeAkitaAPIUnavailable),
+				},
+			}
+			containerRepo := &fakeContainerRepo{containers: []*container.Container{
+				{ID: running, State: container.StatusRunning},
+				{ID: exited, State: container.StatusExited},
+			}}
+			interactor := NewSaveAgentConfigInteractor(
+				agentRepo,
+				userRepo,
+				NewValidateAgentConfigInteractor(agentRepo, containerRepo, userRepo),
+			)
+
+			err := interactor.Handle(context.Background(), tt.config, agent.SaveOptions{})
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Fatalf("Handle() error = %v", err)
+				}
+				if _, ok := agentRepo.configs[tt.config.ID]; !ok {
+					t.Errorf("Handle() didn't save the config")
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Handle() error = %v, want %v", err, tt.wantKind)
+			}
+			if code := failure.From(err).Code; code != tt.wantCode {
+				t.Errorf("Handle() code = %s, want %s", code, tt.wantCode)
+			}
+			if _, ok := agentRepo.configs[tt.config.ID]; ok {
+				t.Errorf("Handle() saved an invalid config")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"akita/domain/settings"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+type SaveTelemetrySettings struct {
+	settingsRepo settings.Repository
+	outboxRepo   event.OutboxRepository
+	logger       *logrus.Logger
+}
+
+func NewSaveTelemetrySettingsInteractor(
+	settingsRepository settings.Repository,
+	outboxRepository event.OutboxRepository,
+	logger *logrus.Logger,
+) *SaveTelemetrySettings {
+	return &SaveTelemetrySettings{
+		settingsRepo: settingsRepository,
+		outboxRepo:   outboxRepository,
+		logger:       logger,
+	}
+}
+
+// Saves the user's decision about telemetry. If the user withdraws their
+// consent, the events that haven't been delivered yet are purged.
+func (s SaveTelemetrySettings) Handle(ctx context.Context, telemetry *settings.Telemetry) error {
+	if err := s.settingsRepo.SaveTelemetry(ctx, telemetry); err != nil {
+		return err
+	}
+
+	if telemetry.IsAllowed() {
+		return nil
+	}
+
+	purged, err := s.outboxRepo.Purge(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to purge queued analytics events: %w", err)
+	}
+	if purged > 0 {
+		s.logger.WithContext(ctx).Infof("Purged %d queued analytics events after telemetry consent was withdrawn", purged)
+	}
+
+	return nil
+}
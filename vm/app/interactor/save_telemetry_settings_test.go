@@ -0,0 +1,38 @@
+package interactor
+
+import (
+	"akita/domain/event"
+	"akita/domain/settings"
+	"context"
+	"testing"
+)
+
+func TestSaveTelemetrySettings_Handle(t *testing.T) {
+	tests := []struct {
+		name       string
+		consent    settings.Consent
+		wantQueued int
+	}{
+		{name: "consent granted", consent: settings.ConsentGranted, wantQueued: 2},
+		{name: "consent denied", consent: settings.ConsentDenied, wantQueued: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settingsRepo := &fakeSettingsRepo{telemetry: settings.NewUndecidedTelemetry()}
+			outboxRepo := &fakeOutboxRepo{events: []*event.QueuedEvent{{ID: "1"}, {ID: "2"}}}
+			interactor := NewSaveTelemetrySettingsInteractor(settingsRepo, outboxRepo, newTestLogger())
+
+			if err := interactor.Handle(context.Background(), &settings.Telemetry{Consent: tt.consent}); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if settingsRepo.telemetry.Consent != tt.consent {
+				t.Errorf("saved consent = %s, want %s", settingsRepo.telemetry.Consent, tt.consent)
+			}
+			if len(outboxRepo.events) != tt.wantQueued {
+				t.Errorf("queued events = %d, want %d", len(outboxRepo.events), tt.wantQueued)
+			}
+		})
+	}
+}
@@ -1,38 +1,48 @@
 package interactor
 
 import (
+	"akita/domain/agent"
 	"akita/domain/demo"
+	"akita/metrics"
 	"context"
 	"fmt"
 )
 
 type SendDemoTraffic struct {
-	retrieveAgentConfigHandler *RetrieveAgentConfig
-	demoRepo                   demo.DemoRepository
+	agentRepo agent.Repository
+	demoRepo  demo.DemoRepository
 }
 
 func NewSendDemoTrafficInteractor(
-	retrievalHandler *RetrieveAgentConfig,
+	agentRepository agent.Repository,
 	demoRepo demo.DemoRepository,
 ) *SendDemoTraffic {
 	return &SendDemoTraffic{
-		retrieveAgentConfigHandler: retrievalHandler,
-		demoRepo:                   demoRepo,
+		agentRepo: agentRepository,
+		demoRepo:  demoRepo,
 	}
 }
 
-// Sends demo traffic to the agent.
+// Sends demo traffic to the agent if any agent has demo mode enabled.
+// The target containers of the configs are checked by the reconciler rather
+// than on every tick.
 func (s SendDemoTraffic) Handle(ctx context.Context) error {
-	config, err := s.retrieveAgentConfigHandler.Handle(ctx)
+	configs, err := s.agentRepo.ListConfigs(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve agent config while checking if demo mode is enabled: %w", err)
+		return fmt.Errorf("failed to retrieve agent configs while checking if demo mode is enabled: %w", err)
 	}
 
-	if !config.IsDemoModeEnabled {
+	isDemoModeEnabled := false
+	for _, config := range configs {
+		isDemoModeEnabled = isDemoModeEnabled || config.IsDemoModeEnabled
+	}
+
+	if !isDemoModeEnabled {
 		return nil
 	}
 
 	err = s.demoRepo.SendMockTraffic()
+	metrics.DemoTrafficRequests.WithLabelValues(metrics.Outcome(err)).Inc()
 	if err != nil {
 		return fmt.Errorf("failed to send mock traffic to agent: %w", err)
 	}
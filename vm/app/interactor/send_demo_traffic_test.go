@@ -0,0 +1,54 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/demo"
+	"context"
+	"testing"
+)
+
+type fakeDemoRepo struct {
+	demo.DemoRepository
+	sent int
+}
+
+func (f *fakeDemoRepo) SendMockTraffic() error {
+	f.sent++
+	return nil
+}
+
+func TestSendDemoTraffic_Handle(t *testing.T) {
+	tests := []struct {
+		name     string
+		configs  map[string]*agent.Config
+		wantSent int
+	}{
+		{name: "no configs"},
+		{
+			name:    "demo mode disabled",
+			configs: map[string]*agent.Config{agent.DefaultConfigID: {ID: agent.DefaultConfigID, IsEnabled: true}},
+		},
+		{
+			name: "demo mode enabled for one config",
+			configs: map[string]*agent.Config{
+				agent.DefaultConfigID: {ID: agent.DefaultConfigID, IsEnabled: true},
+				"other":               {ID: "other", IsEnabled: true, IsDemoModeEnabled: true},
+			},
+			wantSent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			demoRepo := &fakeDemoRepo{}
+			interactor := NewSendDemoTrafficInteractor(&fakeAgentRepo{configs: tt.configs}, demoRepo)
+
+			if err := interactor.Handle(context.Background()); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if demoRepo.sent != tt.wantSent {
+				t.Errorf("sent %d requests, want %d", demoRepo.sent, tt.wantSent)
+			}
+		})
+	}
+}
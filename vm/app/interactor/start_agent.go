@@ -0,0 +1,73 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+)
+
+type StartAgent struct {
+	agentRepo          agent.Repository
+	agentContainerRepo agent.ContainerRepository
+	containerRepo      container.Repository
+}
+
+func NewStartAgentInteractor(
+	agentRepository agent.Repository,
+	agentContainerRepository agent.ContainerRepository,
+	containerRepository container.Repository,
+) *StartAgent {
+	return &StartAgent{
+		agentRepo:          agentRepository,
+		agentContainerRepo: agentContainerRepository,
+		containerRepo:      containerRepository,
+	}
+}
+
+// Starts the agent container of the agent configuration with the given ID and
+// marks the configuration as enabled. The source is recorded with the change.
+func (s StartAgent) Handle(ctx context.Context, configID string, source agent.ChangeSource) (*agent.Status, error) {
+	agentConfig, err := s.agentRepo.GetConfig(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+
+	if agentConfig.TargetContainer != nil {
+		containerExists, err := s.containerRepo.Exists(
+			ctx,
+			*agentConfig.TargetContainer,
+			optionals.Some(container.StatusRunning),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !containerExists {
+			return nil, failure.Unprocessablef(
+				"container %s does not exist or is not running",
+				*agentConfig.TargetContainer,
+			).WithCode(failure.CodeTargetNotRunning)
+		}
+	}
+
+	resolvedConfig, err := resolveTarget(ctx, s.containerRepo, agentConfig)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.Unprocessablef("no running container matches %s", agentConfig.TargetSelector).
+				WithCode(failure.CodeTargetNotFound)
+		}
+		return nil, err
+	}
+
+	if !agentConfig.IsEnabled {
+		agentConfig.IsEnabled = true
+		if err := s.agentRepo.SaveConfig(ctx, agentConfig, agent.SaveOptions{Source: source}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.agentContainerRepo.Start(ctx, resolvedConfig)
+}
@@ -0,0 +1,44 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"context"
+	"errors"
+)
+
+type StopAgent struct {
+	agentRepo          agent.Repository
+	agentContainerRepo agent.ContainerRepository
+}
+
+func NewStopAgentInteractor(
+	agentRepository agent.Repository,
+	agentContainerRepository agent.ContainerRepository,
+) *StopAgent {
+	return &StopAgent{
+		agentRepo:          agentRepository,
+		agentContainerRepo: agentContainerRepository,
+	}
+}
+
+// Stops and removes the agent container of the agent configuration with the
+// given ID and marks the configuration as disabled, if there is one. The
+// source is recorded with the change.
+func (s StopAgent) Handle(ctx context.Context, configID string, source agent.ChangeSource) error {
+	agentConfig, err := s.agentRepo.GetConfig(ctx, configID)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return err
+	}
+
+	if agentConfig != nil && agentConfig.IsEnabled {
+		// Demo mode can't be enabled while the agent is disabled.
+		agentConfig.IsEnabled = false
+		agentConfig.IsDemoModeEnabled = false
+		if err := s.agentRepo.SaveConfig(ctx, agentConfig, agent.SaveOptions{Source: source}); err != nil {
+			return err
+		}
+	}
+
+	return s.agentContainerRepo.Stop(ctx, configID)
+}
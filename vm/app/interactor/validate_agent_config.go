@@ -0,0 +1,148 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
+)
+
+type ValidateAgentConfig struct {
+	agentRepo     agent.Repository
+	containerRepo container.Repository
+	userRepo      user.Repository
+}
+
+func NewValidateAgentConfigInteractor(
+	agentRepository agent.Repository,
+	containerRepository container.Repository,
+	userRepository user.Repository,
+) *ValidateAgentConfig {
+	return &ValidateAgentConfig{
+		agentRepo:     agentRepository,
+		containerRepo: containerRepository,
+		userRepo:      userRepository,
+	}
+}
+
+// Reports every problem that would prevent the agent configuration from being
+// saved, without saving it. Redacted credentials are replaced by those of the
+// saved configuration with the same ID. Checks that need the Akita API or the
+// Docker daemon are reported as unverified if they fail to respond.
+func (v ValidateAgentConfig) Handle(ctx context.Context, config *agent.Config) (*agent.ValidationReport, error) {
+	report, _, err := v.validate(ctx, config)
+	return report, err
+}
+
+// Validates the configuration like Handle, and also returns the error that
+// prevented the first unverified check from being made, if any.
+func (v ValidateAgentConfig) validate(
+	ctx context.Context,
+	config *agent.Config,
+) (report *agent.ValidationReport, unverifiedErr error, err error) {
+	violations := []failure.FieldViolation{}
+	addViolation := func(field string, code failure.Code, message string) {
+		violations = append(violations, failure.FieldViolation{Field: field, Message: message, Code: code})
+	}
+
+	unverified := []failure.FieldViolation{}
+	addUnverified := func(err error, fields ...string) {
+		if unverifiedErr == nil {
+			unverifiedErr = err
+		}
+		fail := failure.From(err)
+		for _, field := range fields {
+			unverified = append(unverified, failure.FieldViolation{Field: field, Message: fail.Message, Code: fail.Code})
+		}
+	}
+
+	if config.HasRedactedCredentials() {
+		existingConfig, err := v.agentRepo.GetConfig(ctx, config.ID)
+		if err != nil && !errors.Is(err, failure.ErrNotFound) {
+			return nil, nil, err
+		}
+
+		if existingConfig != nil {
+			config.RestoreRedactedCredentials(existingConfig)
+		} else {
+			// The violations of the redacted fields are reported instead of their values.
+			if config.APIKey == agent.RedactedCredential {
+				addViolation(
+					"api_key",
+					failure.CodeCredentialsRequired,
+					"api key must be provided for new agent configurations",
+				)
+			}
+			if config.APISecret == agent.RedactedCredential {
+				addViolation(
+					"api_secret",
+					failure.CodeCredentialsRequired,
+					"api secret must be provided for new agent configurations",
+				)
+			}
+		}
+	}
+
+	violations = append(violations, config.Violations()...)
+
+	hasViolation := func(field string) bool {
+		for _, violation := range violations {
+			if violation.Field == field {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Check that the user exists.
+	if !hasViolation("api_key") && !hasViolation("api_secret") {
+		if _, err := v.userRepo.GetUser(ctx, config.Credentials()); err != nil {
+			// Credentials that are rejected or not allowed to access the API are
+			// reported as unauthorized, with a message telling which.
+			if errors.Is(err, failure.ErrUnauthorized) {
+				message := failure.From(err).Message
+				addViolation("api_key", failure.CodeInvalidCredentials, message)
+				addViolation("api_secret", failure.CodeInvalidCredentials, message)
+			} else {
+				addUnverified(err, "api_key", "api_secret")
+			}
+		}
+	}
+
+	if config.TargetSelector != nil && !hasViolation("target_selector") {
+		if _, err := v.containerRepo.Resolve(ctx, *config.TargetSelector); err != nil {
+			if errors.Is(err, failure.ErrNotFound) {
+				addViolation(
+					"target_selector",
+					failure.CodeTargetNotFound,
+					"no running container matches "+config.TargetSelector.String(),
+				)
+			} else {
+				addUnverified(err, "target_selector")
+			}
+		}
+	}
+
+	if config.TargetContainer != nil {
+		containerExists, err := v.containerRepo.Exists(
+			ctx,
+			*config.TargetContainer,
+			optionals.Some(container.StatusRunning),
+		)
+		if err != nil {
+			addUnverified(err, "target_container")
+		} else if !containerExists {
+			addViolation(
+				"target_container",
+				failure.CodeTargetNotRunning,
+				"container "+*config.TargetContainer+" does not exist or is not running",
+			)
+		}
+	}
+
+	report = &agent.ValidationReport{Valid: len(violations) == 0, Violations: violations, Unverified: unverified}
+	return report, unverifiedErr, nil
+}
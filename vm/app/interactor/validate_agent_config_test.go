@@ -0,0 +1,116 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateAgentConfig_Handle(t *testing.T) {
+	running := "running-container"
+	unavailable := failure.Unavailablef("the Akita API is down").WithCode(failure.CodeAkitaAPIUnavailable)
+	forbidden := failure.Unauthorizedf("the user is not allowed to access the Akita API").
+		WithCode(failure.CodeInvalidCredentials)
+
+	tests := []struct {
+		name          string
+		config        *agent.Config
+		containersErr error
+		// The codes of the violations and unverified checks, keyed by field.
+		wantViolations map[string]failure.Code
+		wantUnverified map[string]failure.Code
+	}{
+		{
+			name:   "valid",
+			config: &agent.Config{ID: "new", APIKey: "valid-key", APISecret: "secret", ProjectName: "project"},
+		},
+		{
+			name:   "forbidden credentials",
+			config: &agent.Config{ID: "new", APIKey: "forbidden-key", APISecret: "secret", ProjectName: "project"},
+			wantViolations: map[string]failure.Code{
+				"api_key":    failure.CodeInvalidCredentials,
+				"api_secret": failure.CodeInvalidCredentials,
+			},
+		},
+		{
+			name:           "api unavailable with structural violations",
+			config:         &agent.Config{ID: "new", APIKey: "unavailable-key", APISecret: "secret"},
+			wantViolations: map[string]failure.Code{"project_name": ""},
+			wantUnverified: map[string]failure.Code{
+				"api_key":    failure.CodeAkitaAPIUnavailable,
+				"api_secret": failure.CodeAkitaAPIUnavailable,
+			},
+		},
+		{
+			name: "docker unavailable",
+			config: &agent.Config{
+				ID:              "new",
+				APIKey:          "valid-key",
+				APISecret:       "secret",
+				ProjectName:     "project",
+				TargetContainer: &running,
+			},
+			containersErr:  errors.New("cannot connect to the docker daemon"),
+			wantUnverified: map[string]failure.Code{"target_container": failure.CodeInternal},
+		},
+		{
+			name: "selector unresolved while docker unavailable",
+			config: &agent.Config{
+				ID:             "new",
+				APIKey:         "valid-key",
+				APISecret:      "secret",
+				ProjectName:    "project",
+				TargetSelector: &container.Selector{Name: "api"},
+			},
+			containersErr:  failure.Unavailablef("docker is restarting"),
+			wantUnverified: map[string]failure.Code{"target_selector": failure.CodeUnavailable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentRepo := &fakeAgentRepo{configs: map[string]*agent.Config{}}
+			userRepo := &fakeUserRepo{
+				users: map[string]*user.User{"valid-key": {Email: "user@example.com"}},
+				errs:  map[string]error{"unavailable-key": unavailable, "forbidden-key": forbidden},
+			}
+			containerRepo := &fakeContainerRepo{
+				containers: []*container.Container{{ID: running, State: container.StatusRunning}},
+				err:        tt.containersErr,
+			}
+			interactor := NewValidateAgentConfigInteractor(agentRepo, containerRepo, userRepo)
+
+			report, err := interactor.Handle(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if violations := fieldCodes(report.Violations); !reflect.DeepEqual(violations, tt.wantViolations) {
+				t.Errorf("Handle() violations = %v, want %v", violations, tt.wantViolations)
+			}
+			if unverified := fieldCodes(report.Unverified); !reflect.DeepEqual(unverified, tt.wantUnverified) {
+				t.Errorf("Handle() unverified = %v, want %v", unverified, tt.wantUnverified)
+			}
+			if report.Valid != (len(tt.wantViolations) == 0) {
+				t.Errorf("Handle() valid = %v, want %v", report.Valid, len(tt.wantViolations) == 0)
+			}
+		})
+	}
+}
+
+// Returns the codes of the given violations keyed by field, or nil if there are none.
+func fieldCodes(violations []failure.FieldViolation) map[string]failure.Code {
+	if len(violations) == 0 {
+		return nil
+	}
+	result := map[string]failure.Code{}
+	for _, violation := range violations {
+		result[violation.Field] = violation.Code
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package interactor
+
+import (
+	"akita/domain/user"
+	"context"
+)
+
+type VerifyCredentials struct {
+	userRepo user.Repository
+}
+
+func NewVerifyCredentialsInteractor(userRepository user.Repository) *VerifyCredentials {
+	return &VerifyCredentials{
+		userRepo: userRepository,
+	}
+}
+
+// Verifies the given Akita API credentials and reports why verification
+// failed, if it did, along with the user the credentials belong to.
+func (v VerifyCredentials) Handle(ctx context.Context, credentials user.Credentials) (*user.Verification, error) {
+	if err := credentials.Validate(); err != nil {
+		return nil, err
+	}
+
+	return v.userRepo.VerifyCredentials(ctx, credentials)
+}
@@ -0,0 +1,82 @@
+package interactor
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"context"
+	"fmt"
+	"github.com/akitasoftware/go-utils/optionals"
+	"github.com/sirupsen/logrus"
+)
+
+type WatchTargetContainer struct {
+	agentRepo      agent.Repository
+	watcher        container.Watcher
+	reconcileAgent *ReconcileAgent
+	logger         *logrus.Logger
+}
+
+func NewWatchTargetContainerInteractor(
+	agentRepository agent.Repository,
+	watcher container.Watcher,
+	reconcileAgent *ReconcileAgent,
+	logger *logrus.Logger,
+) *WatchTargetContainer {
+	return &WatchTargetContainer{
+		agentRepo:      agentRepository,
+		watcher:        watcher,
+		reconcileAgent: reconcileAgent,
+		logger:         logger,
+	}
+}
+
+// Watches for state changes of the container targeted by the agent and
+// reconciles the agent as soon as they occur. Blocks until the context is
+// cancelled.
+func (w WatchTargetContainer) Handle(ctx context.Context) error {
+	for event := range w.watcher.Watch(ctx) {
+		if err := w.handleEvent(ctx, event); err != nil {
+			w.logger.WithContext(ctx).Errorf("Failed to handle event %s of container %s: %s", event.Action, event.ContainerID, err)
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (w WatchTargetContainer) handleEvent(ctx context.Context, event container.Event) error {
+	agentConfigs, err := w.agentRepo.ListConfigs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, agentConfig := range agentConfigs {
+		if !agentConfig.IsEnabled || !isTargetEvent(agentConfig, event) {
+			continue
+		}
+
+		options := ReconcileAgentOptions{ConfigID: optionals.Some(agentConfig.ID)}
+		if !event.IsTermination() {
+			// The agent shares the network namespace of the target, which is
+			// replaced when the target starts again.
+			options.RecreateReason = optionals.Some(fmt.Sprintf("targeted container received %s event", event.Action))
+		}
+
+		if _, err := w.reconcileAgent.Handle(ctx, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns true if the event concerns a container targeted by the agent config.
+func isTargetEvent(agentConfig *agent.Config, event container.Event) bool {
+	switch {
+	case agentConfig.TargetContainer != nil:
+		return event.Concerns(*agentConfig.TargetContainer)
+	case agentConfig.TargetSelector != nil:
+		return agentConfig.TargetSelector.Matches(event.ContainerName, event.Labels)
+	default:
+		return false
+	}
+}
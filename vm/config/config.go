@@ -2,12 +2,10 @@ package config
 
 import (
 	"akita/domain/host"
-	"flag"
 	"fmt"
 	"github.com/akitasoftware/akita-libs/analytics"
-	"github.com/akitasoftware/go-utils/optionals"
-	"gopkg.in/yaml.v3"
-	"os"
+	"net/url"
+	"time"
 )
 
 type Config struct {
@@ -18,65 +16,544 @@ type Config struct {
 	// The target architecture that the VM will run on.
 	targetArch string
 	// The analytics client config.
-	// If analytics are disabled, this will be None.
-	analytics optionals.Optional[analytics.Config]
+	analytics analytics.Config
+	// Whether analytics are sent to Segment.
+	analyticsEnabled bool
+	// The storage backend config.
+	storage StorageConfig
+	// The config of the Akita API client.
+	akitaAPI AkitaAPIConfig
+	// The config of the outbox of analytics events.
+	analyticsOutbox AnalyticsOutboxConfig
+	// The config of the sink that analytics events are delivered to.
+	analyticsSink AnalyticsSinkConfig
+	// The config of the policy that scrubs analytics events.
+	analyticsPolicy AnalyticsPolicyConfig
+	// The config of the demo server.
+	demoServer DemoServerConfig
+	// The config of logging.
+	log LogConfig
+	// The effective settings, after all layers and defaults were applied.
+	effective rawConfig
+	// Describes the layers that the settings were loaded from, in order.
+	sources []string
+	// The embedded application.yml, the flags and the layers applied over
+	// them, which the config is reloaded from.
+	embedded []byte
+	flags    Flags
+	layered  layeredConfig
 }
 
+// The backend that persists the extension's data.
+type StorageBackend string
+
+const (
+	// Stores data in a JSON file on a volume mounted into the VM.
+	StorageBackendFile StorageBackend = "file"
+	// Stores data in a MongoDB database.
+	StorageBackendMongo StorageBackend = "mongo"
+)
+
+type StorageConfig struct {
+	// The backend that persists the extension's data.
+	Backend StorageBackend `yaml:"backend"`
+	File    struct {
+		// Path to the JSON file that holds the extension's data.
+		Path string `yaml:"path"`
+	} `yaml:"file"`
+	Mongo struct {
+		// Connection string of the MongoDB server. When the file backend is
+		// used, data is migrated from this server if it is reachable.
+		URI string `yaml:"uri"`
+		// Name of the database that holds the extension's data.
+		Database string `yaml:"database"`
+	} `yaml:"mongo"`
+	// Path to the master key that encrypts credentials at rest. It is
+	// generated on first start and must be kept apart from the stored data.
+	EncryptionKeyPath string `yaml:"encryption_key_path"`
+}
+
+// Configures how the extension calls the Akita API.
+type AkitaAPIConfig struct {
+	// The URL that API paths are relative to.
+	BaseURL string `yaml:"base_url"`
+	// How long a single attempt of a request may take, including reading the response.
+	Timeout time.Duration `yaml:"timeout"`
+	Retry   struct {
+		// How many times a failed request is retried.
+		Count int `yaml:"count"`
+		// How long to wait before the first retry. The wait doubles with each retry.
+		WaitTime time.Duration `yaml:"wait_time"`
+		// The longest wait between retries.
+		MaxWaitTime time.Duration `yaml:"max_wait_time"`
+	} `yaml:"retry"`
+	CircuitBreaker struct {
+		// How many calls must fail in a row before calls fail fast.
+		FailureThreshold int `yaml:"failure_threshold"`
+		// How long calls fail fast before a trial call is made.
+		OpenDuration time.Duration `yaml:"open_duration"`
+	} `yaml:"circuit_breaker"`
+	UserCache struct {
+		// How long a user resolved through the API is cached.
+		TTL time.Duration `yaml:"ttl"`
+		// How long past its TTL a cached user is used while the API is unavailable.
+		MaxStale time.Duration `yaml:"max_stale"`
+	} `yaml:"user_cache"`
+	// The URL of the proxy that requests are sent through. If empty, the
+	// standard proxy environment variables are honored.
+	ProxyURL string `yaml:"proxy_url"`
+	// Path to a PEM bundle of CA certificates that are trusted in addition to
+	// the system's, e.g. that of a TLS-intercepting corporate proxy.
+	CABundlePath string `yaml:"ca_bundle_path"`
+}
+
+// Configures the outbox that persists analytics events until they are delivered.
+type AnalyticsOutboxConfig struct {
+	// How often queued events are delivered.
+	DeliveryInterval time.Duration `yaml:"delivery_interval"`
+	// The most events delivered at once.
+	BatchSize int `yaml:"batch_size"`
+	// Events that couldn't be delivered for this long are dropped.
+	MaxAge time.Duration `yaml:"max_age"`
+	Retry  struct {
+		// The backoff after the first failed delivery of an event. It doubles with each failure.
+		WaitTime time.Duration `yaml:"wait_time"`
+		// The longest backoff between deliveries of an event.
+		MaxWaitTime time.Duration `yaml:"max_wait_time"`
+	} `yaml:"retry"`
+}
+
+type AnalyticsSink string
+
+const (
+	// Sends analytics events to Segment.
+	AnalyticsSinkSegment AnalyticsSink = "segment"
+	// Appends analytics events to a rotating JSONL file.
+	AnalyticsSinkFile AnalyticsSink = "file"
+	// Keeps the most recent analytics events in memory.
+	AnalyticsSinkMemory AnalyticsSink = "memory"
+)
+
+// Configures where analytics events are delivered. The file and memory sinks
+// record events locally instead of sending them, so that they can be inspected.
+type AnalyticsSinkConfig struct {
+	Type AnalyticsSink `yaml:"type"`
+	File struct {
+		// Path to the JSONL file that events are appended to.
+		Path string `yaml:"path"`
+		// The size above which the file is rotated.
+		MaxSizeBytes int64 `yaml:"max_size_bytes"`
+		// How many rotated files are kept.
+		MaxFiles int `yaml:"max_files"`
+	} `yaml:"file"`
+	Memory struct {
+		// How many of the most recent events are kept.
+		Capacity int `yaml:"capacity"`
+	} `yaml:"memory"`
+}
+
+// Configures which analytics events are recorded and how their properties are
+// scrubbed of personal data.
+type AnalyticsPolicyConfig struct {
+	// The properties allowed for each known event, keyed by event name.
+	// Events with other names are rejected.
+	Events map[string][]string `yaml:"events"`
+	// Properties allowed for every known event.
+	CommonProperties []string `yaml:"common_properties"`
+	// Properties whose whole value is personal data, e.g. container names.
+	SensitiveProperties []string `yaml:"sensitive_properties"`
+	// Either "hash" or "drop". Emails, IP addresses and container names are
+	// replaced by their hash, or the properties containing them are dropped.
+	PII string `yaml:"pii"`
+	// The largest size of the JSON encoded properties of an event.
+	MaxPayloadBytes int `yaml:"max_payload_bytes"`
+}
+
+// Configures the server that serves demo traffic to the agent.
+type DemoServerConfig struct {
+	// The port that the demo server listens on.
+	Port int `yaml:"port"`
+	// How often demo traffic is sent while demo mode is enabled.
+	TrafficInterval time.Duration `yaml:"traffic_interval"`
+}
+
+// Configures logging.
+type LogConfig struct {
+	// The least severe level that is logged: debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Either text or json.
+	Format string `yaml:"format"`
+	// How many of the most recent log entries are kept in memory, to be served
+	// by GET /debug/logs. Zero disables the buffer.
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// Marks the size of the log buffer as unset, as zero is a valid size.
+const unsetLogBufferSize = -1
+
+// The schema of the config file. Each setting can also be set by an
+// environment variable or a command line flag; see layers.go.
 type rawConfig struct {
 	Analytics struct {
 		// Configures the analytics client.
 		analytics.Config `yaml:",inline"`
 		// Whether analytics are enabled.
-		Enabled bool `yaml:"enabled"`
+		Enabled bool                  `yaml:"enabled"`
+		Outbox  AnalyticsOutboxConfig `yaml:"outbox"`
+		Sink    AnalyticsSinkConfig   `yaml:"sink"`
+		Policy  AnalyticsPolicyConfig `yaml:"policy"`
 	} `yaml:"analytics"`
+	Storage    StorageConfig    `yaml:"storage"`
+	AkitaAPI   AkitaAPIConfig   `yaml:"akita_api"`
+	DemoServer DemoServerConfig `yaml:"demo_server"`
+	Log        LogConfig        `yaml:"log"`
+}
+
+// Parses the command line flags and loads the config from them and the given
+// embedded application.yml.
+func Parse(embedded []byte) (*Config, error) {
+	return Load(embedded, parseFlags())
 }
 
-func Parse(raw []byte) (*Config, error) {
-	var parsedConfig *rawConfig
+// Loads the config in layers, each overriding the previous one: the embedded
+// application.yml, the optional config file, the AKITA_EXT_* environment
+// variables and finally the command line flags. Defaults are then applied to
+// the unset settings, and the result is validated.
+func Load(embedded []byte, flags Flags) (*Config, error) {
+	layered, err := loadLayers(embedded, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return build(embedded, flags, layered)
+}
+
+// Applies defaults to the unset settings of the layered config and validates it.
+func build(embedded []byte, flags Flags, layered layeredConfig) (*Config, error) {
+	parsedConfig := layered.raw
+
+	akitaAPIConfig := parsedConfig.AkitaAPI
+	applyAkitaAPIDefaults(&akitaAPIConfig)
+	if err := validateAkitaAPIConfig(akitaAPIConfig); err != nil {
+		return nil, err
+	}
+
+	analyticsOutboxConfig, err := parseAnalyticsOutboxConfig(parsedConfig.Analytics.Outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsSinkConfig, err := parseAnalyticsSinkConfig(parsedConfig.Analytics.Sink)
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsPolicyConfig, err := parseAnalyticsPolicyConfig(parsedConfig.Analytics.Policy)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := yaml.Unmarshal(raw, &parsedConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	storageConfig, err := parseStorageConfig(parsedConfig.Storage)
+	if err != nil {
+		return nil, err
 	}
 
-	socketPath, targetOS, targetArch := parseFlags()
-	fmt.Printf("socket path: %s, target OS: %s, target arch: %s\n", socketPath, targetOS, targetArch)
+	demoServerConfig, err := parseDemoServerConfig(parsedConfig.DemoServer)
+	if err != nil {
+		return nil, err
+	}
 
-	analyticsConfig := optionals.Some(parsedConfig.Analytics.Config)
-	if !parsedConfig.Analytics.Enabled {
-		analyticsConfig = optionals.None[analytics.Config]()
+	logConfig, err := parseLogConfig(parsedConfig.Log)
+	if err != nil {
+		return nil, err
 	}
 
+	// Keep the effective settings, so that they can be inspected.
+	parsedConfig.AkitaAPI = akitaAPIConfig
+	parsedConfig.Analytics.Outbox = analyticsOutboxConfig
+	parsedConfig.Analytics.Sink = analyticsSinkConfig
+	parsedConfig.Analytics.Policy = analyticsPolicyConfig
+	parsedConfig.Storage = storageConfig
+	parsedConfig.DemoServer = demoServerConfig
+	parsedConfig.Log = logConfig
+
 	return &Config{
-		socketPath: socketPath,
-		targetOS:   targetOS,
-		targetArch: targetArch,
-		analytics:  analyticsConfig,
+		socketPath:       flags.SocketPath,
+		targetOS:         flags.TargetOS,
+		targetArch:       flags.TargetArch,
+		analytics:        parsedConfig.Analytics.Config,
+		analyticsEnabled: parsedConfig.Analytics.Enabled,
+		analyticsOutbox:  analyticsOutboxConfig,
+		analyticsSink:    analyticsSinkConfig,
+		analyticsPolicy:  analyticsPolicyConfig,
+		storage:          storageConfig,
+		akitaAPI:         akitaAPIConfig,
+		demoServer:       demoServerConfig,
+		log:              logConfig,
+		effective:        parsedConfig,
+		sources:          layered.sources,
+		embedded:         embedded,
+		flags:            flags,
+		layered:          layered,
 	}, nil
 }
 
-func parseFlags() (socketPath, targetOS, targetArch string) {
-	const defaultPlatformValue = "unknown"
+// Applies defaults to the parsed analytics outbox config and validates it.
+func parseAnalyticsOutboxConfig(outbox AnalyticsOutboxConfig) (AnalyticsOutboxConfig, error) {
+	if outbox.DeliveryInterval == 0 {
+		outbox.DeliveryInterval = 10 * time.Second
+	}
+	if outbox.BatchSize == 0 {
+		outbox.BatchSize = 50
+	}
+	if outbox.MaxAge == 0 {
+		outbox.MaxAge = 72 * time.Hour
+	}
+	if outbox.Retry.WaitTime == 0 {
+		outbox.Retry.WaitTime = 10 * time.Second
+	}
+	if outbox.Retry.MaxWaitTime == 0 {
+		outbox.Retry.MaxWaitTime = 10 * time.Minute
+	}
+
+	if outbox.DeliveryInterval < 0 || outbox.MaxAge < 0 || outbox.Retry.WaitTime < 0 || outbox.Retry.MaxWaitTime < 0 {
+		return outbox, fmt.Errorf("analytics.outbox durations must not be negative")
+	}
+	if outbox.BatchSize < 0 {
+		return outbox, fmt.Errorf("analytics.outbox.batch_size must not be negative")
+	}
+
+	return outbox, nil
+}
+
+// Applies defaults to the parsed analytics sink config and validates it.
+func parseAnalyticsSinkConfig(sink AnalyticsSinkConfig) (AnalyticsSinkConfig, error) {
+	if sink.Type == "" {
+		sink.Type = AnalyticsSinkSegment
+	}
+	if sink.File.Path == "" {
+		sink.File.Path = "/data/analytics-events.jsonl"
+	}
+	if sink.File.MaxSizeBytes == 0 {
+		sink.File.MaxSizeBytes = 10 * 1024 * 1024
+	}
+	if sink.File.MaxFiles == 0 {
+		sink.File.MaxFiles = 3
+	}
+	if sink.Memory.Capacity == 0 {
+		sink.Memory.Capacity = 1000
+	}
+
+	switch sink.Type {
+	case AnalyticsSinkSegment:
+	case AnalyticsSinkFile:
+		if sink.File.MaxSizeBytes < 0 || sink.File.MaxFiles < 0 {
+			return sink, fmt.Errorf("analytics.sink.file limits must not be negative")
+		}
+	case AnalyticsSinkMemory:
+		if sink.Memory.Capacity < 0 {
+			return sink, fmt.Errorf("analytics.sink.memory.capacity must not be negative")
+		}
+	default:
+		return sink, fmt.Errorf("unknown analytics sink %q", sink.Type)
+	}
+
+	return sink, nil
+}
+
+// Applies defaults to the parsed analytics policy config and validates it.
+func parseAnalyticsPolicyConfig(policy AnalyticsPolicyConfig) (AnalyticsPolicyConfig, error) {
+	if policy.PII == "" {
+		policy.PII = "hash"
+	}
+	if policy.MaxPayloadBytes == 0 {
+		policy.MaxPayloadBytes = 4096
+	}
+
+	if policy.PII != "hash" && policy.PII != "drop" {
+		return policy, fmt.Errorf("unknown analytics.policy.pii action %q", policy.PII)
+	}
+	if policy.MaxPayloadBytes < 0 {
+		return policy, fmt.Errorf("analytics.policy.max_payload_bytes must not be negative")
+	}
+
+	return policy, nil
+}
+
+// Applies defaults to the parsed demo server config and validates it.
+func parseDemoServerConfig(demoServer DemoServerConfig) (DemoServerConfig, error) {
+	if demoServer.Port == 0 {
+		demoServer.Port = 8080
+	}
+	if demoServer.TrafficInterval == 0 {
+		demoServer.TrafficInterval = time.Second
+	}
+
+	if demoServer.Port < 1 || demoServer.Port > 65535 {
+		return demoServer, fmt.Errorf("demo_server.port must be between 1 and 65535, got %d", demoServer.Port)
+	}
+	if demoServer.TrafficInterval < 0 {
+		return demoServer, fmt.Errorf("demo_server.traffic_interval must not be negative")
+	}
+
+	return demoServer, nil
+}
+
+// Applies defaults to the parsed log config and validates it.
+func parseLogConfig(logConfig LogConfig) (LogConfig, error) {
+	if logConfig.Level == "" {
+		logConfig.Level = "info"
+	}
+	if logConfig.Format == "" {
+		logConfig.Format = "text"
+	}
+	if logConfig.BufferSize == unsetLogBufferSize {
+		logConfig.BufferSize = 1000
+	}
 
-	flag.StringVar(&socketPath, "socket", "/run/guest/volumes-service.sock", "Unix domain socket to listen on")
-	flag.StringVar(&targetOS, "os", defaultPlatformValue, "Target OS that the vm will run on")
-	flag.StringVar(&targetArch, "arch", defaultPlatformValue, "Target architecture that the vm will run on")
-	flag.Parse()
+	switch logConfig.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return logConfig, fmt.Errorf("unknown log.level %q", logConfig.Level)
+	}
+	switch logConfig.Format {
+	case "text", "json":
+	default:
+		return logConfig, fmt.Errorf("unknown log.format %q", logConfig.Format)
+	}
+	if logConfig.BufferSize < 0 {
+		return logConfig, fmt.Errorf("log.buffer_size must not be negative")
+	}
+
+	return logConfig, nil
+}
+
+// Applies defaults to the parsed storage config and validates it.
+func parseStorageConfig(storage StorageConfig) (StorageConfig, error) {
+	if storage.Backend == "" {
+		storage.Backend = StorageBackendFile
+	}
+	if storage.EncryptionKeyPath == "" {
+		storage.EncryptionKeyPath = "/keys/akita-extension.key"
+	}
+	if storage.File.Path == "" {
+		storage.File.Path = "/data/akita-extension.json"
+	}
+	if storage.Mongo.Database == "" {
+		storage.Mongo.Database = "akitaExtension"
+	}
+
+	switch storage.Backend {
+	case StorageBackendFile:
+	case StorageBackendMongo:
+		if storage.Mongo.URI == "" {
+			return storage, fmt.Errorf("storage.mongo.uri is required when using the mongo storage backend")
+		}
+	default:
+		return storage, fmt.Errorf("unknown storage backend %q", storage.Backend)
+	}
+
+	return storage, nil
+}
+
+// Applies defaults to the unset settings of the Akita API config.
+func applyAkitaAPIDefaults(akitaAPI *AkitaAPIConfig) {
+	if akitaAPI.BaseURL == "" {
+		akitaAPI.BaseURL = "https://api.akita.software"
+	}
+	if akitaAPI.Timeout == 0 {
+		akitaAPI.Timeout = 10 * time.Second
+	}
+	if akitaAPI.Retry.WaitTime == 0 {
+		akitaAPI.Retry.WaitTime = 500 * time.Millisecond
+	}
+	if akitaAPI.Retry.MaxWaitTime == 0 {
+		akitaAPI.Retry.MaxWaitTime = 5 * time.Second
+	}
+	if akitaAPI.CircuitBreaker.FailureThreshold == 0 {
+		akitaAPI.CircuitBreaker.FailureThreshold = 5
+	}
+	if akitaAPI.CircuitBreaker.OpenDuration == 0 {
+		akitaAPI.CircuitBreaker.OpenDuration = 30 * time.Second
+	}
+	if akitaAPI.UserCache.TTL == 0 {
+		akitaAPI.UserCache.TTL = 10 * time.Minute
+	}
+	if akitaAPI.UserCache.MaxStale == 0 {
+		akitaAPI.UserCache.MaxStale = time.Hour
+	}
+}
 
-	_ = os.RemoveAll(socketPath)
+func validateAkitaAPIConfig(akitaAPI AkitaAPIConfig) error {
+	baseURL, err := url.Parse(akitaAPI.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return fmt.Errorf("akita_api.base_url must be an absolute http or https URL, got %q", akitaAPI.BaseURL)
+	}
+	if akitaAPI.ProxyURL != "" {
+		if _, err := url.Parse(akitaAPI.ProxyURL); err != nil {
+			return fmt.Errorf("invalid akita_api.proxy_url %q: %w", akitaAPI.ProxyURL, err)
+		}
+	}
+	if akitaAPI.Timeout < 0 {
+		return fmt.Errorf("akita_api.timeout must not be negative")
+	}
+	if akitaAPI.Retry.Count < 0 {
+		return fmt.Errorf("akita_api.retry.count must not be negative")
+	}
+	if akitaAPI.CircuitBreaker.FailureThreshold < 1 {
+		return fmt.Errorf("akita_api.circuit_breaker.failure_threshold must be positive")
+	}
 
-	return
+	return nil
 }
 
-// Returns a analytics client config parsed from the command line flags.
-// If no Segment write key was provided, then an empty config and false will be returned.
+// Returns the analytics client config, and whether analytics are sent to Segment.
 func (c Config) AnalyticsConfig() (analytics.Config, bool) {
-	return c.analytics.Get()
+	return c.analytics, c.analyticsEnabled
+}
+
+func (c Config) AnalyticsOutboxConfig() AnalyticsOutboxConfig {
+	return c.analyticsOutbox
+}
+
+func (c Config) AnalyticsSinkConfig() AnalyticsSinkConfig {
+	return c.analyticsSink
+}
+
+func (c Config) AnalyticsPolicyConfig() AnalyticsPolicyConfig {
+	return c.analyticsPolicy
+}
+
+func (c Config) StorageConfig() StorageConfig {
+	return c.storage
+}
+
+func (c Config) AkitaAPIConfig() AkitaAPIConfig {
+	return c.akitaAPI
+}
+
+func (c Config) DemoServerConfig() DemoServerConfig {
+	return c.demoServer
+}
+
+func (c Config) LogConfig() LogConfig {
+	return c.log
 }
 
 func (c Config) SocketPath() string {
 	return c.socketPath
 }
 
+// Whether the process only checks the readiness of the running backend.
+func (c Config) IsHealthCheck() bool {
+	return c.flags.HealthCheck
+}
+
+// Returns the path of the config file, or an empty string if there is none.
+func (c Config) ConfigPath() string {
+	return c.layered.configPath
+}
+
 // Returns information about the target platform that the VM will run on.
 func (c Config) TargetPlatform() *host.TargetPlatform {
 	return &host.TargetPlatform{
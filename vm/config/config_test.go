@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestLoad_LogConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		embedded string
+		settings []FlagSetting
+		want     LogConfig
+		wantErr  bool
+	}{
+		{
+			name: "defaults",
+			want: LogConfig{Level: "info", Format: "text", BufferSize: 1000},
+		},
+		{
+			name:     "configured",
+			embedded: "log:\n  level: debug\n  format: json\n  buffer_size: 50\n",
+			want:     LogConfig{Level: "debug", Format: "json", BufferSize: 50},
+		},
+		{
+			name:     "buffer disabled",
+			embedded: "log:\n  buffer_size: 0\n",
+			want:     LogConfig{Level: "info", Format: "text", BufferSize: 0},
+		},
+		{
+			name:     "buffer disabled by a flag",
+			embedded: "log:\n  buffer_size: 50\n",
+			settings: []FlagSetting{{Flag: "set", Path: "log.buffer_size", Value: "0"}},
+			want:     LogConfig{Level: "info", Format: "text", BufferSize: 0},
+		},
+		{
+			name:     "negative buffer size",
+			embedded: "log:\n  buffer_size: -5\n",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown level",
+			embedded: "log:\n  level: verbose\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := Load([]byte(tt.embedded), Flags{Settings: tt.settings})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := loaded.LogConfig(); got != tt.want {
+				t.Errorf("LogConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+)
+
+// The placeholder that replaces secrets in the effective config.
+const redactedSecret = "********"
+
+var (
+	// Settings whose values are secrets.
+	secretSettings = map[string]bool{
+		"analytics.segment_write_key": true,
+		"analytics.mixpanel_token":    true,
+		"analytics.mixpanel_secret":   true,
+	}
+	// Settings whose values are URLs that may contain a password.
+	urlSettings = map[string]bool{
+		"storage.mongo.uri":   true,
+		"akita_api.proxy_url": true,
+	}
+)
+
+// The effective config, with secrets redacted.
+type Effective struct {
+	// Describes the layers that the settings were loaded from, in order.
+	Sources    []string `json:"sources"`
+	SocketPath string   `json:"socket_path"`
+	TargetOS   string   `json:"target_os"`
+	TargetArch string   `json:"target_arch"`
+	// The effective settings keyed by their path in the config file.
+	Settings map[string]any `json:"settings"`
+}
+
+// Returns the effective config with secrets redacted.
+func (c Config) Effective() *Effective {
+	effective := c.effective
+	settings := map[string]any{}
+	for path, value := range settingsOf(&effective) {
+		settings[path] = redactSetting(path, value)
+	}
+
+	return &Effective{
+		Sources:    c.sources,
+		SocketPath: c.socketPath,
+		TargetOS:   c.targetOS,
+		TargetArch: c.targetArch,
+		Settings:   settings,
+	}
+}
+
+func redactSetting(path string, value reflect.Value) any {
+	if value.Type() == durationType {
+		return value.Interface().(interface{ String() string }).String()
+	}
+
+	if value.Kind() != reflect.String || value.String() == "" {
+		return value.Interface()
+	}
+
+	switch {
+	case secretSettings[path]:
+		return redactedSecret
+	case urlSettings[path]:
+		parsed, err := url.Parse(value.String())
+		if err != nil {
+			// The URL may not even be parsed far enough to find its password.
+			return redactedSecret
+		}
+		return parsed.Redacted()
+	default:
+		return value.String()
+	}
+}
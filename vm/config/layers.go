@@ -0,0 +1,341 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The prefix of the environment variables that override settings. The
+	// variable of a setting is named after its path in the config file, e.g.
+	// AKITA_EXT_STORAGE_MONGO_URI overrides storage.mongo.uri.
+	envPrefix = "AKITA_EXT_"
+	// The environment variable naming the config file, if the flag doesn't.
+	configFileEnv = envPrefix + "CONFIG_FILE"
+)
+
+// Environment variables that were supported before every setting could be set
+// from the environment, mapped to the path of the setting they override.
+var envAliases = map[string]string{
+	envPrefix + "API_URL":         "akita_api.base_url",
+	envPrefix + "API_PROXY_URL":   "akita_api.proxy_url",
+	envPrefix + "API_CA_BUNDLE":   "akita_api.ca_bundle_path",
+	envPrefix + "API_TIMEOUT":     "akita_api.timeout",
+	envPrefix + "API_RETRY_COUNT": "akita_api.retry.count",
+}
+
+// The command line flags.
+type Flags struct {
+	// Path to a config file that overrides the embedded application.yml.
+	ConfigPath string
+	// Path to the unix domain socket to listen on.
+	SocketPath string
+	// The target OS that the VM will run on.
+	TargetOS string
+	// The target architecture that the VM will run on.
+	TargetArch string
+	// Whether to check the readiness of the backend listening on the socket
+	// and exit, instead of starting one.
+	HealthCheck bool
+	// Settings set by flags, in the order they were given. They override all
+	// other layers.
+	Settings []FlagSetting
+}
+
+// A setting set by a command line flag.
+type FlagSetting struct {
+	// The name of the flag that set it.
+	Flag string
+	// The path of the setting in the config file, e.g. "akita_api.timeout".
+	Path  string
+	Value string
+}
+
+// Parses the command line flags. Besides the generic -set flag, some
+// settings have a dedicated flag.
+func parseFlags() Flags {
+	const defaultPlatformValue = "unknown"
+
+	var flags Flags
+	settingFlag := func(name string, path string, usage string) {
+		flag.Func(name, usage, func(value string) error {
+			flags.Settings = append(flags.Settings, FlagSetting{Flag: name, Path: path, Value: value})
+			return nil
+		})
+	}
+
+	flag.StringVar(&flags.ConfigPath, "config", "", "Config file overriding the embedded application.yml")
+	flag.StringVar(&flags.SocketPath, "socket", "/run/guest/volumes-service.sock", "Unix domain socket to listen on")
+	flag.StringVar(&flags.TargetOS, "os", defaultPlatformValue, "Target OS that the vm will run on")
+	flag.StringVar(&flags.TargetArch, "arch", defaultPlatformValue, "Target architecture that the vm will run on")
+	flag.BoolVar(&flags.HealthCheck, "healthcheck", false, "Check the readiness of the backend listening on the socket and exit")
+	settingFlag("akita-api-url", "akita_api.base_url", "Base URL of the Akita API")
+	settingFlag("akita-api-timeout", "akita_api.timeout", "Timeout of Akita API requests")
+	settingFlag("akita-api-retry-count", "akita_api.retry.count", "Retries of failed Akita API requests")
+	settingFlag("akita-api-proxy", "akita_api.proxy_url", "Proxy to send Akita API requests through")
+	settingFlag("akita-api-ca-bundle", "akita_api.ca_bundle_path", "PEM bundle of additional trusted CAs")
+	flag.Func("set", "Sets a setting of the config file, as path=value, e.g. storage.backend=mongo", func(value string) error {
+		path, settingValue, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected path=value, got %q", value)
+		}
+		flags.Settings = append(flags.Settings, FlagSetting{Flag: "set", Path: path, Value: settingValue})
+		return nil
+	})
+	flag.Parse()
+
+	return flags
+}
+
+// The config file and the settings of the environment and flags, applied
+// over the embedded application.yml, before defaults are applied.
+type layeredConfig struct {
+	raw rawConfig
+	// Describes the layers that the settings were loaded from, in order.
+	sources []string
+	// The path of the config file, if any.
+	configPath string
+}
+
+// Applies the layers of the config over the embedded application.yml.
+func loadLayers(embedded []byte, flags Flags) (layeredConfig, error) {
+	layered := layeredConfig{sources: []string{"embedded application.yml"}}
+	layered.raw.Log.BufferSize = unsetLogBufferSize
+
+	if err := decodeStrict(embedded, &layered.raw); err != nil {
+		return layered, fmt.Errorf("failed to parse embedded config: %w", err)
+	}
+
+	configPath, err := applyConfigFile(&layered.raw, flags)
+	if err != nil {
+		return layered, err
+	}
+	if configPath != "" {
+		layered.configPath = configPath
+		layered.sources = append(layered.sources, "file "+configPath)
+	}
+
+	envSources, err := applyEnv(&layered.raw)
+	if err != nil {
+		return layered, err
+	}
+	layered.sources = append(layered.sources, envSources...)
+
+	flagSources, err := applyFlags(&layered.raw, flags)
+	if err != nil {
+		return layered, err
+	}
+	layered.sources = append(layered.sources, flagSources...)
+
+	return layered, nil
+}
+
+// Decodes the given YAML onto the config, rejecting settings that aren't
+// part of its schema.
+func decodeStrict(raw []byte, parsedConfig *rawConfig) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(parsedConfig); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// Returns the path of the config file given by the flags or the environment,
+// or an empty string if there is none.
+func configFilePath(flags Flags) string {
+	if flags.ConfigPath != "" {
+		return flags.ConfigPath
+	}
+	return os.Getenv(configFileEnv)
+}
+
+// Applies the config file given by the flags or the environment, if any, and
+// returns its path.
+func applyConfigFile(parsedConfig *rawConfig, flags Flags) (string, error) {
+	configPath := configFilePath(flags)
+	if configPath == "" {
+		return "", nil
+	}
+
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := decodeStrict(raw, parsedConfig); err != nil {
+		return "", fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	return configPath, nil
+}
+
+// Applies the settings set in the environment and describes where each came from.
+func applyEnv(parsedConfig *rawConfig) ([]string, error) {
+	settings := settingsOf(parsedConfig)
+
+	envNames := map[string]string{}
+	for path := range settings {
+		envNames[envName(path)] = path
+	}
+
+	var unknown []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, envPrefix) || name == configFileEnv {
+			continue
+		}
+		if _, ok := envNames[name]; ok {
+			continue
+		}
+		if _, ok := envAliases[name]; ok {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown environment variables %s", strings.Join(unknown, ", "))
+	}
+
+	var sources []string
+	// Aliases are applied first, so that the variables named after the setting win.
+	for _, names := range [][]string{sortedKeys(envAliases), sortedKeys(envNames)} {
+		for _, name := range names {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+
+			path, ok := envAliases[name]
+			if !ok {
+				path = envNames[name]
+			}
+
+			if err := setSetting(settings, path, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			sources = append(sources, "env "+name)
+		}
+	}
+
+	return sources, nil
+}
+
+// Applies the settings set by flags and describes where each came from.
+func applyFlags(parsedConfig *rawConfig, flags Flags) ([]string, error) {
+	settings := settingsOf(parsedConfig)
+
+	var sources []string
+	for _, setting := range flags.Settings {
+		if err := setSetting(settings, setting.Path, setting.Value); err != nil {
+			return nil, fmt.Errorf("invalid -%s flag: %w", setting.Flag, err)
+		}
+		sources = append(sources, fmt.Sprintf("flag -%s %s", setting.Flag, setting.Path))
+	}
+
+	return sources, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Returns the settings of the config keyed by their path in the config file.
+func settingsOf(parsedConfig *rawConfig) map[string]reflect.Value {
+	settings := map[string]reflect.Value{}
+	collectSettings(reflect.ValueOf(parsedConfig).Elem(), "", settings)
+	return settings
+}
+
+func collectSettings(value reflect.Value, prefix string, settings map[string]reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("yaml")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		fieldValue := value.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			if options == "inline" {
+				collectSettings(fieldValue, prefix, settings)
+			} else {
+				collectSettings(fieldValue, prefix+name+".", settings)
+			}
+			continue
+		}
+
+		settings[prefix+name] = fieldValue
+	}
+}
+
+// Sets the setting at the given path from its string representation. Lists
+// are given as comma separated values. Maps can only be set in config files.
+func setSetting(settings map[string]reflect.Value, path string, value string) error {
+	setting, ok := settings[path]
+	if !ok {
+		return fmt.Errorf("unknown setting %q", path)
+	}
+
+	switch {
+	case setting.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		setting.SetInt(int64(duration))
+	case setting.Kind() == reflect.String:
+		setting.SetString(value)
+	case setting.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		setting.SetBool(parsed)
+	case setting.CanInt():
+		parsed, err := strconv.ParseInt(value, 10, setting.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		setting.SetInt(parsed)
+	case setting.Kind() == reflect.Slice && setting.Type().Elem().Kind() == reflect.String:
+		parts := []string{}
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		setting.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("%s can only be set in a config file", path)
+	}
+
+	return nil
+}
+
+// Returns the name of the environment variable that overrides the setting at the given path.
+func envName(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
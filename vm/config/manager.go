@@ -0,0 +1,269 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Settings that take effect without restarting the extension.
+var reloadableSettings = map[string]bool{
+	"log.level":                    true,
+	"log.format":                   true,
+	"demo_server.traffic_interval": true,
+	"analytics.enabled":            true,
+	"akita_api.base_url":           true,
+}
+
+// Notified when the config changes at runtime. Subscribers are notified one
+// at a time and must not update the config themselves.
+type Subscriber func(previous *Config, current *Config)
+
+// Holds the current config and reloads it when the config file changes or
+// settings are updated at runtime, notifying subscribers of the changes.
+// Only reloadable settings change at runtime; changes of other settings in the
+// config file take effect after a restart.
+type Manager struct {
+	mu      sync.Mutex
+	current *Config
+	// The layers of the current config, with the settings that require a
+	// restart pinned to their values at startup.
+	layered layeredConfig
+	// Settings updated at runtime, keyed by their path. They override all
+	// other layers until a restart.
+	runtimeSettings map[string]string
+	subscribers     []Subscriber
+	logger          *logrus.Logger
+}
+
+func NewManager(current *Config, logger *logrus.Logger) *Manager {
+	return &Manager{
+		current:         current,
+		logger:          logger,
+		layered:         current.layered,
+		runtimeSettings: map[string]string{},
+	}
+}
+
+func (m *Manager) Current() *Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.current
+}
+
+func (m *Manager) Subscribe(subscriber Subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscribers = append(m.subscribers, subscriber)
+}
+
+// Updates the given reloadable settings, keyed by their path, and returns the
+// resulting config. The settings are given in the same format as environment
+// variables. Updated settings are kept until a restart.
+func (m *Manager) Update(settings map[string]string) (*Config, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runtimeSettings := map[string]string{}
+	for path, value := range m.runtimeSettings {
+		runtimeSettings[path] = value
+	}
+	for path, value := range settings {
+		if _, ok := settingsOf(&m.current.effective)[path]; !ok {
+			return nil, fmt.Errorf("unknown setting %q", path)
+		}
+		if !reloadableSettings[path] {
+			return nil, fmt.Errorf("%s can't be changed without a restart", path)
+		}
+		runtimeSettings[path] = value
+	}
+
+	if _, err := m.apply(m.layered, runtimeSettings); err != nil {
+		return nil, err
+	}
+
+	return m.current, nil
+}
+
+// Reloads the config file and the environment. Returns the settings that
+// changed but require a restart to take effect.
+func (m *Manager) Reload() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	layered, err := loadLayers(m.current.embedded, m.current.flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(layered, m.runtimeSettings)
+}
+
+// Reloads the config whenever the config file changes, until the context is
+// cancelled. The file is polled at the given interval. Does nothing if there
+// is no config file.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	configPath := configFilePath(m.Current().flags)
+	if configPath == "" {
+		return
+	}
+
+	lastInfo, _ := os.Stat(configPath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(configPath)
+		if err != nil || (lastInfo != nil && info.ModTime().Equal(lastInfo.ModTime()) && info.Size() == lastInfo.Size()) {
+			continue
+		}
+		lastInfo = info
+
+		restartRequired, err := m.Reload()
+		if err != nil {
+			m.logger.Errorf("Failed to reload config file %s, keeping the current config: %v", configPath, err)
+			continue
+		}
+		if len(restartRequired) > 0 {
+			m.logger.Warnf("Changes of %v in config file %s take effect after a restart", restartRequired, configPath)
+		}
+	}
+}
+
+// Builds the config from the given layers and runtime settings, and makes it
+// the current config. The settings that require a restart keep their current
+// values. Must be called with the lock held.
+func (m *Manager) apply(layered layeredConfig, runtimeSettings map[string]string) ([]string, error) {
+	candidate, err := m.build(layered, runtimeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	var restartRequired []string
+	for _, path := range changedSettings(&m.current.effective, &candidate.effective) {
+		if !reloadableSettings[path] {
+			restartRequired = append(restartRequired, path)
+		}
+	}
+	if len(restartRequired) > 0 {
+		currentSettings, layeredSettings := settingsOf(&m.current.effective), settingsOf(&layered.raw)
+		for _, path := range restartRequired {
+			layeredSettings[path].Set(currentSettings[path])
+		}
+
+		if candidate, err = m.build(layered, runtimeSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := m.current
+	m.current = candidate
+	m.layered = layered
+	m.runtimeSettings = runtimeSettings
+
+	if len(changedSettings(&previous.effective, &candidate.effective)) > 0 {
+		for _, subscriber := range m.subscribers {
+			subscriber(previous, candidate)
+		}
+	}
+
+	return restartRequired, nil
+}
+
+// Builds the config from the given layers with the runtime settings applied
+// over them.
+func (m *Manager) build(layered layeredConfig, runtimeSettings map[string]string) (*Config, error) {
+	withRuntimeSettings := layered
+	withRuntimeSettings.sources = append([]string{}, layered.sources...)
+
+	paths := make([]string, 0, len(runtimeSettings))
+	for path := range runtimeSettings {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	settings := settingsOf(&withRuntimeSettings.raw)
+	for _, path := range paths {
+		if err := setSetting(settings, path, runtimeSettings[path]); err != nil {
+			return nil, err
+		}
+		withRuntimeSettings.sources = append(withRuntimeSettings.sources, "runtime "+path)
+	}
+
+	candidate, err := build(m.current.embedded, m.current.flags, withRuntimeSettings)
+	if err != nil {
+		return nil, err
+	}
+	// Runtime settings are reapplied on every reload rather than being part of the layers.
+	candidate.layered = layered
+
+	return candidate, nil
+}
+
+// Returns the paths of the settings whose values differ between the configs.
+func changedSettings(previous *rawConfig, current *rawConfig) []string {
+	currentSettings := settingsOf(current)
+
+	var changed []string
+	for path, previousValue := range settingsOf(previous) {
+		if !reflect.DeepEqual(previousValue.Interface(), currentSettings[path].Interface()) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Decodes settings keyed by their path from a JSON object. Values are
+// converted to the format of environment variables, e.g. durations are given
+// as strings such as "5s".
+func DecodeSettings(r io.Reader) (map[string]string, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var payload map[string]any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+	for path, value := range payload {
+		switch value.(type) {
+		case string, bool, json.Number:
+			settings[path] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("%s must be a string, number or boolean", path)
+		}
+	}
+
+	return settings, nil
+}
+
+// Returns the current values of the settings that can be changed at runtime,
+// keyed by their path.
+func (c Config) ReloadableSettings() map[string]any {
+	result := map[string]any{}
+	for path, value := range c.Effective().Settings {
+		if reloadableSettings[path] {
+			result[path] = value
+		}
+	}
+	return result
+}
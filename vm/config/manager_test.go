@@ -0,0 +1,264 @@
+package config
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns a manager of the config loaded from the test embedded config and a
+// config file with the given contents, along with the path of the file.
+func newTestManager(t *testing.T, configFile string) (*Manager, string) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	writeConfigFile(t, configPath, configFile)
+
+	loaded, err := Load([]byte(testEmbeddedConfig), Flags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewManager(loaded, logger), configPath
+}
+
+func writeConfigFile(t *testing.T, path string, contents string) {
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Update(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     map[string]string
+		wantLevel    string
+		wantNotified bool
+		wantErr      string
+	}{
+		{
+			name:         "reloadable setting",
+			settings:     map[string]string{"log.level": "debug"},
+			wantLevel:    "debug",
+			wantNotified: true,
+		},
+		{
+			name:      "unchanged setting",
+			settings:  map[string]string{"log.level": "info"},
+			wantLevel: "info",
+		},
+		{
+			name:     "setting that requires a restart",
+			settings: map[string]string{"storage.backend": "mongo"},
+			wantErr:  "storage.backend can't be changed without a restart",
+		},
+		{
+			name:     "unknown setting",
+			settings: map[string]string{"log.colour": "red"},
+			wantErr:  `unknown setting "log.colour"`,
+		},
+		{
+			name:     "invalid value",
+			settings: map[string]string{"log.level": "verbose"},
+			wantErr:  `unknown log.level "verbose"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := newTestManager(t, "")
+			notified := false
+			manager.Subscribe(func(previous *Config, current *Config) {
+				notified = true
+			})
+
+			updated, err := manager.Update(tt.settings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Update() error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				if manager.Current().LogConfig().Level != "info" {
+					t.Errorf("Update() changed the config despite failing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			if updated.LogConfig().Level != tt.wantLevel || manager.Current().LogConfig().Level != tt.wantLevel {
+				t.Errorf("log level = %s, want %s", manager.Current().LogConfig().Level, tt.wantLevel)
+			}
+			if notified != tt.wantNotified {
+				t.Errorf("subscriber notified = %v, want %v", notified, tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	tests := []struct {
+		name string
+		// Settings updated at runtime before the config file changes.
+		runtimeSettings map[string]string
+		configFile      string
+		wantLevel       string
+		wantBaseURL     string
+		wantPort        int
+		wantRestart     []string
+		wantErr         bool
+	}{
+		{
+			name:        "reloadable settings",
+			configFile:  "log:\n  level: warn\nakita_api:\n  base_url: https://file.example.com\n",
+			wantLevel:   "warn",
+			wantBaseURL: "https://file.example.com",
+			wantPort:    8080,
+		},
+		{
+			name:        "settings that require a restart keep their values",
+			configFile:  "log:\n  level: warn\ndemo_server:\n  port: 9090\n",
+			wantLevel:   "warn",
+			wantBaseURL: "https://embedded.example.com",
+			wantPort:    8080,
+			wantRestart: []string{"demo_server.port"},
+		},
+		{
+			name:            "runtime settings override the config file",
+			runtimeSettings: map[string]string{"log.level": "debug"},
+			configFile:      "log:\n  level: warn\n",
+			wantLevel:       "debug",
+			wantBaseURL:     "https://embedded.example.com",
+			wantPort:        8080,
+		},
+		{
+			name:       "invalid config file",
+			configFile: "log:\n  level: verbose\n",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, configPath := newTestManager(t, "")
+			if tt.runtimeSettings != nil {
+				if _, err := manager.Update(tt.runtimeSettings); err != nil {
+					t.Fatal(err)
+				}
+			}
+			previous := manager.Current()
+
+			writeConfigFile(t, configPath, tt.configFile)
+			restartRequired, err := manager.Reload()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Reload() error = nil, want an error")
+				}
+				if manager.Current() != previous {
+					t.Error("Reload() replaced the config despite failing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+
+			current := manager.Current()
+			if current.LogConfig().Level != tt.wantLevel {
+				t.Errorf("log level = %s, want %s", current.LogConfig().Level, tt.wantLevel)
+			}
+			if current.AkitaAPIConfig().BaseURL != tt.wantBaseURL {
+				t.Errorf("base URL = %s, want %s", current.AkitaAPIConfig().BaseURL, tt.wantBaseURL)
+			}
+			if current.DemoServerConfig().Port != tt.wantPort {
+				t.Errorf("demo server port = %d, want %d", current.DemoServerConfig().Port, tt.wantPort)
+			}
+			if !reflect.DeepEqual(restartRequired, tt.wantRestart) {
+				t.Errorf("Reload() restart required = %v, want %v", restartRequired, tt.wantRestart)
+			}
+		})
+	}
+}
+
+func TestManager_Watch(t *testing.T) {
+	manager, configPath := newTestManager(t, "")
+	changes := make(chan string, 1)
+	manager.Subscribe(func(previous *Config, current *Config) {
+		changes <- current.LogConfig().Level
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		manager.Watch(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+
+	// The file is changed until the watcher, which may not have started yet, notices.
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	contents := "log:\n  level: error\n"
+	for waiting := true; waiting; {
+		select {
+		case level := <-changes:
+			if level != "error" {
+				t.Errorf("reloaded log level = %s, want error", level)
+			}
+			waiting = false
+		case <-ticker.C:
+			contents += "\n"
+			writeConfigFile(t, configPath, contents)
+		case <-timeout:
+			t.Error("Watch() didn't reload the changed config file")
+			waiting = false
+		}
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("Watch() didn't return once the context was cancelled")
+	}
+}
+
+func TestDecodeSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "strings, numbers and booleans",
+			body: `{"log.level": "debug", "demo_server.traffic_interval": "5s", "analytics.enabled": false, "akita_api.retry.count": 3}`,
+			want: map[string]string{
+				"log.level":                    "debug",
+				"demo_server.traffic_interval": "5s",
+				"analytics.enabled":            "false",
+				"akita_api.retry.count":        "3",
+			},
+		},
+		{name: "nested object", body: `{"log": {"level": "debug"}}`, wantErr: true},
+		{name: "invalid JSON", body: `{"log.level": `, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeSettings(strings.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeSettings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
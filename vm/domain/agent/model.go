@@ -1,35 +1,122 @@
 package agent
 
 import (
+	"akita/domain/container"
 	"akita/domain/failure"
 	"akita/domain/user"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The ID of the agent config managed through the single-config endpoints.
+// Configs saved before multiple configs were supported also have this ID.
+const DefaultConfigID = "default"
+
+// The placeholder that replaces credentials in redacted configs.
+const RedactedCredential = "********"
+
+// Agent config IDs are used in container names, so they are restricted to
+// the characters Docker allows there.
+var configIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// The range of ports that an agent can be restricted to.
+const (
+	minPort = 1
+	maxPort = 65535
 )
 
 type Config struct {
+	// Uniquely identifies the config. Each config runs in its own agent container.
+	ID string `json:"id" bson:"id"`
+	// A human-readable name for the config.
+	Name            string  `json:"name,omitempty" bson:"name,omitempty"`
 	APIKey          string  `json:"api_key" bson:"api_key"`
 	APISecret       string  `json:"api_secret" bson:"api_secret"`
 	ProjectName     string  `json:"project_name" bson:"project_name"`
 	TargetPort      *int    `json:"target_port" bson:"target_port"`
 	TargetContainer *string `json:"target_container" bson:"target_container"`
+	// Selects the target container by properties that survive recreating it.
+	// Mutually exclusive with TargetContainer, which pins a container ID.
+	TargetSelector *container.Selector `json:"target_selector,omitempty" bson:"target_selector,omitempty"`
 	// Indicates whether the agent should be started by the frontend on app startup
 	IsEnabled         bool `json:"enabled" bson:"enabled"`
 	IsDemoModeEnabled bool `json:"demo_mode_enabled" bson:"demo_mode_enabled"`
 }
 
-func DecodeConfig(r io.Reader) (*Config, error) {
-	var result *Config
+// Represents the state of the container running the Akita agent.
+type Status struct {
+	// The ID of the agent config that the container runs.
+	ConfigID string `json:"config_id"`
+	// The ID of the agent container. Empty if no agent container exists.
+	ContainerID string `json:"container_id,omitempty"`
+	// The current state of the agent container.
+	State container.Status `json:"state"`
+	// The network mode the agent container was started with, e.g. "host" or "container:<id>".
+	NetworkMode string `json:"network_mode,omitempty"`
+	// The time at which the agent container was last started.
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// The digest of the agent configuration the container was started with.
+	ConfigDigest string `json:"config_digest,omitempty"`
+}
+
+// Returns the status of an agent that has no container.
+func NewAbsentStatus(configID string) *Status {
+	return &Status{ConfigID: configID, State: container.StatusNone}
+}
+
+func (s Status) IsRunning() bool {
+	return s.State == container.StatusRunning
+}
+
+// The action taken to bring the agent container in sync with the agent configuration.
+type Action string
+
+const (
+	ActionNone     Action = "none"
+	ActionStart    Action = "start"
+	ActionRestart  Action = "restart"
+	ActionRecreate Action = "recreate"
+	ActionStop     Action = "stop"
+	ActionDisable  Action = "disable"
+)
+
+// A record of a single reconciliation of the agent container against the agent configuration.
+type Decision struct {
+	// The ID of the agent config that was reconciled.
+	ConfigID string `json:"config_id" bson:"config_id"`
+	// The action that was taken.
+	Action Action `json:"action" bson:"action"`
+	// A human-readable explanation of why the action was taken.
+	Reason string `json:"reason" bson:"reason"`
+	// The error that occurred while taking the action, if any.
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+	// The time at which the decision was made.
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+func NewDecision(configID string, action Action, reason string) *Decision {
+	return &Decision{ConfigID: configID, Action: action, Reason: reason, Timestamp: time.Now().UTC()}
+}
+
+// Decodes the agent config with the given ID. Any ID in the payload is ignored.
+// The config is not validated.
+func DecodeConfig(r io.Reader, id string) (*Config, error) {
+	var result Config
 
 	if err := json.NewDecoder(r).Decode(&result); err != nil {
 		return nil, failure.Invalidf("failed to decode agent config: %v", err)
 	}
 
-	if err := result.Validate(); err != nil {
-		return nil, err
-	}
+	result.ID = id
 
-	return result, nil
+	return &result, nil
 }
 
 func (a *Config) Credentials() user.Credentials {
@@ -39,21 +126,162 @@ func (a *Config) Credentials() user.Credentials {
 	}
 }
 
+// Returns a copy of the config whose credentials are replaced by
+// RedactedCredential, so that it can be returned by the API.
+func (a *Config) Redacted() *Config {
+	result := *a
+	result.APIKey = RedactedCredential
+	result.APISecret = RedactedCredential
+	return &result
+}
+
+// Returns true if any credential of the config was redacted.
+func (a *Config) HasRedactedCredentials() bool {
+	return a.APIKey == RedactedCredential || a.APISecret == RedactedCredential
+}
+
+// Replaces the redacted credentials of the config with those of the given config.
+// This allows a config that was returned redacted to be saved back unchanged.
+func (a *Config) RestoreRedactedCredentials(from *Config) {
+	if a.APIKey == RedactedCredential {
+		a.APIKey = from.APIKey
+	}
+	if a.APISecret == RedactedCredential {
+		a.APISecret = from.APISecret
+	}
+}
+
+// Returns a digest of the fields that determine how the agent container is run.
+// A change in digest means that a running agent container is out of date. The
+// digest is an HMAC with the given key, so that it doesn't reveal the credentials.
+func (a *Config) Digest(key []byte) string {
+	fields, _ := json.Marshal([]any{a.APIKey, a.APISecret, a.ProjectName, a.TargetPort, a.TargetContainer})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(fields)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns an error reporting every violation found by Violations, if any.
 func (a *Config) Validate() error {
+	if violations := a.Violations(); len(violations) > 0 {
+		return InvalidConfigError(violations)
+	}
+	return nil
+}
+
+// Returns every problem with the fields of the config. Checks that depend on
+// the state of the host, such as whether the target container is running,
+// are left to the caller.
+func (a *Config) Violations() []failure.FieldViolation {
+	violations := []failure.FieldViolation{}
+	addViolation := func(field string, message string) {
+		violations = append(violations, failure.FieldViolation{Field: field, Message: message})
+	}
+
+	if err := ValidateConfigID(a.ID); err != nil {
+		addViolation("id", failure.From(err).Message)
+	}
+
 	if a.IsDemoModeEnabled && !a.IsEnabled {
-		return failure.Invalidf("demo mode cannot be enabled when the agent is disabled")
+		addViolation("demo_mode_enabled", "demo mode cannot be enabled when the agent is disabled")
 	}
+
 	if a.APIKey == "" {
-		return failure.Invalidf("api key is missing")
+		addViolation("api_key", "api key is missing")
 	}
 
 	if a.APISecret == "" {
-		return failure.Invalidf("api secret is missing")
+		addViolation("api_secret", "api secret is missing")
 	}
 
 	if a.ProjectName == "" {
-		return failure.Invalidf("project name is missing")
+		addViolation("project_name", "project name is missing")
+	}
+
+	if a.TargetPort != nil && (*a.TargetPort < minPort || *a.TargetPort > maxPort) {
+		addViolation("target_port", fmt.Sprintf("target port must be between %d and %d", minPort, maxPort))
+	}
+
+	if a.TargetSelector != nil {
+		if a.TargetContainer != nil {
+			addViolation("target_selector", "target container and target selector cannot both be set")
+		} else if err := a.TargetSelector.Validate(); err != nil {
+			addViolation("target_selector", failure.From(err).Message)
+		}
 	}
 
+	return violations
+}
+
+// Returns true if the agent monitors a specific container rather than the host.
+func (a *Config) HasTarget() bool {
+	return a.TargetContainer != nil || a.TargetSelector != nil
+}
+
+// Returns a copy of the config that targets the container with the given ID.
+func (a *Config) WithTargetContainer(id string) *Config {
+	result := *a
+	result.TargetContainer = &id
+	result.TargetSelector = nil
+	return &result
+}
+
+func ValidateConfigID(id string) error {
+	if !configIDPattern.MatchString(id) {
+		return failure.Invalidf("invalid agent config id %q", id).
+			WithCode(failure.CodeInvalidAgentConfig).
+			WithField("id", fmt.Sprintf("invalid agent config id %q", id))
+	}
 	return nil
 }
+
+// The kinds of failures reported for violations with these codes, if no other
+// violations are found.
+var violationKinds = map[failure.Code]func(format string, a ...interface{}) *failure.Error{
+	failure.CodeCredentialsRequired: failure.Invalidf,
+	failure.CodeInvalidCredentials:  failure.Unauthorizedf,
+	failure.CodeTargetNotFound:      failure.Unprocessablef,
+	failure.CodeTargetNotRunning:    failure.Unprocessablef,
+}
+
+// Returns an error reporting the given violations of an agent config. If all
+// violations share a code, such as that of a target container that isn't
+// running, the error has that code and its kind. Otherwise the config is invalid.
+func InvalidConfigError(violations []failure.FieldViolation) error {
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	message := "invalid agent configuration: " + strings.Join(messages, "; ")
+
+	code := failure.CodeInvalidAgentConfig
+	newError := failure.Invalidf
+	if len(violations) > 0 {
+		if kind, ok := violationKinds[violations[0].Code]; ok && haveCode(violations, violations[0].Code) {
+			code = violations[0].Code
+			newError = kind
+		}
+	}
+
+	return newError("%s", message).WithCode(code).WithFields(violations...)
+}
+
+// Returns true if all of the given violations have the given code.
+func haveCode(violations []failure.FieldViolation, code failure.Code) bool {
+	for _, violation := range violations {
+		if violation.Code != code {
+			return false
+		}
+	}
+	return true
+}
+
+// The outcome of validating an agent config without saving it.
+type ValidationReport struct {
+	// Whether no violation was found. The unverified checks may still find some.
+	Valid      bool                     `json:"valid"`
+	Violations []failure.FieldViolation `json:"violations"`
+	// The checks of fields that couldn't be made, e.g. because the Akita API
+	// is unavailable, along with the reason.
+	Unverified []failure.FieldViolation `json:"unverified"`
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestConfig_Digest(t *testing.T) {
+	port := 8080
+	base := Config{ID: DefaultConfigID, APIKey: "apk_key", APISecret: "secret", ProjectName: "project", TargetPort: &port}
+	key := []byte("local key")
+
+	tests := []struct {
+		name       string
+		modify     func(config *Config)
+		key        []byte
+		wantChange bool
+	}{
+		{name: "same config and key", modify: func(*Config) {}, key: key},
+		{name: "name is not part of the digest", modify: func(c *Config) { c.Name = "renamed" }, key: key},
+		{name: "secret changed", modify: func(c *Config) { c.APISecret = "rotated" }, key: key, wantChange: true},
+		{name: "project changed", modify: func(c *Config) { c.ProjectName = "other" }, key: key, wantChange: true},
+		{name: "port removed", modify: func(c *Config) { c.TargetPort = nil }, key: key, wantChange: true},
+		{name: "other key", modify: func(*Config) {}, key: []byte("other key"), wantChange: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := base
+			tt.modify(&modified)
+
+			changed := base.Digest(key) != modified.Digest(tt.key)
+			if changed != tt.wantChange {
+				t.Errorf("digest changed = %v, want %v", changed, tt.wantChange)
+			}
+		})
+	}
+}
+
+func TestConfig_DigestIsKeyed(t *testing.T) {
+	config := Config{APIKey: "apk_key", APISecret: "secret", ProjectName: "project"}
+
+	// An unkeyed hash of the fields can be recomputed by anyone who guesses the credentials.
+	fields, _ := json.Marshal([]any{config.APIKey, config.APISecret, config.ProjectName, config.TargetPort, config.TargetContainer})
+	sum := sha256.Sum256(fields)
+
+	if config.Digest([]byte("local key")) == hex.EncodeToString(sum[:]) {
+		t.Error("Digest() is an unkeyed hash of the credentials")
+	}
+}
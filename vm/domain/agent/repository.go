@@ -1,9 +1,58 @@
 package agent
 
-import "context"
+import (
+	"context"
+	"github.com/akitasoftware/go-utils/optionals"
+)
 
 type Repository interface {
-	GetConfig(ctx context.Context) (*Config, error)
-	SaveConfig(ctx context.Context, agentConfig *Config) error
-	DeleteConfig(ctx context.Context) error
+	// Returns all agent configs, ordered by ID.
+	ListConfigs(ctx context.Context) ([]*Config, error)
+	// Returns the agent config with the given ID.
+	// If no config is found, a failure.ErrNotFound error is returned.
+	GetConfig(ctx context.Context, id string) (*Config, error)
+	// Saves the given agent config, replacing any existing config with the same ID.
+	// If the config changed, a new revision is appended to its history.
+	SaveConfig(ctx context.Context, agentConfig *Config, options SaveOptions) error
+	// Removes the agent config with the given ID. Does nothing if no config is found.
+	// The history of the config is kept.
+	DeleteConfig(ctx context.Context, id string) error
+	// Returns the revisions of the agent config with the given ID, newest first.
+	ListRevisions(ctx context.Context, id string) ([]*Revision, error)
+	// Returns the given revision of the agent config with the given ID.
+	// If no revision is found, a failure.ErrNotFound error is returned.
+	GetRevision(ctx context.Context, id string, version int) (*Revision, error)
+}
+
+// Describes a change made by saving an agent config.
+type SaveOptions struct {
+	// What caused the change.
+	Source ChangeSource
+	// The version that the change restores, if it is a rollback.
+	RestoredVersion optionals.Optional[int]
+}
+
+// Manages the lifecycle of the Akita agent container.
+type ContainerRepository interface {
+	// Pulls the agent image and starts an agent container configured from the given config.
+	// If an agent container already exists for the config, it is replaced.
+	Start(ctx context.Context, agentConfig *Config) (*Status, error)
+	// Stops and removes the agent container of the given config. Does nothing if no agent container exists.
+	Stop(ctx context.Context, configID string) error
+	// Returns the status of the agent container of the given config.
+	// If no agent container exists, a failure.ErrNotFound error is returned.
+	GetStatus(ctx context.Context, configID string) (*Status, error)
+	// Returns the statuses of all agent containers, keyed by config ID.
+	ListStatuses(ctx context.Context) (map[string]*Status, error)
+	// Returns the digest that an agent container started from the given config
+	// is labelled with. Containers with another digest are out of date.
+	Digest(agentConfig *Config) string
+}
+
+// Stores the decisions made while reconciling the agent container.
+type DecisionRepository interface {
+	// Records the given decision.
+	RecordDecision(ctx context.Context, decision *Decision) error
+	// Returns the most recent decisions, newest first, up to the given limit.
+	ListDecisions(ctx context.Context, limit int) ([]*Decision, error)
 }
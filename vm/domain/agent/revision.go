@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"akita/domain/failure"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Identifies what caused a change to an agent config.
+type ChangeSource string
+
+const (
+	ChangeSourceUI         ChangeSource = "ui"
+	ChangeSourceAPI        ChangeSource = "api"
+	ChangeSourceReconciler ChangeSource = "reconciler"
+	ChangeSourceMigration  ChangeSource = "migration"
+)
+
+// Parses a change source supplied by a client of the API.
+func ParseChangeSource(raw string) (ChangeSource, error) {
+	switch source := ChangeSource(raw); source {
+	case ChangeSourceUI, ChangeSourceAPI:
+		return source, nil
+	default:
+		return "", failure.Invalidf("unknown change source %q", raw)
+	}
+}
+
+// A change to a single field of an agent config.
+type FieldChange struct {
+	// The JSON name of the field.
+	Field string `json:"field" bson:"field"`
+	// The value before the change. Empty if the field was unset.
+	From string `json:"from" bson:"from"`
+	// The value after the change. Empty if the field was unset.
+	To string `json:"to" bson:"to"`
+}
+
+// A saved version of an agent config.
+type Revision struct {
+	ConfigID string `json:"config_id"`
+	// Revisions of a config are numbered consecutively, starting at 1.
+	Version   int          `json:"version"`
+	Timestamp time.Time    `json:"timestamp"`
+	Source    ChangeSource `json:"source"`
+	// The version this revision restored, if it was created by a rollback.
+	RestoredVersion *int `json:"restored_version,omitempty"`
+	// The changes compared to the previous revision.
+	Changes []FieldChange `json:"changes"`
+	// The config as of this revision.
+	Config *Config `json:"config"`
+}
+
+// Returns a copy of the revision whose config credentials are redacted.
+func (r Revision) Redacted() *Revision {
+	r.Config = r.Config.Redacted()
+	return &r
+}
+
+// Returns the changes between two versions of an agent config. The previous
+// version is nil if the config is new. Credential values are redacted.
+func DiffConfigs(previous, current *Config) []FieldChange {
+	previousFields := configFields(previous)
+	currentFields := configFields(current)
+
+	names := map[string]struct{}{}
+	for name := range previousFields {
+		names[name] = struct{}{}
+	}
+	for name := range currentFields {
+		names[name] = struct{}{}
+	}
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	changes := []FieldChange{}
+	for _, name := range sortedNames {
+		from, to := previousFields[name], currentFields[name]
+		if name == "id" || reflect.DeepEqual(from, to) {
+			continue
+		}
+
+		change := FieldChange{Field: name, From: formatFieldValue(from), To: formatFieldValue(to)}
+		if name == "api_key" || name == "api_secret" {
+			change.From, change.To = redactFieldValue(from), redactFieldValue(to)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// Returns the fields of the config keyed by their JSON name.
+func configFields(config *Config) map[string]any {
+	fields := map[string]any{}
+	if config == nil {
+		return fields
+	}
+
+	raw, _ := json.Marshal(config)
+	_ = json.Unmarshal(raw, &fields)
+
+	return fields
+}
+
+func formatFieldValue(value any) string {
+	switch typedValue := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typedValue
+	default:
+		raw, _ := json.Marshal(typedValue)
+		return string(raw)
+	}
+}
+
+func redactFieldValue(value any) string {
+	if value == nil || value == "" {
+		return ""
+	}
+	return RedactedCredential
+}
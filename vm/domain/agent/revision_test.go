@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	port := 8080
+	otherPort := 9090
+	base := &Config{ID: DefaultConfigID, APIKey: "apk_key", APISecret: "secret", ProjectName: "project", TargetPort: &port}
+	modified := func(modify func(config *Config)) *Config {
+		config := *base
+		modify(&config)
+		return &config
+	}
+
+	tests := []struct {
+		name     string
+		previous *Config
+		current  *Config
+		want     []FieldChange
+	}{
+		{name: "unchanged", previous: base, current: base, want: []FieldChange{}},
+		{
+			name:     "new config",
+			previous: nil,
+			current:  &Config{ID: DefaultConfigID, APIKey: "apk_key", ProjectName: "project"},
+			want: []FieldChange{
+				{Field: "api_key", To: RedactedCredential},
+				{Field: "api_secret"},
+				{Field: "demo_mode_enabled", To: "false"},
+				{Field: "enabled", To: "false"},
+				{Field: "project_name", To: "project"},
+			},
+		},
+		{
+			name:     "changed fields",
+			previous: base,
+			current: modified(func(c *Config) {
+				c.ProjectName = "other"
+				c.TargetPort = &otherPort
+				c.IsEnabled = true
+			}),
+			want: []FieldChange{
+				{Field: "enabled", From: "false", To: "true"},
+				{Field: "project_name", From: "project", To: "other"},
+				{Field: "target_port", From: "8080", To: "9090"},
+			},
+		},
+		{
+			name:     "cleared field",
+			previous: base,
+			current:  modified(func(c *Config) { c.TargetPort = nil }),
+			want:     []FieldChange{{Field: "target_port", From: "8080"}},
+		},
+		{
+			name:     "rotated secret is redacted",
+			previous: base,
+			current:  modified(func(c *Config) { c.APISecret = "rotated" }),
+			want:     []FieldChange{{Field: "api_secret", From: RedactedCredential, To: RedactedCredential}},
+		},
+		{
+			name:     "id is ignored",
+			previous: base,
+			current:  modified(func(c *Config) { c.ID = "other" }),
+			want:     []FieldChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffConfigs(tt.previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffConfigs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
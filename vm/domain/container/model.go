@@ -1,5 +1,19 @@
 package container
 
+import (
+	"akita/domain/failure"
+	"github.com/akitasoftware/go-utils/optionals"
+	"strings"
+	"time"
+)
+
+const (
+	// Label set by Docker Compose on containers to the name of their project.
+	ComposeProjectLabel = "com.docker.compose.project"
+	// Label set by Docker Compose on containers to the name of their service.
+	ComposeServiceLabel = "com.docker.compose.service"
+)
+
 // Represents the current state of a Docker container.
 type Status string
 
@@ -11,4 +25,199 @@ const (
 	StatusPaused     Status = "paused"
 	StatusExited     Status = "exited"
 	StatusDead       Status = "dead"
+	// Indicates that the container does not exist.
+	StatusNone Status = "none"
 )
+
+// Parses the given string into a Status of an existing container.
+func ParseStatus(raw string) (Status, error) {
+	switch status := Status(raw); status {
+	case StatusCreated, StatusRestarting, StatusRunning, StatusRemoving, StatusPaused, StatusExited, StatusDead:
+		return status, nil
+	default:
+		return "", failure.Invalidf("unknown container status %q", raw)
+	}
+}
+
+// Represents a port of a Docker container.
+type Port struct {
+	// The port inside the container.
+	PrivatePort int `json:"private_port"`
+	// The port on the host that the private port is published to.
+	// Nil if the port is only exposed.
+	PublicPort *int `json:"public_port,omitempty"`
+	// The host IP that the port is published on.
+	IP string `json:"ip,omitempty"`
+	// The protocol of the port, e.g. "tcp" or "udp".
+	Protocol string `json:"protocol"`
+}
+
+// Represents a Docker container that the agent can target.
+type Container struct {
+	ID    string   `json:"id"`
+	Names []string `json:"names"`
+	Image string   `json:"image"`
+	State Status   `json:"state"`
+	// The published and exposed ports of the container.
+	Ports []Port `json:"ports"`
+	// The names of the networks the container is attached to.
+	Networks []string          `json:"networks"`
+	Labels   map[string]string `json:"labels"`
+	// The Docker Compose project the container belongs to, if any.
+	ComposeProject string `json:"compose_project,omitempty"`
+	// The Docker Compose service the container belongs to, if any.
+	ComposeService string `json:"compose_service,omitempty"`
+}
+
+// Criteria for listing containers. Empty fields match all containers.
+type Filter struct {
+	// Only match containers in the given state.
+	Status optionals.Optional[Status]
+	// Only match the container with the given name.
+	Name optionals.Optional[string]
+	// Only match containers that belong to the given Docker Compose project.
+	ComposeProject optionals.Optional[string]
+	// Only match containers that have all the given labels. Each label is
+	// either a key, or a key and value in the form "key=value".
+	Labels []string
+}
+
+// Identifies a container by properties that are preserved when the container
+// is recreated, e.g. by `docker compose up`. Exactly one of the name, labels
+// or compose service must be set.
+type Selector struct {
+	// Matches the container with the given name.
+	Name string `json:"name,omitempty" bson:"name,omitempty"`
+	// Matches containers that have all the given labels. Each label is either
+	// a key, or a key and value in the form "key=value".
+	Labels []string `json:"labels,omitempty" bson:"labels,omitempty"`
+	// Matches containers of the given Docker Compose project. Requires ComposeService.
+	ComposeProject string `json:"compose_project,omitempty" bson:"compose_project,omitempty"`
+	// Matches containers of the given Docker Compose service.
+	ComposeService string `json:"compose_service,omitempty" bson:"compose_service,omitempty"`
+}
+
+func (s Selector) Validate() error {
+	kinds := 0
+	if s.Name != "" {
+		kinds++
+	}
+	if len(s.Labels) > 0 {
+		kinds++
+	}
+	if s.ComposeProject != "" || s.ComposeService != "" {
+		kinds++
+	}
+
+	if kinds != 1 {
+		return failure.Invalidf("exactly one of name, labels or compose service must be selected")
+	}
+
+	if (s.ComposeProject == "") != (s.ComposeService == "") {
+		return failure.Invalidf("compose project and compose service must be selected together")
+	}
+
+	for _, label := range s.Labels {
+		if label == "" || strings.HasPrefix(label, "=") {
+			return failure.Invalidf("invalid label selector %q", label)
+		}
+	}
+
+	return nil
+}
+
+// Returns a filter matching the running containers selected by the selector.
+func (s Selector) Filter() Filter {
+	filter := Filter{
+		Status: optionals.Some(StatusRunning),
+		Labels: append([]string{}, s.Labels...),
+	}
+
+	if s.Name != "" {
+		filter.Name = optionals.Some(s.Name)
+	}
+
+	if s.ComposeProject != "" {
+		filter.ComposeProject = optionals.Some(s.ComposeProject)
+		filter.Labels = append(filter.Labels, ComposeServiceLabel+"="+s.ComposeService)
+	}
+
+	return filter
+}
+
+// Returns true if a container with the given name and labels is selected by the selector.
+func (s Selector) Matches(name string, labels map[string]string) bool {
+	if s.Name != "" {
+		return strings.TrimPrefix(name, "/") == s.Name
+	}
+
+	if s.ComposeProject != "" {
+		return labels[ComposeProjectLabel] == s.ComposeProject && labels[ComposeServiceLabel] == s.ComposeService
+	}
+
+	for _, label := range s.Labels {
+		key, value, hasValue := strings.Cut(label, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+
+	return len(s.Labels) > 0
+}
+
+func (s Selector) String() string {
+	switch {
+	case s.Name != "":
+		return "name " + s.Name
+	case s.ComposeProject != "":
+		return "compose service " + s.ComposeProject + "/" + s.ComposeService
+	default:
+		return "labels " + strings.Join(s.Labels, ",")
+	}
+}
+
+// Represents a change in the lifecycle of a Docker container.
+type EventAction string
+
+const (
+	EventActionStart   EventAction = "start"
+	EventActionRestart EventAction = "restart"
+	EventActionStop    EventAction = "stop"
+	EventActionDie     EventAction = "die"
+	EventActionPause   EventAction = "pause"
+	EventActionUnpause EventAction = "unpause"
+	EventActionDestroy EventAction = "destroy"
+)
+
+// Represents a state change of a Docker container.
+type Event struct {
+	// The ID of the container.
+	ContainerID string
+	// The name of the container.
+	ContainerName string
+	// The labels of the container.
+	Labels map[string]string
+	// The kind of state change.
+	Action EventAction
+	// The time at which the state change occurred.
+	Timestamp time.Time
+}
+
+// Returns true if the event concerns the container with the given ID, ID prefix or name.
+func (e Event) Concerns(idOrName string) bool {
+	if idOrName == "" {
+		return false
+	}
+	return strings.HasPrefix(e.ContainerID, idOrName) || strings.TrimPrefix(e.ContainerName, "/") == idOrName
+}
+
+// Returns true if the container is no longer running after the event.
+func (e Event) IsTermination() bool {
+	switch e.Action {
+	case EventActionStop, EventActionDie, EventActionPause, EventActionDestroy:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,150 @@
+package container
+
+import (
+	"github.com/akitasoftware/go-utils/optionals"
+	"reflect"
+	"testing"
+)
+
+func TestEvent_Concerns(t *testing.T) {
+	event := Event{ContainerID: "4f1c2b9e8d7a", ContainerName: "/api"}
+
+	tests := []struct {
+		name     string
+		idOrName string
+		want     bool
+	}{
+		{name: "full id", idOrName: "4f1c2b9e8d7a", want: true},
+		{name: "id prefix", idOrName: "4f1c", want: true},
+		{name: "name without slash", idOrName: "api", want: true},
+		{name: "other container", idOrName: "worker"},
+		{name: "empty", idOrName: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := event.Concerns(tt.idOrName); got != tt.want {
+				t.Errorf("Concerns(%q) = %v, want %v", tt.idOrName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvent_IsTermination(t *testing.T) {
+	tests := []struct {
+		action EventAction
+		want   bool
+	}{
+		{action: EventActionStart},
+		{action: EventActionRestart},
+		{action: EventActionUnpause},
+		{action: EventActionStop, want: true},
+		{action: EventActionDie, want: true},
+		{action: EventActionPause, want: true},
+		{action: EventActionDestroy, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			if got := (Event{Action: tt.action}).IsTermination(); got != tt.want {
+				t.Errorf("IsTermination() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelector_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector Selector
+		wantErr  bool
+	}{
+		{name: "name", selector: Selector{Name: "api"}},
+		{name: "labels", selector: Selector{Labels: []string{"app", "tier=web"}}},
+		{name: "compose service", selector: Selector{ComposeProject: "shop", ComposeService: "api"}},
+		{name: "nothing selected", selector: Selector{}, wantErr: true},
+		{name: "name and labels", selector: Selector{Name: "api", Labels: []string{"app"}}, wantErr: true},
+		{name: "compose project only", selector: Selector{ComposeProject: "shop"}, wantErr: true},
+		{name: "compose service only", selector: Selector{ComposeService: "api"}, wantErr: true},
+		{name: "empty label", selector: Selector{Labels: []string{""}}, wantErr: true},
+		{name: "label without key", selector: Selector{Labels: []string{"=web"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.selector.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelector_Filter(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector Selector
+		want     Filter
+	}{
+		{
+			name:     "name",
+			selector: Selector{Name: "api"},
+			want:     Filter{Status: optionals.Some(StatusRunning), Name: optionals.Some("api"), Labels: []string{}},
+		},
+		{
+			name:     "labels",
+			selector: Selector{Labels: []string{"app", "tier=web"}},
+			want:     Filter{Status: optionals.Some(StatusRunning), Labels: []string{"app", "tier=web"}},
+		},
+		{
+			name:     "compose service",
+			selector: Selector{ComposeProject: "shop", ComposeService: "api"},
+			want: Filter{
+				Status:         optionals.Some(StatusRunning),
+				ComposeProject: optionals.Some("shop"),
+				Labels:         []string{ComposeServiceLabel + "=api"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Filter(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	labels := map[string]string{
+		"app":               "shop",
+		"tier":              "web",
+		ComposeProjectLabel: "shop",
+		ComposeServiceLabel: "api",
+	}
+
+	tests := []struct {
+		name          string
+		selector      Selector
+		containerName string
+		want          bool
+	}{
+		{name: "same name", selector: Selector{Name: "api"}, containerName: "/api", want: true},
+		{name: "other name", selector: Selector{Name: "worker"}, containerName: "/api"},
+		{name: "label key", selector: Selector{Labels: []string{"app"}}, want: true},
+		{name: "label key and value", selector: Selector{Labels: []string{"app", "tier=web"}}, want: true},
+		{name: "other label value", selector: Selector{Labels: []string{"tier=db"}}},
+		{name: "missing label", selector: Selector{Labels: []string{"app", "owner"}}},
+		{name: "compose service", selector: Selector{ComposeProject: "shop", ComposeService: "api"}, want: true},
+		{name: "other compose service", selector: Selector{ComposeProject: "shop", ComposeService: "worker"}},
+		{name: "nothing selected", selector: Selector{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches(tt.containerName, labels); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,4 +9,16 @@ type Repository interface {
 	// Checks for the existence of a container with the given ID.
 	// If the requiredStatus parameter is provided, the container must also be in the given state.
 	Exists(ctx context.Context, id string, requiredStatus optionals.Optional[Status]) (bool, error)
+	// Returns all containers, running or not, that match the given filter.
+	List(ctx context.Context, filter Filter) ([]*Container, error)
+	// Returns the running container matched by the given selector. If several
+	// containers match, the most recently created one is returned.
+	// If no running container matches, a failure.ErrNotFound error is returned.
+	Resolve(ctx context.Context, selector Selector) (*Container, error)
+}
+
+// Notifies subscribers about state changes of Docker containers.
+type Watcher interface {
+	// Streams container events until the context is cancelled, at which point the returned channel is closed.
+	Watch(ctx context.Context) <-chan Event
 }
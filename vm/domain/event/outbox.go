@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Returned by a Sender that discards events because sending them is disabled.
+var ErrSendingDisabled = errors.New("sending analytics events is disabled")
+
+// An analytics event waiting in the outbox to be delivered.
+type QueuedEvent struct {
+	// Uniquely identifies the queued event. IDs sort in the order events were queued.
+	ID         string         `json:"id" bson:"id"`
+	DistinctID string         `json:"distinct_id" bson:"distinct_id"`
+	Name       string         `json:"name" bson:"name"`
+	Properties map[string]any `json:"properties" bson:"properties"`
+	// When the event was raised. Delivered events keep this timestamp.
+	EnqueuedAt time.Time `json:"enqueued_at" bson:"enqueued_at"`
+	// The number of failed delivery attempts.
+	Attempts int `json:"attempts" bson:"attempts"`
+	// The event isn't delivered again before this time.
+	NextAttemptAt time.Time `json:"next_attempt_at" bson:"next_attempt_at"`
+	// The error of the last failed delivery attempt, if any.
+	LastError string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+func NewQueuedEvent(distinctID string, name string, properties map[string]any) *QueuedEvent {
+	now := time.Now().UTC()
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return &QueuedEvent{
+		ID:            now.Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix),
+		DistinctID:    distinctID,
+		Name:          name,
+		Properties:    properties,
+		EnqueuedAt:    now,
+		NextAttemptAt: now,
+	}
+}
+
+// The outcome of a run of the delivery of queued events.
+type DeliveryReport struct {
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time `json:"finished_at" bson:"finished_at"`
+	// The number of events that were delivered.
+	Delivered int `json:"delivered" bson:"delivered"`
+	// The number of events whose delivery failed. They are retried later.
+	Failed int `json:"failed" bson:"failed"`
+	// The number of events that were dropped because they were too old.
+	Dropped int `json:"dropped" bson:"dropped"`
+	// The error of the last failed delivery, if any.
+	LastError string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// The state of the outbox of analytics events.
+type QueueStatus struct {
+	// The number of events waiting to be delivered.
+	Depth int `json:"depth"`
+	// When the oldest waiting event was raised, if there is one.
+	OldestEnqueuedAt *time.Time `json:"oldest_enqueued_at,omitempty"`
+	// The last delivery run that delivered, retried or dropped events, if any.
+	LastDelivery *DeliveryReport `json:"last_delivery,omitempty"`
+}
+
+// Persists analytics events until they are delivered, so that events raised
+// while the extension is offline aren't lost.
+type OutboxRepository interface {
+	// Adds the event to the outbox, scrubbed by the analytics policy and with
+	// its distinct ID pseudonymized. Events unknown to the policy are rejected.
+	Enqueue(ctx context.Context, queuedEvent *QueuedEvent) error
+	// Returns the queued events, oldest first.
+	List(ctx context.Context) ([]*QueuedEvent, error)
+	// Saves the delivery state of the queued event.
+	Update(ctx context.Context, queuedEvent *QueuedEvent) error
+	// Removes the queued event with the given ID. Does nothing if it is not found.
+	Remove(ctx context.Context, id string) error
+	// Removes all queued events and returns how many were removed.
+	Purge(ctx context.Context) (int, error)
+	// Records the outcome of a delivery run.
+	RecordDelivery(ctx context.Context, report *DeliveryReport) error
+	// Returns the last recorded delivery run, or nil if there is none.
+	LastDelivery(ctx context.Context) (*DeliveryReport, error)
+}
+
+// Delivers analytics events to where they are analysed.
+type Sender interface {
+	// Sends the given events as one batch. Returns nil only once the whole batch
+	// has been accepted, and ErrSendingDisabled if the events were discarded.
+	Send(ctx context.Context, queuedEvents []*QueuedEvent) error
+}
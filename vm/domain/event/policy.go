@@ -0,0 +1,209 @@
+package event
+
+import (
+	"akita/domain/failure"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// How personal data found in event properties is handled.
+type PIIAction string
+
+const (
+	// Replaces personal data with a hash of it, so that events can still be
+	// correlated without revealing the data.
+	PIIActionHash PIIAction = "hash"
+	// Removes properties that contain personal data.
+	PIIActionDrop PIIAction = "drop"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern  = regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`)
+)
+
+// Decides which analytics events are recorded, and which of their properties
+// are sent.
+type Policy struct {
+	// The properties allowed for each known event. Events with other names are
+	// rejected, and properties that aren't allowed are removed.
+	AllowedProperties map[string][]string
+	// Properties allowed for every known event, e.g. the platform of the host
+	// that the extension adds to each event.
+	CommonProperties []string
+	// Properties whose whole value is personal data, e.g. container names.
+	SensitiveProperties []string
+	// How emails, IP addresses and container names are handled.
+	PIIAction PIIAction
+	// The largest size of the JSON encoded properties of an event.
+	MaxPayloadBytes int
+}
+
+// Returns the properties of the event with the given name that may be sent.
+// Properties that aren't allowed are removed, and emails, IP addresses and the
+// given container names are hashed or dropped. An error is returned if the
+// event is unknown or its scrubbed properties exceed the payload size.
+func (p Policy) Scrub(name string, properties map[string]any, containerNames []string) (map[string]any, error) {
+	allowedProperties, ok := p.AllowedProperties[name]
+	if !ok {
+		return nil, failure.Invalidf("unknown analytics event %q", name).
+			WithCode(failure.CodeUnknownAnalyticsEvent).
+			WithField("name", "event is not allowed")
+	}
+
+	scrubber := newPIIScrubber(p.PIIAction, containerNames)
+
+	result := map[string]any{}
+	for _, property := range append(append([]string{}, allowedProperties...), p.CommonProperties...) {
+		value, ok := properties[property]
+		if !ok {
+			continue
+		}
+
+		if p.isSensitive(property) {
+			if p.PIIAction == PIIActionHash {
+				result[property] = hashPII(fmt.Sprint(value))
+			}
+			continue
+		}
+
+		if scrubbedValue, ok := scrubber.scrub(value); ok {
+			result[property] = scrubbedValue
+		}
+	}
+
+	if p.MaxPayloadBytes > 0 {
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return nil, failure.Invalidf("failed to encode properties of analytics event %q: %v", name, err)
+		}
+		if len(payload) > p.MaxPayloadBytes {
+			return nil, failure.Invalidf(
+				"properties of analytics event %q are %d bytes, more than the limit of %d bytes",
+				name,
+				len(payload),
+				p.MaxPayloadBytes,
+			).WithCode(failure.CodeAnalyticsPayloadTooLarge).WithField("properties", "payload is too large")
+		}
+	}
+
+	return result, nil
+}
+
+// Returns a pseudonym of the given distinct ID, which is usually an email, so
+// that the events of a user can be correlated without revealing the user.
+func (p Policy) Pseudonymize(distinctID string) string {
+	if distinctID == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(distinctID))))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p Policy) isSensitive(property string) bool {
+	for _, sensitiveProperty := range p.SensitiveProperties {
+		if property == sensitiveProperty {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds personal data in property values.
+type piiScrubber struct {
+	action PIIAction
+	// Matches any of the known container names as a whole word. Nil if there
+	// are no known container names.
+	containerNamePattern *regexp.Regexp
+}
+
+func newPIIScrubber(action PIIAction, containerNames []string) piiScrubber {
+	scrubber := piiScrubber{action: action}
+
+	quotedNames := make([]string, 0, len(containerNames))
+	for _, containerName := range containerNames {
+		containerName = strings.TrimPrefix(containerName, "/")
+		if containerName != "" {
+			quotedNames = append(quotedNames, regexp.QuoteMeta(containerName))
+		}
+	}
+	if len(quotedNames) > 0 {
+		// Prefer the longest name when names overlap.
+		sort.Slice(quotedNames, func(i, j int) bool { return len(quotedNames[i]) > len(quotedNames[j]) })
+		scrubber.containerNamePattern = regexp.MustCompile(
+			`(?:^|\b)(?:` + strings.Join(quotedNames, "|") + `)(?:\b|$)`,
+		)
+	}
+
+	return scrubber
+}
+
+// Returns the scrubbed value, or false if the value should be dropped.
+// Strings nested in lists and objects are scrubbed as well.
+func (s piiScrubber) scrub(value any) (any, bool) {
+	switch typedValue := value.(type) {
+	case string:
+		return s.scrubString(typedValue)
+	case []any:
+		result := make([]any, 0, len(typedValue))
+		for _, element := range typedValue {
+			scrubbedElement, ok := s.scrub(element)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, scrubbedElement)
+		}
+		return result, true
+	case map[string]any:
+		result := make(map[string]any, len(typedValue))
+		for key, element := range typedValue {
+			scrubbedElement, ok := s.scrub(element)
+			if !ok {
+				return nil, false
+			}
+			result[key] = scrubbedElement
+		}
+		return result, true
+	default:
+		return value, true
+	}
+}
+
+func (s piiScrubber) scrubString(value string) (any, bool) {
+	patterns := []*regexp.Regexp{emailPattern, ipv4Pattern, ipv6Pattern}
+	if s.containerNamePattern != nil {
+		patterns = append(patterns, s.containerNamePattern)
+	}
+
+	found := false
+	for _, pattern := range patterns {
+		value = pattern.ReplaceAllStringFunc(value, func(match string) string {
+			// The IP patterns are loose, e.g. they match times, so matches are parsed to confirm them.
+			if (pattern == ipv4Pattern || pattern == ipv6Pattern) && net.ParseIP(match) == nil {
+				return match
+			}
+			found = true
+			return hashPII(match)
+		})
+	}
+
+	if found && s.action != PIIActionHash {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Returns a short, stable hash of the given personal data.
+func hashPII(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "pii:" + hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,155 @@
+package event
+
+import (
+	"akita/domain/failure"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPolicy_Scrub(t *testing.T) {
+	policy := Policy{
+		AllowedProperties: map[string][]string{
+			"Agent Failed to Start": {"errorMessage", "container_name", "details"},
+		},
+		CommonProperties:    []string{"target-os"},
+		SensitiveProperties: []string{"container_name"},
+		PIIAction:           PIIActionHash,
+		MaxPayloadBytes:     128,
+	}
+	dropPolicy := policy
+	dropPolicy.PIIAction = PIIActionDrop
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		eventName      string
+		properties     map[string]any
+		containerNames []string
+		want           map[string]any
+		wantCode       failure.Code
+	}{
+		{
+			name:       "removes properties that aren't allowed",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"errorMessage": "failed", "target-os": "linux", "token": "secret"},
+			want:       map[string]any{"errorMessage": "failed", "target-os": "linux"},
+		},
+		{
+			name:       "hashes sensitive properties",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"container_name": "my-api"},
+			want:       map[string]any{"container_name": hashPII("my-api")},
+		},
+		{
+			name:       "drops sensitive properties",
+			policy:     dropPolicy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"container_name": "my-api"},
+			want:       map[string]any{},
+		},
+		{
+			name:       "hashes emails and IP addresses",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"errorMessage": "user@example.com at 10.0.0.1"},
+			want:       map[string]any{"errorMessage": hashPII("user@example.com") + " at " + hashPII("10.0.0.1")},
+		},
+		{
+			name:       "leaves times that look like IPv6 addresses",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"errorMessage": "failed at 12:30:45"},
+			want:       map[string]any{"errorMessage": "failed at 12:30:45"},
+		},
+		{
+			name:           "hashes container names",
+			policy:         policy,
+			eventName:      "Agent Failed to Start",
+			properties:     map[string]any{"errorMessage": "my-api exited"},
+			containerNames: []string{"/my-api"},
+			want:           map[string]any{"errorMessage": hashPII("my-api") + " exited"},
+		},
+		{
+			name:       "scrubs nested values",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"details": map[string]any{"emails": []any{"user@example.com"}}},
+			want:       map[string]any{"details": map[string]any{"emails": []any{hashPII("user@example.com")}}},
+		},
+		{
+			name:       "drops properties with personal data",
+			policy:     dropPolicy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"errorMessage": "user@example.com", "target-os": "linux"},
+			want:       map[string]any{"target-os": "linux"},
+		},
+		{
+			name:      "rejects unknown events",
+			policy:    policy,
+			eventName: "Unknown",
+			wantCode:  failure.CodeUnknownAnalyticsEvent,
+		},
+		{
+			name:       "rejects large payloads",
+			policy:     policy,
+			eventName:  "Agent Failed to Start",
+			properties: map[string]any{"errorMessage": string(make([]byte, 200))},
+			wantCode:   failure.CodeAnalyticsPayloadTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Scrub(tt.eventName, tt.properties, tt.containerNames)
+			if tt.wantCode != "" {
+				if !errors.Is(err, failure.ErrInvalid) || failure.From(err).Code != tt.wantCode {
+					t.Fatalf("Scrub() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scrub() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scrub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Pseudonymize(t *testing.T) {
+	policy := Policy{}
+
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		wantSame bool
+	}{
+		{name: "same email", a: "user@example.com", b: "user@example.com", wantSame: true},
+		{name: "different case and spacing", a: "User@Example.com ", b: "user@example.com", wantSame: true},
+		{name: "different emails", a: "user@example.com", b: "other@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := policy.Pseudonymize(tt.a), policy.Pseudonymize(tt.b)
+			if a == tt.a || emailPattern.MatchString(a) {
+				t.Fatalf("Pseudonymize(%q) = %q, which reveals the email", tt.a, a)
+			}
+			if (a == b) != tt.wantSame {
+				t.Errorf(
+					"Pseudonymize(%q) = %q and Pseudonymize(%q) = %q, want same = %t",
+					tt.a,
+					a,
+					tt.b,
+					b,
+					tt.wantSame,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// An analytics event as it was handed to the analytics sink.
+type RecordedEvent struct {
+	// When the sink recorded the event.
+	RecordedAt time.Time `json:"recorded_at"`
+	// When the event was raised.
+	Timestamp  time.Time      `json:"timestamp"`
+	DistinctID string         `json:"distinct_id"`
+	Name       string         `json:"name"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Provides the analytics events recorded by a local analytics sink.
+type RecordedEventRepository interface {
+	// Returns up to limit of the most recently recorded events, newest first.
+	// If events are not recorded locally, a failure.ErrUnprocessable error is returned.
+	ListRecordedEvents(ctx context.Context, limit int) ([]*RecordedEvent, error)
+}
@@ -0,0 +1,29 @@
+package failure
+
+// A machine-readable code identifying a failure. Codes are part of the API,
+// so existing codes must not change.
+type Code string
+
+// Codes of failures that have no more specific code.
+const (
+	CodeInvalid       Code = "invalid"
+	CodeNotFound      Code = "not_found"
+	CodeUnprocessable Code = "unprocessable"
+	CodeUnauthorized  Code = "unauthorized"
+	CodeUnavailable   Code = "unavailable"
+	CodeInternal      Code = "internal"
+)
+
+// Codes of specific failures.
+const (
+	CodeInvalidAgentConfig       Code = "invalid_agent_config"
+	CodeCredentialsRequired      Code = "credentials_required"
+	CodeInvalidCredentials       Code = "invalid_credentials"
+	CodeTargetNotFound           Code = "target_container_not_found"
+	CodeTargetNotRunning         Code = "target_container_not_running"
+	CodeRevisionNotFound         Code = "revision_not_found"
+	CodeAkitaAPIUnavailable      Code = "akita_api_unavailable"
+	CodeAnalyticsNotRecorded     Code = "analytics_not_recorded"
+	CodeUnknownAnalyticsEvent    Code = "unknown_analytics_event"
+	CodeAnalyticsPayloadTooLarge Code = "analytics_payload_too_large"
+)
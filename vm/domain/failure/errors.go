@@ -10,21 +10,134 @@ var (
 	ErrNotFound      = errors.New("not found")
 	ErrUnprocessable = errors.New("unprocessable")
 	ErrUnauthorized  = errors.New("unauthorized")
+	ErrUnavailable   = errors.New("unavailable")
 )
 
+// The codes of failures of each kind that have no more specific code.
+var defaultCodes = map[error]Code{
+	ErrInvalid:       CodeInvalid,
+	ErrNotFound:      CodeNotFound,
+	ErrUnprocessable: CodeUnprocessable,
+	ErrUnauthorized:  CodeUnauthorized,
+	ErrUnavailable:   CodeUnavailable,
+}
+
+// A failure of one of the kinds above, along with the details that are
+// reported to API clients. Errors of a kind match its sentinel with errors.Is.
+type Error struct {
+	// One of the sentinel errors above.
+	kind error
+	// The underlying error, if any.
+	cause error
+	// A machine-readable code identifying the failure.
+	Code Code
+	// A human-readable description of the failure.
+	Message string
+	// The fields of the request that caused the failure, if any.
+	Fields []FieldViolation
+	// Whether the request may succeed if it is retried unchanged.
+	Retryable bool
+}
+
+// A problem with a single field of a request.
+type FieldViolation struct {
+	// The JSON name of the field.
+	Field string `json:"field"`
+	// A human-readable description of the problem.
+	Message string `json:"message"`
+	// The code of the problem, if it has a more specific code than the failure.
+	Code Code `json:"code,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.kind == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.kind, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Returns the sentinel error of the failure's kind, or nil for internal errors.
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+// Returns a copy of the error with the given code.
+func (e *Error) WithCode(code Code) *Error {
+	result := *e
+	result.Code = code
+	return &result
+}
+
+// Returns a copy of the error that reports a problem with the given field.
+func (e *Error) WithField(field string, message string) *Error {
+	result := *e
+	result.Fields = append(append([]FieldViolation{}, e.Fields...), FieldViolation{Field: field, Message: message})
+	return &result
+}
+
+// Returns a copy of the error that reports problems with the given fields.
+func (e *Error) WithFields(fields ...FieldViolation) *Error {
+	result := *e
+	result.Fields = append(append([]FieldViolation{}, e.Fields...), fields...)
+	return &result
+}
+
+// Returns a copy of the error caused by the given error.
+func (e *Error) WithCause(cause error) *Error {
+	result := *e
+	result.cause = cause
+	return &result
+}
+
 // Returns an ErrInvalid error along with the given message.
-func Invalidf(format string, a ...interface{}) error {
-	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, a...))
+func Invalidf(format string, a ...interface{}) *Error {
+	return newError(ErrInvalid, false, format, a...)
 }
 
-func NotFoundf(format string, a ...interface{}) error {
-	return fmt.Errorf("%w: %s", ErrNotFound, fmt.Sprintf(format, a...))
+func NotFoundf(format string, a ...interface{}) *Error {
+	return newError(ErrNotFound, false, format, a...)
 }
 
-func Unprocessablef(format string, a ...interface{}) error {
-	return fmt.Errorf("%w: %s", ErrUnprocessable, fmt.Sprintf(format, a...))
+func Unprocessablef(format string, a ...interface{}) *Error {
+	return newError(ErrUnprocessable, false, format, a...)
+}
+
+func Unauthorizedf(format string, a ...interface{}) *Error {
+	return newError(ErrUnauthorized, false, format, a...)
+}
+
+// Returns an ErrUnavailable error along with the given message. Such errors
+// are caused by dependencies that are temporarily unavailable, so they are retryable.
+func Unavailablef(format string, a ...interface{}) *Error {
+	return newError(ErrUnavailable, true, format, a...)
+}
+
+// Returns the failure.Error in the chain of the given error. Errors that only
+// wrap a sentinel error are given its default code, and all other errors are
+// reported as internal errors.
+func From(err error) *Error {
+	var result *Error
+	if errors.As(err, &result) {
+		return result
+	}
+
+	for kind, code := range defaultCodes {
+		if errors.Is(err, kind) {
+			return &Error{kind: kind, cause: err, Code: code, Message: err.Error()}
+		}
+	}
+
+	return &Error{cause: err, Code: CodeInternal, Message: err.Error()}
 }
 
-func Unauthorizedf(format string, a ...interface{}) error {
-	return fmt.Errorf("%w: %s", ErrUnauthorized, fmt.Sprintf(format, a...))
+func newError(kind error, retryable bool, format string, a ...interface{}) *Error {
+	return &Error{kind: kind, Code: defaultCodes[kind], Message: fmt.Sprintf(format, a...), Retryable: retryable}
 }
@@ -0,0 +1,67 @@
+package failure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantKind      error
+		wantCode      Code
+		wantRetryable bool
+	}{
+		{name: "failure", err: Invalidf("bad"), wantKind: ErrInvalid, wantCode: CodeInvalid},
+		{
+			name:     "failure with code",
+			err:      NotFoundf("missing").WithCode(CodeRevisionNotFound),
+			wantKind: ErrNotFound,
+			wantCode: CodeRevisionNotFound,
+		},
+		{
+			name:          "wrapped failure",
+			err:           fmt.Errorf("failed to call: %w", Unavailablef("down")),
+			wantKind:      ErrUnavailable,
+			wantCode:      CodeUnavailable,
+			wantRetryable: true,
+		},
+		{
+			name:     "wrapped sentinel",
+			err:      fmt.Errorf("failed to check: %w", ErrUnauthorized),
+			wantKind: ErrUnauthorized,
+			wantCode: CodeUnauthorized,
+		},
+		{name: "other error", err: errors.New("boom"), wantCode: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Kind() != tt.wantKind {
+				t.Errorf("From() kind = %v, want %v", got.Kind(), tt.wantKind)
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("From() code = %s, want %s", got.Code, tt.wantCode)
+			}
+			if got.Retryable != tt.wantRetryable {
+				t.Errorf("From() retryable = %v, want %v", got.Retryable, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestError_WithFieldsKeepsOriginal(t *testing.T) {
+	original := Invalidf("invalid config").WithField("name", "is required")
+
+	extended := original.WithFields(FieldViolation{Field: "port", Message: "is out of range"})
+
+	if len(original.Fields) != 1 || len(extended.Fields) != 2 {
+		t.Errorf("fields = %v and %v, want the original left unchanged", original.Fields, extended.Fields)
+	}
+	if !errors.Is(extended, ErrInvalid) {
+		t.Errorf("errors.Is(%v, ErrInvalid) = false", extended)
+	}
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// An optional dependency is unreachable. The extension still serves requests,
+	// but the features relying on it fail.
+	StatusDegraded Status = "degraded"
+	// A required dependency is unreachable.
+	StatusDown Status = "down"
+)
+
+// Checks whether a dependency of the extension is reachable.
+type Checker interface {
+	Name() string
+	// Whether the extension is not ready while the dependency is unreachable.
+	Required() bool
+	// Returns an error if the dependency is unreachable.
+	Check(ctx context.Context) error
+}
+
+// The outcome of the latest check of a dependency.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Status   Status `json:"status"`
+	// How long the check took, in milliseconds.
+	LatencyMS float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	// The error of the last failed check, if any. It is kept after the
+	// dependency recovers, to help diagnose intermittent failures.
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Whether the extension is ready to serve requests, based on its dependencies.
+type Readiness struct {
+	// Down if a required dependency is unreachable, degraded if an optional
+	// one is, up otherwise.
+	Status Status         `json:"status"`
+	Checks []*CheckResult `json:"checks"`
+}
+
+// Whether the extension is alive. It is as long as it responds.
+type Liveness struct {
+	Status    Status    `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+	// How long the extension has been running, in seconds.
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
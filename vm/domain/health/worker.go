@@ -0,0 +1,40 @@
+package health
+
+import "time"
+
+type WorkerState string
+
+const (
+	WorkerRunning WorkerState = "running"
+	// The worker crashed and waits to be restarted.
+	WorkerRestarting WorkerState = "restarting"
+	// The worker stopped because the extension is shutting down.
+	WorkerStopped WorkerState = "stopped"
+)
+
+// The status of a background worker.
+type WorkerStatus struct {
+	Name  string      `json:"name"`
+	State WorkerState `json:"state"`
+	// How many times the worker was restarted after crashing.
+	Restarts  int       `json:"restarts"`
+	StartedAt time.Time `json:"started_at"`
+	// The error of the last crash, if any.
+	LastError   string     `json:"last_error,omitempty"`
+	LastCrashAt *time.Time `json:"last_crash_at,omitempty"`
+	// When the worker is restarted, if it is restarting.
+	RestartAt *time.Time `json:"restart_at,omitempty"`
+}
+
+// The health of all background workers.
+type WorkersHealth struct {
+	// Whether all workers are running.
+	Healthy bool            `json:"healthy"`
+	Workers []*WorkerStatus `json:"workers"`
+}
+
+// Reports the status of the background workers.
+type WorkerMonitor interface {
+	// Returns the status of every worker, ordered by name.
+	WorkerStatuses() []*WorkerStatus
+}
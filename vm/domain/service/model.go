@@ -0,0 +1,17 @@
+package service
+
+import "time"
+
+// Represents a service, also known as a project, of the Akita platform.
+type Service struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	DeploymentInfos []*DeploymentInfo `json:"deployment_infos"`
+}
+
+// Describes a deployment in which traffic of a service was observed.
+type DeploymentInfo struct {
+	Name          string    `json:"name"`
+	FirstObserved time.Time `json:"first_observed"`
+	LastObserved  time.Time `json:"last_observed"`
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"akita/domain/user"
+	"context"
+)
+
+type Repository interface {
+	// Returns the services that the user with the given credentials can access.
+	// If the Akita API rejects the credentials, a failure.ErrUnauthorized error
+	// is returned.
+	ListServices(ctx context.Context, credentials user.Credentials) ([]*Service, error)
+}
@@ -0,0 +1,54 @@
+package settings
+
+import (
+	"akita/domain/failure"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Whether the user agreed to send analytics.
+type Consent string
+
+const (
+	// The user hasn't decided yet. Nothing is sent until they do.
+	ConsentUndecided Consent = "undecided"
+	ConsentGranted   Consent = "granted"
+	ConsentDenied    Consent = "denied"
+)
+
+// The user's telemetry settings.
+type Telemetry struct {
+	Consent Consent `json:"consent"`
+	// When the user last decided. Nil while the user is undecided.
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// Returns the settings of a user who hasn't decided yet, e.g. on first run.
+func NewUndecidedTelemetry() *Telemetry {
+	return &Telemetry{Consent: ConsentUndecided}
+}
+
+// Reports whether analytics may be recorded.
+func (t Telemetry) IsAllowed() bool {
+	return t.Consent == ConsentGranted
+}
+
+// Decodes the user's decision about telemetry from the given reader.
+func DecodeTelemetry(r io.Reader) (*Telemetry, error) {
+	var payload struct {
+		Consent Consent `json:"consent"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, failure.Invalidf("failed to decode telemetry settings: %v", err)
+	}
+
+	if payload.Consent != ConsentGranted && payload.Consent != ConsentDenied {
+		return nil, failure.Invalidf("invalid telemetry consent %q", payload.Consent).
+			WithField("consent", "must be granted or denied")
+	}
+
+	decidedAt := time.Now().UTC()
+	return &Telemetry{Consent: payload.Consent, DecidedAt: &decidedAt}, nil
+}
@@ -0,0 +1,64 @@
+package settings
+
+import (
+	"akita/domain/failure"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTelemetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantConsent Consent
+		wantErr     bool
+	}{
+		{name: "granted", body: `{"consent": "granted"}`, wantConsent: ConsentGranted},
+		{name: "denied", body: `{"consent": "denied"}`, wantConsent: ConsentDenied},
+		{name: "undecided", body: `{"consent": "undecided"}`, wantErr: true},
+		{name: "missing consent", body: `{}`, wantErr: true},
+		{name: "malformed", body: `{"consent":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry, err := DecodeTelemetry(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if !errors.Is(err, failure.ErrInvalid) {
+					t.Fatalf("DecodeTelemetry() error = %v, want %v", err, failure.ErrInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeTelemetry() error = %v", err)
+			}
+
+			if telemetry.Consent != tt.wantConsent {
+				t.Errorf("DecodeTelemetry() consent = %s, want %s", telemetry.Consent, tt.wantConsent)
+			}
+			if telemetry.DecidedAt == nil {
+				t.Error("DecodeTelemetry() didn't record when the user decided")
+			}
+		})
+	}
+}
+
+func TestTelemetry_IsAllowed(t *testing.T) {
+	tests := []struct {
+		consent Consent
+		want    bool
+	}{
+		{consent: ConsentUndecided},
+		{consent: ConsentGranted, want: true},
+		{consent: ConsentDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.consent), func(t *testing.T) {
+			if got := (Telemetry{Consent: tt.consent}).IsAllowed(); got != tt.want {
+				t.Errorf("IsAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package settings
+
+import "context"
+
+type Repository interface {
+	// Returns the user's telemetry settings. If the user hasn't decided yet,
+	// undecided settings are returned.
+	GetTelemetry(ctx context.Context) (*Telemetry, error)
+	// Saves the user's telemetry settings, overwriting the previous ones.
+	SaveTelemetry(ctx context.Context, telemetry *Telemetry) error
+}
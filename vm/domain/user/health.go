@@ -0,0 +1,31 @@
+package user
+
+import "time"
+
+// The health of the Akita API as observed by the extension.
+type APIHealth struct {
+	// The URL of the API.
+	APIURL string `json:"api_url"`
+	// False while calls to the API fail fast because it has been failing.
+	Healthy bool `json:"healthy"`
+	// The state of the circuit breaker guarding calls to the API: closed,
+	// open or half_open.
+	BreakerState string `json:"breaker_state"`
+	// The number of failed calls since the last successful call.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// When calls last started failing fast, if ever.
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	// When a call is attempted again, if calls currently fail fast.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+	// The error of the last failed call, if any.
+	LastError string `json:"last_error,omitempty"`
+	// The statistics of the cache of users resolved through the API.
+	UserCache CacheStats `json:"user_cache"`
+}
+
+// Statistics of a cache.
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
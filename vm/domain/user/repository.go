@@ -1,8 +1,19 @@
 package user
 
+import "context"
+
 type Repository interface {
-	// Returns the Akita user based on the given API credentials.
-	GetUser(credentials Credentials) (*User, error)
+	// Returns the Akita user based on the given API credentials. Users are
+	// cached, and a recently cached user is returned while the API is unavailable.
+	GetUser(ctx context.Context, credentials Credentials) (*User, error)
+	// Removes the cached user of the given credentials, if any.
+	InvalidateUser(credentials Credentials)
+	// Verifies the given API credentials against the Akita API, bypassing the
+	// cache, and classifies the outcome. Failures to reach the API are reported as outcomes rather
+	// than errors.
+	VerifyCredentials(ctx context.Context, credentials Credentials) (*Verification, error)
 	// Enqueues an analytics event for the given user.
-	EnqueueUserEvent(event *Event) error
+	EnqueueUserEvent(ctx context.Context, event *Event) error
+	// Returns the health of the Akita API.
+	Health() *APIHealth
 }
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"akita/domain/failure"
+	"encoding/json"
+	"io"
+)
+
+// The outcome of verifying credentials against the Akita API.
+type VerificationOutcome string
+
+const (
+	// The credentials belong to a user.
+	OutcomeValid VerificationOutcome = "valid"
+	// The API rejected the credentials.
+	OutcomeUnauthorized VerificationOutcome = "unauthorized"
+	// The credentials are valid but the user isn't allowed to access the API.
+	OutcomeForbidden VerificationOutcome = "forbidden"
+	// The API couldn't be reached, e.g. because of a firewall or proxy.
+	OutcomeNetworkUnreachable VerificationOutcome = "network_unreachable"
+	// The host name of the API couldn't be resolved.
+	OutcomeDNSFailure VerificationOutcome = "dns_failure"
+	// The TLS connection to the API couldn't be established, e.g. because a
+	// proxy intercepts it with an untrusted certificate.
+	OutcomeTLSError VerificationOutcome = "tls_error"
+	// The API failed to handle the request.
+	OutcomeServerError VerificationOutcome = "server_error"
+	// The API rejected the request because too many requests were made.
+	OutcomeRateLimited VerificationOutcome = "rate_limited"
+	// The API responded in an unexpected way.
+	OutcomeUnexpected VerificationOutcome = "unexpected"
+	// The API wasn't called because it has been failing recently.
+	OutcomeCircuitOpen VerificationOutcome = "circuit_open"
+)
+
+// Returns true if the outcome may change when the verification is retried.
+func (o VerificationOutcome) IsTransient() bool {
+	switch o {
+	case OutcomeNetworkUnreachable, OutcomeDNSFailure, OutcomeServerError, OutcomeRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// The result of verifying credentials against the Akita API.
+type Verification struct {
+	Outcome VerificationOutcome `json:"outcome"`
+	// A human-readable explanation of the outcome.
+	Message string `json:"message"`
+	// The URL of the API that the credentials were verified against.
+	APIURL string `json:"api_url"`
+	// The HTTP status of the API response, if there was one.
+	StatusCode int `json:"status_code,omitempty"`
+	// The number of calls made to the API.
+	Attempts int `json:"attempts"`
+	// How long the API took to respond to the last call, in milliseconds.
+	LatencyMillis int64 `json:"latency_ms"`
+	// The user that the credentials belong to, if they are valid.
+	User *User `json:"user,omitempty"`
+}
+
+// Returns true if the credentials belong to a user.
+func (v Verification) IsValid() bool {
+	return v.Outcome == OutcomeValid
+}
+
+// Returns the error that the outcome of the verification represents, or nil
+// if the credentials are valid.
+func (v Verification) Err() error {
+	switch v.Outcome {
+	case OutcomeValid:
+		return nil
+	case OutcomeUnauthorized, OutcomeForbidden:
+		return failure.Unauthorizedf("%s", v.Message).WithCode(failure.CodeInvalidCredentials)
+	default:
+		// The API is failing to serve the request, even if in an unexpected way.
+		return failure.Unavailablef("%s", v.Message).WithCode(failure.CodeAkitaAPIUnavailable)
+	}
+}
+
+// Decodes the credentials in the given payload.
+func DecodeCredentials(r io.Reader) (Credentials, error) {
+	var payload struct {
+		APIKey    string `json:"api_key"`
+		APISecret string `json:"api_secret"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return Credentials{}, failure.Invalidf("failed to decode credentials: %v", err)
+	}
+
+	result := Credentials{APIKey: payload.APIKey, APISecret: payload.APISecret}
+	if err := result.Validate(); err != nil {
+		return Credentials{}, err
+	}
+
+	return result, nil
+}
+
+func (c Credentials) Validate() error {
+	err := failure.Invalidf("credentials are incomplete")
+	if c.APIKey == "" {
+		err = err.WithField("api_key", "api key is missing")
+	}
+	if c.APISecret == "" {
+		err = err.WithField("api_secret", "api secret is missing")
+	}
+
+	if len(err.Fields) > 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"akita/domain/failure"
+	"errors"
+	"testing"
+)
+
+func TestVerification_Err(t *testing.T) {
+	tests := []struct {
+		outcome       VerificationOutcome
+		wantKind      error
+		wantCode      failure.Code
+		wantRetryable bool
+	}{
+		{outcome: OutcomeValid},
+		{outcome: OutcomeUnauthorized, wantKind: failure.ErrUnauthorized, wantCode: failure.CodeInvalidCredentials},
+		{outcome: OutcomeForbidden, wantKind: failure.ErrUnauthorized, wantCode: failure.CodeInvalidCredentials},
+		{
+			outcome:       OutcomeNetworkUnreachable,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeDNSFailure,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeTLSError,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeServerError,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeRateLimited,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeUnexpected,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+		{
+			outcome:       OutcomeCircuitOpen,
+			wantKind:      failure.ErrUnavailable,
+			wantCode:      failure.CodeAkitaAPIUnavailable,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.outcome), func(t *testing.T) {
+			err := Verification{Outcome: tt.outcome, Message: "message"}.Err()
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Fatalf("Err() = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Err() = %v, want %v", err, tt.wantKind)
+			}
+			fail := failure.From(err)
+			if fail.Code != tt.wantCode || fail.Retryable != tt.wantRetryable {
+				t.Errorf(
+					"Err() code = %s, retryable = %t, want %s, %t",
+					fail.Code,
+					fail.Retryable,
+					tt.wantCode,
+					tt.wantRetryable,
+				)
+			}
+		})
+	}
+}
@@ -1,32 +1,384 @@
 package datasource
 
 import (
+	"akita/domain/event"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/akitasoftware/akita-libs/analytics"
-	"github.com/labstack/gommon/log"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-func ProvideAnalyticsClient(config analytics.Config, enabled bool) (analytics.Client, error) {
+// The Segment endpoint that events are sent to if none is configured.
+const defaultSegmentEndpoint = "https://api.segment.io"
+
+// Sends analytics events and releases its resources once closed.
+type AnalyticsClient interface {
+	event.Sender
+	Close() error
+}
+
+// Creates a client that sends events to Segment while analytics are enabled,
+// and discards them otherwise. The Segment write key is only required while
+// analytics are enabled.
+func ProvideSegmentClient(
+	config analytics.Config,
+	enabled bool,
+	httpClient *http.Client,
+	logger *logrus.Logger,
+) (*SegmentClient, error) {
+	endpoint := config.SegmentEndpoint
+	if endpoint == "" {
+		endpoint = defaultSegmentEndpoint
+	}
+
+	client := &SegmentClient{
+		config:     config,
+		batchURL:   strings.TrimRight(endpoint, "/") + "/v1/batch",
+		httpClient: httpClient,
+		logger:     logger,
+	}
+	if err := client.SetEnabled(enabled); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Sends batches of events to the Segment HTTP API. Every batch is sent in a
+// single request, so that a failure is reported to the caller rather than
+// retried in the background. Analytics can be enabled and disabled at runtime.
+type SegmentClient struct {
+	config     analytics.Config
+	batchURL   string
+	httpClient *http.Client
+	logger     *logrus.Logger
+	enabled    atomic.Bool
+}
+
+// The body of a request to the Segment batch endpoint.
+type segmentBatch struct {
+	Batch   []segmentTrack `json:"batch"`
+	SentAt  time.Time      `json:"sentAt"`
+	Context segmentContext `json:"context"`
+}
+
+type segmentTrack struct {
+	Type string `json:"type"`
+	// Segment deduplicates messages by ID, so a batch that is sent again after
+	// an ambiguous failure isn't recorded twice.
+	MessageID    string          `json:"messageId"`
+	UserID       string          `json:"userId"`
+	Event        string          `json:"event"`
+	Properties   map[string]any  `json:"properties"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Integrations map[string]bool `json:"integrations,omitempty"`
+}
+
+type segmentContext struct {
+	App analytics.AppInfo `json:"app"`
+}
+
+// Enables or disables sending events. Analytics can't be enabled without a
+// Segment write key, in which case they stay disabled and an error is returned.
+func (s *SegmentClient) SetEnabled(enabled bool) error {
+	if enabled && s.config.WriteKey == "" {
+		s.enabled.Store(false)
+		return errors.New("analytics can't be enabled without a Segment write key")
+	}
+
 	if !enabled {
-		log.Infof("No Segment write key provided, analytics will be disabled")
-		return &disabledAnalyticsClient{}, nil
+		s.logger.Infof("Analytics are disabled")
+	}
+	s.enabled.Store(enabled)
+	return nil
+}
+
+func (s *SegmentClient) Send(ctx context.Context, queuedEvents []*event.QueuedEvent) error {
+	if !s.enabled.Load() {
+		return event.ErrSendingDisabled
+	}
+
+	batch := segmentBatch{
+		Batch:   make([]segmentTrack, 0, len(queuedEvents)),
+		SentAt:  time.Now().UTC(),
+		Context: segmentContext{App: s.config.App},
+	}
+	for _, queuedEvent := range queuedEvents {
+		batch.Batch = append(batch.Batch, segmentTrack{
+			Type:         "track",
+			MessageID:    queuedEvent.ID,
+			UserID:       queuedEvent.DistinctID,
+			Event:        queuedEvent.Name,
+			Properties:   queuedEvent.Properties,
+			Timestamp:    queuedEvent.EnqueuedAt,
+			Integrations: s.config.DefaultIntegrations,
+		})
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode analytics events: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.batchURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create analytics request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.SetBasicAuth(s.config.WriteKey, "")
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send analytics events: %w", err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("failed to send analytics events: %s", response.Status)
+	}
+
+	return nil
+}
+
+// Events are sent synchronously, so there is nothing to flush.
+func (s *SegmentClient) Close() error {
+	s.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// An analytics client that records events locally instead of sending them,
+// so that exactly what the extension would send can be inspected.
+type LocalAnalyticsClient interface {
+	AnalyticsClient
+	// Returns up to limit of the most recently recorded events, newest first.
+	RecentEvents(limit int) ([]*event.RecordedEvent, error)
+}
+
+// Creates an analytics client that keeps the given number of the most recent
+// events in memory.
+func ProvideMemoryAnalyticsClient(capacity int) LocalAnalyticsClient {
+	return &memoryAnalyticsClient{events: make([]*event.RecordedEvent, 0, capacity), capacity: capacity}
+}
+
+type memoryAnalyticsClient struct {
+	mu sync.Mutex
+	// A ring buffer of the recorded events.
+	events   []*event.RecordedEvent
+	capacity int
+	// The index of the oldest event once the buffer is full.
+	next int
+}
+
+func (m *memoryAnalyticsClient) Send(_ context.Context, queuedEvents []*event.QueuedEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, queuedEvent := range queuedEvents {
+		recordedEvent := toRecordedEvent(queuedEvent)
+		if len(m.events) < m.capacity {
+			m.events = append(m.events, recordedEvent)
+			continue
+		}
+
+		m.events[m.next] = recordedEvent
+		m.next = (m.next + 1) % m.capacity
 	}
 
-	return analytics.NewClient(config)
+	return nil
 }
 
-// A custom analytics client that does nothing. This is used when no
-// configuration for Segment has been provided.
-type disabledAnalyticsClient struct {
+func (m *memoryAnalyticsClient) RecentEvents(limit int) ([]*event.RecordedEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := []*event.RecordedEvent{}
+	for i := 0; i < len(m.events) && len(result) < limit; i++ {
+		// Walk backwards from the newest event.
+		index := (m.next - 1 - i + 2*len(m.events)) % len(m.events)
+		result = append(result, m.events[index])
+	}
+
+	return result, nil
 }
 
-func (d disabledAnalyticsClient) TrackEvent(*analytics.Event) error {
+func (m *memoryAnalyticsClient) Close() error {
 	return nil
 }
 
-func (d disabledAnalyticsClient) Track(string, string, map[string]any) error {
+// Creates an analytics client that appends events to the JSONL file at the
+// given path. Once the file exceeds the maximum size it is rotated, and the
+// given number of rotated files are kept.
+func ProvideFileAnalyticsClient(path string, maxSizeBytes int64, maxFiles int) (LocalAnalyticsClient, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of analytics event file: %w", err)
+	}
+
+	client := &fileAnalyticsClient{path: path, maxSizeBytes: maxSizeBytes, maxFiles: maxFiles}
+	if err := client.open(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+type fileAnalyticsClient struct {
+	path         string
+	maxSizeBytes int64
+	maxFiles     int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (f *fileAnalyticsClient) Send(_ context.Context, queuedEvents []*event.QueuedEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, queuedEvent := range queuedEvents {
+		line, err := json.Marshal(toRecordedEvent(queuedEvent))
+		if err != nil {
+			return fmt.Errorf("failed to encode analytics event: %w", err)
+		}
+		line = append(line, '\n')
+
+		if f.size > 0 && f.size+int64(len(line)) > f.maxSizeBytes {
+			if err := f.rotate(); err != nil {
+				return err
+			}
+		}
+
+		written, err := f.file.Write(line)
+		f.size += int64(written)
+		if err != nil {
+			return fmt.Errorf("failed to write analytics event: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (d disabledAnalyticsClient) Close() error {
+func (f *fileAnalyticsClient) RecentEvents(limit int) ([]*event.RecordedEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := []*event.RecordedEvent{}
+	// The current file holds the newest events, followed by the rotated files in order.
+	for i := 0; i <= f.maxFiles && len(result) < limit; i++ {
+		events, err := readRecordedEvents(f.rotatedPath(i))
+		if err != nil {
+			return nil, err
+		}
+
+		for j := len(events) - 1; j >= 0 && len(result) < limit; j-- {
+			result = append(result, events[j])
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fileAnalyticsClient) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+// Opens the current file for appending.
+func (f *fileAnalyticsClient) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open analytics event file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open analytics event file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
 	return nil
 }
+
+// Shifts the rotated files, dropping the oldest, and starts a new current file.
+func (f *fileAnalyticsClient) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate analytics event file: %w", err)
+	}
+
+	_ = os.Remove(f.rotatedPath(f.maxFiles))
+	for i := f.maxFiles - 1; i >= 0; i-- {
+		err := os.Rename(f.rotatedPath(i), f.rotatedPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate analytics event file: %w", err)
+		}
+	}
+
+	return f.open()
+}
+
+// Returns the path of the file rotated the given number of times. The current file is rotated zero times.
+func (f *fileAnalyticsClient) rotatedPath(rotations int) string {
+	if rotations == 0 {
+		return f.path
+	}
+	return fmt.Sprintf("%s.%d", f.path, rotations)
+}
+
+// Returns the events recorded in the given JSONL file, oldest first. A missing
+// file holds no events.
+func readRecordedEvents(path string) ([]*event.RecordedEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read analytics event file: %w", err)
+	}
+	defer file.Close()
+
+	result := []*event.RecordedEvent{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var recordedEvent event.RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &recordedEvent); err != nil {
+			// Skip lines that were cut short, e.g. by a crash.
+			continue
+		}
+		result = append(result, &recordedEvent)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytics event file: %w", err)
+	}
+
+	return result, nil
+}
+
+// Returns the recorded form of the given event.
+func toRecordedEvent(queuedEvent *event.QueuedEvent) *event.RecordedEvent {
+	return &event.RecordedEvent{
+		RecordedAt: time.Now().UTC(),
+		Timestamp:  queuedEvent.EnqueuedAt,
+		DistinctID: queuedEvent.DistinctID,
+		Name:       queuedEvent.Name,
+		Properties: queuedEvent.Properties,
+	}
+}
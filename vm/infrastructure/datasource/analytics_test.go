@@ -0,0 +1,243 @@
+package datasource
+
+import (
+	"akita/domain/event"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/akitasoftware/akita-libs/analytics"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSegmentClient_Send(t *testing.T) {
+	queuedEvents := []*event.QueuedEvent{
+		{ID: "1", DistinctID: "user", Name: "Viewed Agent Page", EnqueuedAt: time.Now().UTC()},
+		{ID: "2", DistinctID: "user", Name: "Stopped Agent", EnqueuedAt: time.Now().UTC()},
+	}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		status     int
+		wantErr    bool
+		wantErrIs  error
+		wantSentTo bool
+	}{
+		{name: "accepted batch", enabled: true, status: http.StatusOK, wantSentTo: true},
+		{name: "rejected batch", enabled: true, status: http.StatusBadRequest, wantErr: true, wantSentTo: true},
+		{name: "server error", enabled: true, status: http.StatusServiceUnavailable, wantErr: true, wantSentTo: true},
+		{name: "disabled", enabled: false, wantErr: true, wantErrIs: event.ErrSendingDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *segmentBatch
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/batch" {
+					t.Errorf("request path = %s, want /v1/batch", r.URL.Path)
+				}
+				if key, _, _ := r.BasicAuth(); key != "write-key" {
+					t.Errorf("write key = %q, want write-key", key)
+				}
+
+				received = &segmentBatch{}
+				if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+					t.Errorf("failed to decode batch: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			client, err := ProvideSegmentClient(
+				analytics.Config{WriteKey: "write-key", SegmentEndpoint: server.URL},
+				tt.enabled,
+				server.Client(),
+				logger,
+			)
+			if err != nil {
+				t.Fatalf("ProvideSegmentClient() error = %v", err)
+			}
+
+			err = client.Send(context.Background(), queuedEvents)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("Send() error = %v, want %v", err, tt.wantErrIs)
+			}
+
+			if !tt.wantSentTo {
+				if received != nil {
+					t.Error("Send() sent a request while disabled")
+				}
+				return
+			}
+			if received == nil || len(received.Batch) != len(queuedEvents) {
+				t.Fatalf("received batch %+v, want all %d events in one request", received, len(queuedEvents))
+			}
+			for i, track := range received.Batch {
+				if track.MessageID != queuedEvents[i].ID || track.Event != queuedEvents[i].Name {
+					t.Errorf("message %d = %+v, want event %s with ID %s", i, track, queuedEvents[i].Name, queuedEvents[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestProvideSegmentClient_WriteKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		writeKey    string
+		enabled     bool
+		wantErr     bool
+		wantEnabled bool
+		// Whether enabling analytics at runtime fails.
+		wantEnableErr bool
+	}{
+		{name: "enabled with key", writeKey: "write-key", enabled: true, wantEnabled: true},
+		{name: "disabled with key", writeKey: "write-key"},
+		{name: "disabled without key", wantEnableErr: true},
+		{name: "enabled without key", enabled: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			client, err := ProvideSegmentClient(analytics.Config{WriteKey: tt.writeKey}, tt.enabled, http.DefaultClient, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProvideSegmentClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if enabled := client.enabled.Load(); enabled != tt.wantEnabled {
+				t.Errorf("enabled = %v, want %v", enabled, tt.wantEnabled)
+			}
+
+			err = client.SetEnabled(true)
+			if (err != nil) != tt.wantEnableErr {
+				t.Fatalf("SetEnabled(true) error = %v, wantErr %v", err, tt.wantEnableErr)
+			}
+			if err != nil {
+				// Events are discarded rather than sent without a key.
+				if err := client.Send(context.Background(), nil); !errors.Is(err, event.ErrSendingDisabled) {
+					t.Errorf("Send() error = %v, want %v", err, event.ErrSendingDisabled)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalAnalyticsClient_Send(t *testing.T) {
+	enqueuedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	queuedEvent := func(name string) *event.QueuedEvent {
+		return &event.QueuedEvent{
+			ID:         name,
+			DistinctID: "user",
+			Name:       name,
+			Properties: map[string]any{"port": float64(8080)},
+			EnqueuedAt: enqueuedAt,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		provide   func(t *testing.T) LocalAnalyticsClient
+		sent      []string
+		limit     int
+		wantNames []string
+	}{
+		{
+			name:      "memory",
+			provide:   func(*testing.T) LocalAnalyticsClient { return ProvideMemoryAnalyticsClient(10) },
+			sent:      []string{"a", "b", "c"},
+			limit:     10,
+			wantNames: []string{"c", "b", "a"},
+		},
+		{
+			name:      "memory over capacity",
+			provide:   func(*testing.T) LocalAnalyticsClient { return ProvideMemoryAnalyticsClient(2) },
+			sent:      []string{"a", "b", "c"},
+			limit:     10,
+			wantNames: []string{"c", "b"},
+		},
+		{
+			name:      "memory with limit",
+			provide:   func(*testing.T) LocalAnalyticsClient { return ProvideMemoryAnalyticsClient(10) },
+			sent:      []string{"a", "b", "c"},
+			limit:     1,
+			wantNames: []string{"c"},
+		},
+		{
+			name: "file",
+			provide: func(t *testing.T) LocalAnalyticsClient {
+				return provideTestFileAnalyticsClient(t, 1024*1024, 1)
+			},
+			sent:      []string{"a", "b", "c"},
+			limit:     10,
+			wantNames: []string{"c", "b", "a"},
+		},
+		{
+			name: "file across rotations",
+			provide: func(t *testing.T) LocalAnalyticsClient {
+				// Each event is larger than the maximum size, so every event rotates the file.
+				return provideTestFileAnalyticsClient(t, 1, 1)
+			},
+			sent:      []string{"a", "b", "c"},
+			limit:     10,
+			wantNames: []string{"c", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.provide(t)
+			defer client.Close()
+
+			var queuedEvents []*event.QueuedEvent
+			for _, name := range tt.sent {
+				queuedEvents = append(queuedEvents, queuedEvent(name))
+			}
+			if err := client.Send(context.Background(), queuedEvents); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			recordedEvents, err := client.RecentEvents(tt.limit)
+			if err != nil {
+				t.Fatalf("RecentEvents() error = %v", err)
+			}
+
+			var names []string
+			for _, recordedEvent := range recordedEvents {
+				names = append(names, recordedEvent.Name)
+				if recordedEvent.DistinctID != "user" || !recordedEvent.Timestamp.Equal(enqueuedAt) {
+					t.Errorf("RecentEvents() event = %+v, want the distinct ID and time it was queued with", recordedEvent)
+				}
+				if recordedEvent.Properties["port"] != float64(8080) {
+					t.Errorf("RecentEvents() properties = %v, want the queued properties", recordedEvent.Properties)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("RecentEvents() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func provideTestFileAnalyticsClient(t *testing.T, maxSizeBytes int64, maxFiles int) LocalAnalyticsClient {
+	client, err := ProvideFileAnalyticsClient(filepath.Join(t.TempDir(), "events.jsonl"), maxSizeBytes, maxFiles)
+	if err != nil {
+		t.Fatalf("ProvideFileAnalyticsClient() error = %v", err)
+	}
+	return client
+}
@@ -0,0 +1,147 @@
+package datasource
+
+import (
+	"sync"
+	"time"
+)
+
+// The state of a circuit breaker.
+type BreakerState string
+
+const (
+	// Calls are allowed.
+	BreakerClosed BreakerState = "closed"
+	// Calls fail fast because the dependency has been failing.
+	BreakerOpen BreakerState = "open"
+	// A single trial call is allowed to find out whether the dependency recovered.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// A snapshot of the state of a circuit breaker.
+type BreakerSnapshot struct {
+	State BreakerState
+	// The number of failed calls since the last successful call.
+	ConsecutiveFailures int
+	// When the breaker last opened. Zero if it never opened.
+	OpenedAt time.Time
+	// When the breaker may allow calls again. While a trial call is in flight,
+	// calls may be retried as soon as it succeeds. Zero while calls are allowed.
+	RetryAt time.Time
+	// The error of the last failed call, if any.
+	LastError string
+}
+
+// Stops calls to a dependency after too many consecutive failures, so that
+// callers fail fast while the dependency is down. After a cool-down, a single
+// trial call decides whether calls are allowed again.
+type CircuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu                  sync.Mutex
+	state               BreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+	lastError           string
+}
+
+// Creates a circuit breaker that opens after the given number of consecutive
+// failures and stays open for the given duration.
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		state:            BreakerClosed,
+	}
+}
+
+// Returns true if a call may be made. Every allowed call must be followed by
+// a call to RecordSuccess, RecordFailure or Release.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Records that a call succeeded, which closes the breaker.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.trialInFlight = false
+}
+
+// Records that a call failed. The breaker opens if the trial call failed or
+// too many calls failed in a row.
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.lastError = err.Error()
+	b.trialInFlight = false
+
+	if b.state == BreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Records that an allowed call was abandoned before its outcome was known.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+}
+
+// Closes the breaker and forgets past failures, e.g. when the dependency is replaced.
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.trialInFlight = false
+	b.lastError = ""
+}
+
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := BreakerSnapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		OpenedAt:            b.openedAt,
+		LastError:           b.lastError,
+	}
+	switch {
+	case b.state == BreakerOpen:
+		snapshot.RetryAt = b.openedAt.Add(b.openDuration)
+	case b.state == BreakerHalfOpen && b.trialInFlight:
+		snapshot.RetryAt = time.Now()
+	}
+
+	return snapshot
+}
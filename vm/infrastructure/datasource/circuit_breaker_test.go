@@ -0,0 +1,148 @@
+package datasource
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	// The steps that can be applied to a breaker. Calls are only recorded if
+	// the breaker allowed them.
+	allowAndFail := func(b *CircuitBreaker) bool {
+		allowed := b.Allow()
+		if allowed {
+			b.RecordFailure(failure)
+		}
+		return allowed
+	}
+	allowAndSucceed := func(b *CircuitBreaker) bool {
+		allowed := b.Allow()
+		if allowed {
+			b.RecordSuccess()
+		}
+		return allowed
+	}
+	allowAndRelease := func(b *CircuitBreaker) bool {
+		allowed := b.Allow()
+		if allowed {
+			b.Release()
+		}
+		return allowed
+	}
+	allow := func(b *CircuitBreaker) bool {
+		return b.Allow()
+	}
+	waitOpenDuration := func(b *CircuitBreaker) bool {
+		time.Sleep(b.openDuration)
+		return true
+	}
+
+	tests := []struct {
+		name         string
+		steps        []func(b *CircuitBreaker) bool
+		wantAllowed  []bool
+		wantState    BreakerState
+		wantFailures int
+		wantRetryAt  bool
+	}{
+		{
+			name:         "closed below the threshold",
+			steps:        []func(b *CircuitBreaker) bool{allowAndFail, allowAndFail},
+			wantAllowed:  []bool{true, true},
+			wantState:    BreakerClosed,
+			wantFailures: 2,
+		},
+		{
+			name:        "success resets failures",
+			steps:       []func(b *CircuitBreaker) bool{allowAndFail, allowAndFail, allowAndSucceed},
+			wantAllowed: []bool{true, true, true},
+			wantState:   BreakerClosed,
+		},
+		{
+			name:         "opens at the threshold",
+			steps:        []func(b *CircuitBreaker) bool{allowAndFail, allowAndFail, allowAndFail, allow},
+			wantAllowed:  []bool{true, true, true, false},
+			wantState:    BreakerOpen,
+			wantFailures: 3,
+			wantRetryAt:  true,
+		},
+		{
+			name: "refuses calls while the trial is in flight",
+			steps: []func(b *CircuitBreaker) bool{
+				allowAndFail, allowAndFail, allowAndFail, waitOpenDuration, allow, allow,
+			},
+			wantAllowed:  []bool{true, true, true, true, true, false},
+			wantState:    BreakerHalfOpen,
+			wantFailures: 3,
+			wantRetryAt:  true,
+		},
+		{
+			name: "closes after a successful trial",
+			steps: []func(b *CircuitBreaker) bool{
+				allowAndFail, allowAndFail, allowAndFail, waitOpenDuration, allowAndSucceed, allow,
+			},
+			wantAllowed: []bool{true, true, true, true, true, true},
+			wantState:   BreakerClosed,
+		},
+		{
+			name: "reopens after a failed trial",
+			steps: []func(b *CircuitBreaker) bool{
+				allowAndFail, allowAndFail, allowAndFail, waitOpenDuration, allowAndFail, allow,
+			},
+			wantAllowed:  []bool{true, true, true, true, true, false},
+			wantState:    BreakerOpen,
+			wantFailures: 4,
+			wantRetryAt:  true,
+		},
+		{
+			name: "allows another trial after a released one",
+			steps: []func(b *CircuitBreaker) bool{
+				allowAndFail, allowAndFail, allowAndFail, waitOpenDuration, allowAndRelease, allowAndRelease,
+			},
+			wantAllowed:  []bool{true, true, true, true, true, true},
+			wantState:    BreakerHalfOpen,
+			wantFailures: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(3, 10*time.Millisecond)
+
+			for i, step := range tt.steps {
+				if allowed := step(breaker); allowed != tt.wantAllowed[i] {
+					t.Fatalf("step %d allowed = %t, want %t", i, allowed, tt.wantAllowed[i])
+				}
+			}
+
+			snapshot := breaker.Snapshot()
+			if snapshot.State != tt.wantState {
+				t.Errorf("Snapshot() state = %s, want %s", snapshot.State, tt.wantState)
+			}
+			if snapshot.ConsecutiveFailures != tt.wantFailures {
+				t.Errorf("Snapshot() failures = %d, want %d", snapshot.ConsecutiveFailures, tt.wantFailures)
+			}
+			if hasRetryAt := !snapshot.RetryAt.IsZero(); hasRetryAt != tt.wantRetryAt {
+				t.Errorf("Snapshot() retry at = %s, want set = %t", snapshot.RetryAt, tt.wantRetryAt)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_Reset(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Hour)
+	breaker.Allow()
+	breaker.RecordFailure(errors.New("connection refused"))
+
+	breaker.Reset()
+
+	if !breaker.Allow() {
+		t.Fatalf("Allow() = false after Reset(), want true")
+	}
+	if snapshot := breaker.Snapshot(); snapshot.State != BreakerClosed || snapshot.LastError != "" {
+		t.Errorf("Snapshot() = %+v after Reset(), want a closed breaker without errors", snapshot)
+	}
+}
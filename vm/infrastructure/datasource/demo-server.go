@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -13,6 +14,8 @@ type (
 		GetBreed() error
 		// Send a random trick request to the demo server.
 		PostTrick() error
+		// Returns an error if the demo server can't be reached.
+		Ping(ctx context.Context) error
 	}
 	demoServerImpl struct {
 		client *resty.Client
@@ -95,6 +98,19 @@ func getRandomTrickID() (string, error) {
 	return pickFromWeightedMap(tricks)
 }
 
+func (d demoServerImpl) Ping(ctx context.Context) error {
+	// The admin API answers as soon as the server is up, regardless of the stubs.
+	response, err := d.client.R().SetContext(ctx).SetQueryParam("limit", "1").Get("/__admin/mappings")
+	if err != nil {
+		return fmt.Errorf("failed to reach demo server: %w", err)
+	}
+	if response.IsError() {
+		return fmt.Errorf("demo server responded with status %d", response.StatusCode())
+	}
+
+	return nil
+}
+
 // Adds stubs & mappings to the demo server.
 func (d demoServerImpl) addConfiguration(configuration []byte) error {
 	_, err := d.client.R().SetBody(configuration).Post("/__admin/mappings/import")
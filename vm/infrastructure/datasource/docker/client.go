@@ -4,9 +4,14 @@ import (
 	"akita/domain/failure"
 	"context"
 	"errors"
+	"fmt"
 	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	docker "github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+	"io"
 )
 
 type (
@@ -20,26 +25,55 @@ type (
 		// Returns true if a container exists in the docker host that matches the input filter options.
 		// If an error occurs, false is returned along with the error.
 		ContainerExists(ctx context.Context, opts ContainerFilterOptions) (bool, error)
+		// Returns low-level information about the container with the given ID or name.
+		// If no container is found, a failure.ErrNotFound error is returned.
+		InspectContainer(ctx context.Context, id string) (*dockertypes.ContainerJSON, error)
+		// Pulls the given image reference and blocks until the pull has completed.
+		PullImage(ctx context.Context, ref string) error
+		// Returns true if the given image reference is present on the docker host.
+		ImageExists(ctx context.Context, ref string) (bool, error)
+		// Creates a container with the given name and returns its ID.
+		CreateContainer(ctx context.Context, name string, opts ContainerCreateOptions) (string, error)
+		// Starts the container with the given ID.
+		StartContainer(ctx context.Context, id string) error
+		// Removes the container with the given ID. Running containers are killed first.
+		// If no container is found, a failure.ErrNotFound error is returned.
+		RemoveContainer(ctx context.Context, id string) error
+		// Streams events from the docker host that match the input options until the context is cancelled.
+		// If the stream fails, it is re-established with exponential backoff, resuming after the last received event.
+		// The returned channel is closed once the context is cancelled.
+		SubscribeEvents(ctx context.Context, opts EventOptions) <-chan events.Message
+		// Returns an error if the docker daemon can't be reached.
+		Ping(ctx context.Context) error
 		Close() error
 	}
 	clientImpl struct {
-		cli *docker.Client
+		cli    *docker.Client
+		logger *logrus.Logger
 	}
 )
 
-func NewClient() (Client, error) {
+func NewClient(logger *logrus.Logger) (Client, error) {
 	cli, err := docker.NewClientWithOpts(docker.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
 
-	return &clientImpl{cli: cli}, nil
+	return &clientImpl{cli: cli, logger: logger}, nil
 }
 
 func (c clientImpl) Close() error {
 	return c.cli.Close()
 }
 
+func (c clientImpl) Ping(ctx context.Context) error {
+	if _, err := c.cli.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to reach docker daemon: %w", err)
+	}
+
+	return nil
+}
+
 func (c clientImpl) ListContainers(
 	ctx context.Context,
 	opts dockertypes.ContainerListOptions,
@@ -88,3 +122,74 @@ func (c clientImpl) ContainerExists(ctx context.Context, opts ContainerFilterOpt
 	}
 	return true, nil
 }
+
+func (c clientImpl) InspectContainer(ctx context.Context, id string) (*dockertypes.ContainerJSON, error) {
+	result, err := c.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		if docker.IsErrNotFound(err) {
+			return nil, failure.NotFoundf("no container found with id %s", id)
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c clientImpl) PullImage(ctx context.Context, ref string) error {
+	reader, err := c.cli.ImagePull(ctx, ref, dockertypes.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer reader.Close()
+
+	// The pull only completes once the progress stream has been fully consumed.
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+func (c clientImpl) ImageExists(ctx context.Context, ref string) (bool, error) {
+	if _, _, err := c.cli.ImageInspectWithRaw(ctx, ref); err != nil {
+		if docker.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	return true, nil
+}
+
+// Options for creating a new container.
+type ContainerCreateOptions struct {
+	// Configuration of the container that is independent of the host it runs on.
+	Config *dockercontainer.Config
+	// Host-specific configuration such as the network mode.
+	HostConfig *dockercontainer.HostConfig
+}
+
+func (c clientImpl) CreateContainer(ctx context.Context, name string, opts ContainerCreateOptions) (string, error) {
+	result, err := c.cli.ContainerCreate(ctx, opts.Config, opts.HostConfig, nil, nil, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+
+	return result.ID, nil
+}
+
+func (c clientImpl) StartContainer(ctx context.Context, id string) error {
+	return c.cli.ContainerStart(ctx, id, dockertypes.ContainerStartOptions{})
+}
+
+func (c clientImpl) RemoveContainer(ctx context.Context, id string) error {
+	err := c.cli.ContainerRemove(ctx, id, dockertypes.ContainerRemoveOptions{Force: true})
+	if err != nil {
+		if docker.IsErrNotFound(err) {
+			return failure.NotFoundf("no container found with id %s", id)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"time"
+)
+
+const (
+	defaultMinEventBackoff = time.Second
+	defaultMaxEventBackoff = time.Minute
+)
+
+// Options for subscribing to the Docker events stream.
+type EventOptions struct {
+	// A list of filters consisting of a key and value pairs. This corresponds to the Docker API's filters parameter.
+	Filters filters.Args
+	// The delay before the first reconnection attempt after the stream fails. Defaults to one second.
+	MinBackoff time.Duration
+	// The upper bound of the delay between reconnection attempts. Defaults to one minute.
+	MaxBackoff time.Duration
+}
+
+func (c clientImpl) SubscribeEvents(ctx context.Context, opts EventOptions) <-chan events.Message {
+	minBackoff, maxBackoff := opts.MinBackoff, opts.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinEventBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = defaultMaxEventBackoff
+	}
+
+	result := make(chan events.Message)
+
+	go func() {
+		defer close(result)
+
+		backoff := minBackoff
+		// Resume from the last received event after reconnecting so that no events are missed.
+		since := ""
+
+		for {
+			messages, errs := c.cli.Events(ctx, dockertypes.EventsOptions{Since: since, Filters: opts.Filters})
+
+		stream:
+			for {
+				select {
+				case message := <-messages:
+					backoff = minBackoff
+					since = formatEventTimestamp(message.TimeNano + 1)
+
+					select {
+					case result <- message:
+					case <-ctx.Done():
+						return
+					}
+				case err := <-errs:
+					if ctx.Err() != nil {
+						return
+					}
+					c.logger.Warnf("Docker event stream failed, reconnecting in %s: %v", backoff, err)
+					break stream
+				}
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}()
+
+	return result
+}
+
+// Formats a Unix timestamp in nanoseconds the way the Docker API's since parameter expects it.
+func formatEventTimestamp(timeNano int64) string {
+	return fmt.Sprintf("%d.%09d", timeNano/int64(time.Second), timeNano%int64(time.Second))
+}
@@ -0,0 +1,171 @@
+package datasource
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// The size of the master key and of the per-value data keys, selecting AES-256.
+const encryptionKeySize = 32
+
+type (
+	// Encrypts small values using envelope encryption: every value is encrypted
+	// with a fresh data key, which is in turn encrypted with a master key that
+	// is kept apart from the encrypted values.
+	Encrypter interface {
+		Encrypt(plaintext []byte) (*EncryptedValue, error)
+		Decrypt(value *EncryptedValue) ([]byte, error)
+		// Returns a key for the given purpose, derived from the master key. It
+		// keys hashes of secret values, such as digests of credentials.
+		DeriveKey(purpose string) []byte
+	}
+	// A value encrypted by an Encrypter.
+	EncryptedValue struct {
+		// The data key, encrypted with the master key.
+		WrappedKey []byte `json:"wrapped_key" bson:"wrapped_key"`
+		// The value, encrypted with the data key.
+		Ciphertext []byte `json:"ciphertext" bson:"ciphertext"`
+	}
+	envelopeEncrypter struct {
+		masterKey    cipher.AEAD
+		rawMasterKey []byte
+	}
+)
+
+// Creates an Encrypter whose master key is stored in the file at the given path.
+// If the file doesn't exist, a new master key is generated and written to it.
+func ProvideEncrypter(keyPath string) (Encrypter, error) {
+	key, err := loadOrGenerateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &envelopeEncrypter{masterKey: masterKey, rawMasterKey: key}, nil
+}
+
+func (e envelopeEncrypter) Encrypt(plaintext []byte) (*EncryptedValue, error) {
+	dataKey := make([]byte, encryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	dataCipher, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(dataCipher, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := seal(e.masterKey, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedValue{WrappedKey: wrappedKey, Ciphertext: ciphertext}, nil
+}
+
+func (e envelopeEncrypter) Decrypt(value *EncryptedValue) ([]byte, error) {
+	dataKey, err := open(e.masterKey, value.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	dataCipher, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataCipher, value.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func (e envelopeEncrypter) DeriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, e.rawMasterKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func loadOrGenerateKey(keyPath string) ([]byte, error) {
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("encryption key %s has an invalid size of %d bytes", keyPath, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read encryption key %s: %w", keyPath, err)
+	}
+
+	key = make([]byte, encryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create encryption key directory: %w", err)
+	}
+
+	// O_EXCL guards against overwriting a key that was created concurrently.
+	file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption key %s: %w", keyPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(key); err != nil {
+		return nil, fmt.Errorf("failed to write encryption key %s: %w", keyPath, err)
+	}
+
+	return key, file.Sync()
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypts the plaintext and prefixes the result with the random nonce used.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypts a ciphertext produced by seal.
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,183 @@
+package datasource
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvelopeEncrypter_Decrypt(t *testing.T) {
+	otherEncrypter := newTestEncrypter(t, filepath.Join(t.TempDir(), "master.key"))
+
+	tests := []struct {
+		name string
+		// Changes the encrypted value before it is decrypted.
+		tamper  func(value *EncryptedValue)
+		decrypt func(encrypter Encrypter, value *EncryptedValue) ([]byte, error)
+		wantErr bool
+	}{
+		{name: "round trip"},
+		{
+			name:    "tampered ciphertext",
+			tamper:  func(value *EncryptedValue) { value.Ciphertext[len(value.Ciphertext)-1] ^= 1 },
+			wantErr: true,
+		},
+		{
+			name:    "tampered wrapped key",
+			tamper:  func(value *EncryptedValue) { value.WrappedKey[len(value.WrappedKey)-1] ^= 1 },
+			wantErr: true,
+		},
+		{
+			name:    "truncated ciphertext",
+			tamper:  func(value *EncryptedValue) { value.Ciphertext = value.Ciphertext[:4] },
+			wantErr: true,
+		},
+		{
+			name: "other master key",
+			decrypt: func(_ Encrypter, value *EncryptedValue) ([]byte, error) {
+				return otherEncrypter.Decrypt(value)
+			},
+			wantErr: true,
+		},
+	}
+
+	plaintext := []byte("api-secret")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypter := newTestEncrypter(t, filepath.Join(t.TempDir(), "master.key"))
+
+			value, err := encrypter.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if bytes.Contains(value.Ciphertext, plaintext) {
+				t.Fatal("Encrypt() ciphertext contains the plaintext")
+			}
+			if tt.tamper != nil {
+				tt.tamper(value)
+			}
+
+			decrypt := tt.decrypt
+			if decrypt == nil {
+				decrypt = func(encrypter Encrypter, value *EncryptedValue) ([]byte, error) {
+					return encrypter.Decrypt(value)
+				}
+			}
+			got, err := decrypt(encrypter, value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, plaintext) {
+				t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
+
+func TestEnvelopeEncrypter_EncryptUsesFreshKeys(t *testing.T) {
+	encrypter := newTestEncrypter(t, filepath.Join(t.TempDir(), "master.key"))
+
+	first, err := encrypter.Encrypt([]byte("api-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := encrypter.Encrypt([]byte("api-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(first.WrappedKey, second.WrappedKey) || bytes.Equal(first.Ciphertext, second.Ciphertext) {
+		t.Error("Encrypt() produced the same output twice for the same plaintext")
+	}
+}
+
+func TestProvideEncrypter(t *testing.T) {
+	tests := []struct {
+		name string
+		// Prepares the key file at the given path.
+		setup   func(t *testing.T, keyPath string)
+		wantErr bool
+	}{
+		{name: "generates a missing key"},
+		{
+			name: "rejects a key of the wrong size",
+			setup: func(t *testing.T, keyPath string) {
+				if err := os.WriteFile(keyPath, []byte("short"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "fails if the key can't be read",
+			setup: func(t *testing.T, keyPath string) {
+				if err := os.Mkdir(keyPath, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyPath := filepath.Join(t.TempDir(), "keys", "master.key")
+			if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, keyPath)
+			}
+
+			encrypter, err := ProvideEncrypter(keyPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProvideEncrypter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			info, err := os.Stat(keyPath)
+			if err != nil {
+				t.Fatalf("key file not written: %v", err)
+			}
+			if info.Size() != encryptionKeySize || info.Mode().Perm() != 0o600 {
+				t.Errorf("key file size = %d, mode = %v, want %d bytes with mode 0600", info.Size(), info.Mode().Perm(), encryptionKeySize)
+			}
+
+			value, err := encrypter.Encrypt([]byte("api-secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The generated key is reused when the encrypter is reopened.
+			reopened := newTestEncrypter(t, keyPath)
+			if _, err := reopened.Decrypt(value); err != nil {
+				t.Errorf("Decrypt() after reopening error = %v", err)
+			}
+			if !bytes.Equal(reopened.DeriveKey("credentials"), encrypter.DeriveKey("credentials")) {
+				t.Error("DeriveKey() changed after reopening")
+			}
+		})
+	}
+}
+
+func TestEnvelopeEncrypter_DeriveKey(t *testing.T) {
+	encrypter := newTestEncrypter(t, filepath.Join(t.TempDir(), "master.key"))
+	otherEncrypter := newTestEncrypter(t, filepath.Join(t.TempDir(), "master.key"))
+
+	if bytes.Equal(encrypter.DeriveKey("credentials"), encrypter.DeriveKey("cache")) {
+		t.Error("DeriveKey() returned the same key for different purposes")
+	}
+	if bytes.Equal(encrypter.DeriveKey("credentials"), otherEncrypter.DeriveKey("credentials")) {
+		t.Error("DeriveKey() returned the same key for different master keys")
+	}
+}
+
+func newTestEncrypter(t *testing.T, keyPath string) Encrypter {
+	encrypter, err := ProvideEncrypter(keyPath)
+	if err != nil {
+		t.Fatalf("ProvideEncrypter() error = %v", err)
+	}
+	return encrypter
+}
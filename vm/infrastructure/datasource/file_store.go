@@ -0,0 +1,205 @@
+package datasource
+
+import (
+	"akita/domain/failure"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// A Store that keeps all documents in memory and persists them as a single
+// JSON file, which is atomically replaced on every write.
+type fileStore struct {
+	path string
+	mu   sync.Mutex
+	// Documents encoded as JSON, keyed by collection and then by key.
+	data map[string]map[string]json.RawMessage
+}
+
+// Creates a store backed by the JSON file at the given path.
+// The file and its parent directories are created on the first write if they don't exist.
+func ProvideFileStore(path string) (Store, error) {
+	store := &fileStore{path: path, data: map[string]map[string]json.RawMessage{}}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read store file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(raw, &store.data); err != nil {
+		return nil, fmt.Errorf("failed to parse store file %s: %w", path, err)
+	}
+
+	return store, nil
+}
+
+func (f *fileStore) Get(_ context.Context, collection, key string, target any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	raw, ok := f.data[collection][key]
+	if !ok {
+		return failure.NotFoundf("no document %s found in collection %s", key, collection)
+	}
+
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("failed to decode document %s from collection %s: %w", key, collection, err)
+	}
+
+	return nil
+}
+
+func (f *fileStore) List(_ context.Context, collection string, target any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	documents := f.data[collection]
+
+	keys := make([]string, 0, len(documents))
+	for key := range documents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rawDocuments := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		rawDocuments = append(rawDocuments, documents[key])
+	}
+
+	rawList := append(append([]byte("["), bytes.Join(rawDocuments, []byte(","))...), ']')
+	if err := json.Unmarshal(rawList, target); err != nil {
+		return fmt.Errorf("failed to decode documents from collection %s: %w", collection, err)
+	}
+
+	return nil
+}
+
+func (f *fileStore) Put(_ context.Context, collection, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode document %s for collection %s: %w", key, collection, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.data[collection] == nil {
+		f.data[collection] = map[string]json.RawMessage{}
+	}
+
+	previous, existed := f.data[collection][key]
+	f.data[collection][key] = raw
+
+	if err := f.persist(); err != nil {
+		// Keep the in-memory state consistent with the file.
+		if existed {
+			f.data[collection][key] = previous
+		} else {
+			delete(f.data[collection], key)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (f *fileStore) Delete(_ context.Context, collection, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, existed := f.data[collection][key]
+	if !existed {
+		return nil
+	}
+
+	delete(f.data[collection], key)
+
+	if err := f.persist(); err != nil {
+		f.data[collection][key] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Checks that the store file can be written, without creating its directory.
+// Until the first write, the directory may not exist yet, in which case its
+// closest existing ancestor must be writable.
+func (f *fileStore) Ping(context.Context) error {
+	dir := filepath.Dir(f.path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("store directory %s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) || filepath.Dir(dir) == dir {
+			return fmt.Errorf("failed to access store directory: %w", err)
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	probe, err := os.CreateTemp(dir, ".ping.*.tmp")
+	if err != nil {
+		return fmt.Errorf("store directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	_ = os.Remove(probe.Name())
+
+	return nil
+}
+
+// Every change is persisted as it is made, so there is nothing to release.
+func (f *fileStore) Close(context.Context) error {
+	return nil
+}
+
+// Writes all documents to the store file. The file is replaced atomically so
+// that a crash never leaves a partially written file behind.
+// Must be called with the lock held.
+func (f *fileStore) persist() error {
+	raw, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(raw); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to sync store file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close store file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package datasource
+
+import (
+	"akita/domain/failure"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testDocument struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name string
+		// Operations applied to the store before it is reopened from its file.
+		apply    func(ctx context.Context, store Store) error
+		wantList []testDocument
+	}{
+		{
+			name:     "empty collection",
+			apply:    func(context.Context, Store) error { return nil },
+			wantList: []testDocument{},
+		},
+		{
+			name: "documents listed by key",
+			apply: func(ctx context.Context, store Store) error {
+				if err := store.Put(ctx, "things", "b", testDocument{Name: "b", Count: 2}); err != nil {
+					return err
+				}
+				return store.Put(ctx, "things", "a", testDocument{Name: "a", Count: 1})
+			},
+			wantList: []testDocument{{Name: "a", Count: 1}, {Name: "b", Count: 2}},
+		},
+		{
+			name: "document replaced",
+			apply: func(ctx context.Context, store Store) error {
+				if err := store.Put(ctx, "things", "a", testDocument{Name: "a", Count: 1}); err != nil {
+					return err
+				}
+				return store.Put(ctx, "things", "a", testDocument{Name: "a", Count: 5})
+			},
+			wantList: []testDocument{{Name: "a", Count: 5}},
+		},
+		{
+			name: "document deleted",
+			apply: func(ctx context.Context, store Store) error {
+				if err := store.Put(ctx, "things", "a", testDocument{Name: "a"}); err != nil {
+					return err
+				}
+				if err := store.Delete(ctx, "things", "a"); err != nil {
+					return err
+				}
+				// Deleting a missing document does nothing.
+				return store.Delete(ctx, "things", "missing")
+			},
+			wantList: []testDocument{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "nested", "store.json")
+
+			store, err := ProvideFileStore(path)
+			if err != nil {
+				t.Fatalf("ProvideFileStore() error = %v", err)
+			}
+			if err := tt.apply(ctx, store); err != nil {
+				t.Fatalf("apply error = %v", err)
+			}
+
+			// Everything written must survive reopening the store.
+			reopened, err := ProvideFileStore(path)
+			if err != nil {
+				t.Fatalf("ProvideFileStore() on reopen error = %v", err)
+			}
+
+			var got []testDocument
+			if err := reopened.List(ctx, "things", &got); err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantList) {
+				t.Errorf("List() = %v, want %v", got, tt.wantList)
+			}
+		})
+	}
+}
+
+func TestFileStore_GetMissing(t *testing.T) {
+	store, err := ProvideFileStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("ProvideFileStore() error = %v", err)
+	}
+
+	var document testDocument
+	if err := store.Get(context.Background(), "things", "missing", &document); !errors.Is(err, failure.ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, failure.ErrNotFound)
+	}
+}
+
+func TestFileStore_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ProvideFileStore(path); err == nil {
+		t.Error("ProvideFileStore() error = nil, want an error for a corrupt file")
+	}
+}
+
+func TestFileStore_Ping(t *testing.T) {
+	tests := []struct {
+		name string
+		// The path of the store file, relative to an empty directory.
+		path string
+		// Changes the files under the directory once the store is open.
+		prepare func(t *testing.T, root string)
+		wantErr bool
+	}{
+		{name: "existing directory", path: "store.json"},
+		{name: "directory created on the first write", path: "data/akita/store.json"},
+		{
+			name: "directory is a file",
+			path: "data/store.json",
+			prepare: func(t *testing.T, root string) {
+				if err := os.WriteFile(filepath.Join(root, "data"), nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "read-only directory",
+			path: "data/store.json",
+			prepare: func(t *testing.T, root string) {
+				if os.Geteuid() == 0 {
+					t.Skip("root can write to read-only directories")
+				}
+				if err := os.Mkdir(filepath.Join(root, "data"), 0o500); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			store, err := ProvideFileStore(filepath.Join(root, tt.path))
+			if err != nil {
+				t.Fatalf("ProvideFileStore() error = %v", err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(t, root)
+			}
+			before := listFiles(t, root)
+
+			err = store.Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if after := listFiles(t, root); !reflect.DeepEqual(after, before) {
+				t.Errorf("Ping() changed the files to %v, want %v", after, before)
+			}
+		})
+	}
+}
+
+// Returns the paths of all files and directories under the given root.
+func listFiles(t *testing.T, root string) []string {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, _ os.DirEntry, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
@@ -0,0 +1,65 @@
+package datasource
+
+import (
+	"akita/domain/health"
+	"akita/infrastructure/datasource/docker"
+	"context"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+)
+
+// A health.Checker that runs the given check.
+type checker struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+func (c checker) Name() string {
+	return c.name
+}
+
+func (c checker) Required() bool {
+	return c.required
+}
+
+func (c checker) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// Checks that the store holding the agent configs can be reached.
+func NewStoreChecker(store Store) health.Checker {
+	return checker{name: "store", required: true, check: store.Ping}
+}
+
+// Checks that the docker daemon running the agent containers can be reached.
+func NewDockerChecker(dockerClient docker.Client) health.Checker {
+	return checker{name: "docker", required: true, check: dockerClient.Ping}
+}
+
+// Checks that the demo server can be reached. Only demo traffic relies on it.
+func NewDemoServerChecker(demoServer DemoServer) health.Checker {
+	return checker{name: "demo-server", required: false, check: demoServer.Ping}
+}
+
+// Checks that the Akita API at the URL returned by baseURL can be reached.
+// Any response other than a server error counts, as no credentials are sent.
+// Only credential checks and analytics rely on it, as running agents talk to
+// the API directly.
+func NewAkitaAPIChecker(client *resty.Client, baseURL func() string) health.Checker {
+	return checker{
+		name:     "akita-api",
+		required: false,
+		check: func(ctx context.Context) error {
+			response, err := client.R().SetContext(ctx).Get(baseURL())
+			if err != nil {
+				return fmt.Errorf("failed to reach Akita API: %w", err)
+			}
+			if response.StatusCode() >= 500 {
+				return fmt.Errorf("Akita API responded with status %d", response.StatusCode())
+			}
+
+			return nil
+		},
+	}
+}
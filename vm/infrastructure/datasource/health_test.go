@@ -0,0 +1,44 @@
+package datasource
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAkitaAPIChecker_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		down    bool
+		wantErr bool
+	}{
+		{name: "ok", status: 200},
+		{name: "unauthorized", status: 401},
+		{name: "not found", status: 404},
+		{name: "server error", status: 503, wantErr: true},
+		{name: "unreachable", down: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			if tt.down {
+				server.Close()
+			}
+
+			checker := NewAkitaAPIChecker(resty.New(), func() string { return server.URL })
+			if checker.Required() {
+				t.Error("Required() = true, want the Akita API to be optional")
+			}
+			if err := checker.Check(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
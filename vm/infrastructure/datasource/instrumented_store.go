@@ -0,0 +1,64 @@
+package datasource
+
+import (
+	"akita/domain/failure"
+	"akita/metrics"
+	"context"
+	"errors"
+	"time"
+)
+
+// A Store that records the duration and outcome of the operations of another store.
+type instrumentedStore struct {
+	store Store
+}
+
+func NewInstrumentedStore(store Store) Store {
+	return &instrumentedStore{store: store}
+}
+
+func (i instrumentedStore) Get(ctx context.Context, collection, key string, target any) error {
+	start := time.Now()
+	err := i.store.Get(ctx, collection, key, target)
+	observeStoreOperation("get", collection, start, err)
+	return err
+}
+
+func (i instrumentedStore) List(ctx context.Context, collection string, target any) error {
+	start := time.Now()
+	err := i.store.List(ctx, collection, target)
+	observeStoreOperation("list", collection, start, err)
+	return err
+}
+
+func (i instrumentedStore) Put(ctx context.Context, collection, key string, value any) error {
+	start := time.Now()
+	err := i.store.Put(ctx, collection, key, value)
+	observeStoreOperation("put", collection, start, err)
+	return err
+}
+
+func (i instrumentedStore) Delete(ctx context.Context, collection, key string) error {
+	start := time.Now()
+	err := i.store.Delete(ctx, collection, key)
+	observeStoreOperation("delete", collection, start, err)
+	return err
+}
+
+func (i instrumentedStore) Ping(ctx context.Context) error {
+	return i.store.Ping(ctx)
+}
+
+func (i instrumentedStore) Close(ctx context.Context) error {
+	return i.store.Close(ctx)
+}
+
+func observeStoreOperation(operation string, collection string, start time.Time, err error) {
+	// Documents that don't exist are an expected answer rather than a failure of the store.
+	outcome := metrics.Outcome(err)
+	if errors.Is(err, failure.ErrNotFound) {
+		outcome = metrics.OutcomeSuccess
+	}
+
+	metrics.StoreOperationDuration.WithLabelValues(operation, collection, outcome).Observe(time.Since(start).Seconds())
+}
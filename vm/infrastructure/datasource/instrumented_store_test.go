@@ -0,0 +1,78 @@
+package datasource
+
+import (
+	"akita/domain/failure"
+	"akita/metrics"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"testing"
+)
+
+// A store failing every operation with err. Methods that the tests don't use
+// panic through the embedded nil interface.
+type failingStore struct {
+	Store
+	err error
+}
+
+func (f failingStore) Get(context.Context, string, string, any) error {
+	return f.err
+}
+
+func (f failingStore) Put(context.Context, string, string, any) error {
+	return f.err
+}
+
+func TestInstrumentedStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   string
+		err         error
+		wantOutcome string
+	}{
+		{name: "successful get", operation: "get", wantOutcome: metrics.OutcomeSuccess},
+		{
+			name:        "missing document",
+			operation:   "get",
+			err:         failure.NotFoundf("no document found"),
+			wantOutcome: metrics.OutcomeSuccess,
+		},
+		{name: "failed get", operation: "get", err: errors.New("disk full"), wantOutcome: metrics.OutcomeError},
+		{name: "failed put", operation: "put", err: errors.New("disk full"), wantOutcome: metrics.OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInstrumentedStore(failingStore{err: tt.err})
+			durations := metrics.StoreOperationDuration.WithLabelValues(tt.operation, "things", tt.wantOutcome)
+			before := sampleCount(t, durations)
+
+			var err error
+			switch tt.operation {
+			case "get":
+				var document testDocument
+				err = store.Get(context.Background(), "things", "key", &document)
+			case "put":
+				err = store.Put(context.Background(), "things", "key", testDocument{})
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s error = %v, want %v", tt.operation, err, tt.err)
+			}
+			if got := sampleCount(t, durations) - before; got != 1 {
+				t.Errorf("operations observed with outcome %s = %d, want 1", tt.wantOutcome, got)
+			}
+		})
+	}
+}
+
+// Returns the number of observations of the given histogram.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	if err := observer.(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
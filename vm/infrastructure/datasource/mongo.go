@@ -1,21 +1,105 @@
 package datasource
 
 import (
+	"akita/domain/failure"
 	"context"
 	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 )
 
-func ProvideMongoDB(ctx context.Context) (*mongo.Database, error) {
+func ProvideMongoDB(ctx context.Context, uri, database string) (*mongo.Database, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://akita-db:27017"))
+	// Fail fast if the database is unreachable instead of blocking every query.
+	opts := options.Client().ApplyURI(uri).SetServerSelectionTimeout(5 * time.Second)
+
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to mongo: %w", err)
 	}
 
-	return client.Database("akitaExtension"), nil
+	return client.Database(database), nil
+}
+
+// A Store that keeps each document in a Mongo collection, using the document key as its _id.
+type mongoStore struct {
+	db *mongo.Database
+}
+
+func NewMongoStore(db *mongo.Database) Store {
+	return &mongoStore{db: db}
+}
+
+func (m mongoStore) Get(ctx context.Context, collection, key string, target any) error {
+	err := m.db.Collection(collection).FindOne(ctx, bson.M{"_id": key}).Decode(target)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return failure.NotFoundf("no document %s found in collection %s", key, collection)
+		}
+		return fmt.Errorf("failed to retrieve document %s from collection %s: %w", key, collection, err)
+	}
+
+	return nil
+}
+
+func (m mongoStore) List(ctx context.Context, collection string, target any) error {
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := m.db.Collection(collection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to list documents from collection %s: %w", collection, err)
+	}
+
+	if err := cursor.All(ctx, target); err != nil {
+		return fmt.Errorf("failed to decode documents from collection %s: %w", collection, err)
+	}
+
+	return nil
+}
+
+func (m mongoStore) Put(ctx context.Context, collection, key string, value any) error {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode document %s for collection %s: %w", key, collection, err)
+	}
+
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return fmt.Errorf("failed to encode document %s for collection %s: %w", key, collection, err)
+	}
+	document = append(bson.D{{Key: "_id", Value: key}}, document...)
+
+	opts := options.Replace().SetUpsert(true)
+
+	_, err = m.db.Collection(collection).ReplaceOne(ctx, bson.M{"_id": key}, document, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save document %s into collection %s: %w", key, collection, err)
+	}
+
+	return nil
+}
+
+func (m mongoStore) Delete(ctx context.Context, collection, key string) error {
+	_, err := m.db.Collection(collection).DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("failed to remove document %s from collection %s: %w", key, collection, err)
+	}
+
+	return nil
+}
+
+func (m mongoStore) Ping(ctx context.Context) error {
+	if err := m.db.Client().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("failed to reach mongo: %w", err)
+	}
+
+	return nil
+}
+
+func (m mongoStore) Close(ctx context.Context) error {
+	return m.db.Client().Disconnect(ctx)
 }
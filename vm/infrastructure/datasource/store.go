@@ -0,0 +1,21 @@
+package datasource
+
+import "context"
+
+// A minimal document store that holds documents under unique keys in named collections.
+// It abstracts the storage backend away from the repositories.
+type Store interface {
+	// Decodes the document with the given key into target.
+	// If no document is found, a failure.ErrNotFound error is returned.
+	Get(ctx context.Context, collection, key string, target any) error
+	// Decodes all documents of the collection, ordered by key, into target, which must be a pointer to a slice.
+	List(ctx context.Context, collection string, target any) error
+	// Saves the given value under the given key, replacing any existing document.
+	Put(ctx context.Context, collection, key string, value any) error
+	// Removes the document with the given key. Does nothing if no document is found.
+	Delete(ctx context.Context, collection, key string) error
+	// Returns an error if the storage backend can't be reached.
+	Ping(ctx context.Context) error
+	// Releases the resources of the store. The store must not be used afterwards.
+	Close(ctx context.Context) error
+}
@@ -2,37 +2,286 @@ package repo
 
 import (
 	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/user"
+	"akita/infrastructure/datasource"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const (
+	// The collection holding agent configs, keyed by config ID.
+	agentConfigCollection = "configs"
+	// The collection holding the revisions of agent configs, keyed by config ID and version.
+	agentRevisionCollection = "config_revisions"
+	// The number of revisions that are kept per config. Older revisions are removed.
+	maxStoredRevisions = 50
 )
 
 type AgentRepository struct {
-	db *mongo.Database
+	store     datasource.Store
+	encrypter datasource.Encrypter
+}
+
+// The representation of an agent config in the store. The credentials are
+// only stored encrypted; the plaintext credential fields are left empty.
+type storedAgentConfig struct {
+	agent.Config `bson:",inline"`
+	// The encrypted user.Credentials of the config.
+	EncryptedCredentials *datasource.EncryptedValue `json:"encrypted_credentials,omitempty" bson:"encrypted_credentials,omitempty"`
+}
+
+// The representation of an agent config revision in the store.
+type storedRevision struct {
+	ConfigID        string              `json:"config_id" bson:"config_id"`
+	Version         int                 `json:"version" bson:"version"`
+	Timestamp       time.Time           `json:"timestamp" bson:"timestamp"`
+	Source          agent.ChangeSource  `json:"source" bson:"source"`
+	RestoredVersion *int                `json:"restored_version,omitempty" bson:"restored_version,omitempty"`
+	Changes         []agent.FieldChange `json:"changes" bson:"changes"`
+	Config          *storedAgentConfig  `json:"config" bson:"config"`
+}
+
+func NewAgentRepository(store datasource.Store, encrypter datasource.Encrypter) agent.Repository {
+	return &AgentRepository{store: store, encrypter: encrypter}
+}
+
+func (a AgentRepository) ListConfigs(ctx context.Context) ([]*agent.Config, error) {
+	var storedConfigs []*storedAgentConfig
+	if err := a.store.List(ctx, agentConfigCollection, &storedConfigs); err != nil {
+		return nil, err
+	}
+
+	result := make([]*agent.Config, 0, len(storedConfigs))
+	for _, storedConfig := range storedConfigs {
+		agentConfig, err := a.fromStored(ctx, storedConfig)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, agentConfig)
+	}
+
+	return result, nil
+}
+
+func (a AgentRepository) GetConfig(ctx context.Context, id string) (*agent.Config, error) {
+	var storedConfig storedAgentConfig
+	if err := a.store.Get(ctx, agentConfigCollection, id, &storedConfig); err != nil {
+		return nil, err
+	}
+
+	return a.fromStored(ctx, &storedConfig)
+}
+
+func (a AgentRepository) SaveConfig(ctx context.Context, agentConfig *agent.Config, options agent.SaveOptions) error {
+	previousConfig, err := a.GetConfig(ctx, agentConfig.ID)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return err
+	}
+
+	if err := a.putConfig(ctx, agentConfig); err != nil {
+		return err
+	}
+
+	changes := agent.DiffConfigs(previousConfig, agentConfig)
+	if previousConfig != nil && len(changes) == 0 {
+		return nil
+	}
+
+	return a.appendRevision(ctx, agentConfig, changes, options)
+}
+
+func (a AgentRepository) DeleteConfig(ctx context.Context, id string) error {
+	return a.store.Delete(ctx, agentConfigCollection, id)
+}
+
+func (a AgentRepository) ListRevisions(ctx context.Context, id string) ([]*agent.Revision, error) {
+	storedRevisions, err := a.listStoredRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*agent.Revision, 0, len(storedRevisions))
+	for i := len(storedRevisions) - 1; i >= 0; i-- {
+		revision, err := a.revisionFromStored(storedRevisions[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, revision)
+	}
+
+	return result, nil
+}
+
+func (a AgentRepository) GetRevision(ctx context.Context, id string, version int) (*agent.Revision, error) {
+	var storedRevision storedRevision
+	if err := a.store.Get(ctx, agentRevisionCollection, revisionKey(id, version), &storedRevision); err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.NotFoundf("revision %d of agent config %s not found", version, id).
+				WithCode(failure.CodeRevisionNotFound)
+		}
+		return nil, err
+	}
+
+	return a.revisionFromStored(&storedRevision)
+}
+
+// Stores the agent config without recording a revision.
+func (a AgentRepository) putConfig(ctx context.Context, agentConfig *agent.Config) error {
+	storedConfig, err := a.toStored(agentConfig)
+	if err != nil {
+		return err
+	}
+
+	return a.store.Put(ctx, agentConfigCollection, agentConfig.ID, storedConfig)
 }
 
-func NewAgentRepository(db *mongo.Database) agent.Repository {
-	return &AgentRepository{db: db}
+// Appends a revision holding the given config to its history and removes the
+// oldest revisions beyond maxStoredRevisions.
+func (a AgentRepository) appendRevision(
+	ctx context.Context,
+	agentConfig *agent.Config,
+	changes []agent.FieldChange,
+	options agent.SaveOptions,
+) error {
+	storedRevisions, err := a.listStoredRevisions(ctx, agentConfig.ID)
+	if err != nil {
+		return err
+	}
+
+	version := 1
+	if len(storedRevisions) > 0 {
+		version = storedRevisions[len(storedRevisions)-1].Version + 1
+	}
+
+	storedConfig, err := a.toStored(agentConfig)
+	if err != nil {
+		return err
+	}
+
+	revision := &storedRevision{
+		ConfigID:  agentConfig.ID,
+		Version:   version,
+		Timestamp: time.Now().UTC(),
+		Source:    options.Source,
+		Changes:   changes,
+		Config:    storedConfig,
+	}
+	if restoredVersion, ok := options.RestoredVersion.Get(); ok {
+		revision.RestoredVersion = &restoredVersion
+	}
+
+	if err := a.store.Put(ctx, agentRevisionCollection, revisionKey(agentConfig.ID, version), revision); err != nil {
+		return fmt.Errorf("failed to record revision of agent config %s: %w", agentConfig.ID, err)
+	}
+
+	for i := 0; i < len(storedRevisions)+1-maxStoredRevisions; i++ {
+		key := revisionKey(agentConfig.ID, storedRevisions[i].Version)
+		if err := a.store.Delete(ctx, agentRevisionCollection, key); err != nil {
+			return fmt.Errorf("failed to prune revisions of agent config %s: %w", agentConfig.ID, err)
+		}
+	}
+
+	return nil
 }
 
-func (a AgentRepository) GetConfig(ctx context.Context) (*agent.Config, error) {
-	return getFirstDocument[agent.Config](ctx, a.configCollection())
+// Returns the stored revisions of the agent config with the given ID, oldest first.
+func (a AgentRepository) listStoredRevisions(ctx context.Context, id string) ([]*storedRevision, error) {
+	var storedRevisions []*storedRevision
+	if err := a.store.List(ctx, agentRevisionCollection, &storedRevisions); err != nil {
+		return nil, fmt.Errorf("failed to list revisions of agent config %s: %w", id, err)
+	}
+
+	result := []*storedRevision{}
+	for _, storedRevision := range storedRevisions {
+		if storedRevision.ConfigID == id {
+			result = append(result, storedRevision)
+		}
+	}
+
+	return result, nil
 }
 
-func (a AgentRepository) SaveConfig(ctx context.Context, agentConfig *agent.Config) error {
-	return upsertFirstDocument(ctx, a.configCollection(), agentConfig)
+// Returns the representation of the agent config in the store, with its credentials encrypted.
+func (a AgentRepository) toStored(agentConfig *agent.Config) (*storedAgentConfig, error) {
+	rawCredentials, err := json.Marshal(agentConfig.Credentials())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credentials of agent config %s: %w", agentConfig.ID, err)
+	}
+
+	encryptedCredentials, err := a.encrypter.Encrypt(rawCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials of agent config %s: %w", agentConfig.ID, err)
+	}
+
+	storedConfig := &storedAgentConfig{Config: *agentConfig, EncryptedCredentials: encryptedCredentials}
+	storedConfig.APIKey = ""
+	storedConfig.APISecret = ""
+
+	return storedConfig, nil
 }
 
-func (a AgentRepository) DeleteConfig(ctx context.Context) error {
-	_, err := a.configCollection().DeleteMany(ctx, bson.M{})
+// Returns the agent config represented by the stored config, with its credentials decrypted.
+func (a AgentRepository) fromStored(ctx context.Context, storedConfig *storedAgentConfig) (*agent.Config, error) {
+	agentConfig := storedConfig.Config
+
+	if storedConfig.EncryptedCredentials == nil {
+		// Configs saved before credentials were encrypted hold them in
+		// plaintext. Storing them again encrypts them.
+		if err := a.putConfig(ctx, &agentConfig); err != nil {
+			return nil, err
+		}
+		return &agentConfig, nil
+	}
+
+	if err := a.decryptCredentials(storedConfig, &agentConfig); err != nil {
+		return nil, err
+	}
+
+	return &agentConfig, nil
+}
+
+// Returns the revision represented by the stored revision, with the credentials of its config decrypted.
+func (a AgentRepository) revisionFromStored(storedRevision *storedRevision) (*agent.Revision, error) {
+	agentConfig := storedRevision.Config.Config
+	if err := a.decryptCredentials(storedRevision.Config, &agentConfig); err != nil {
+		return nil, err
+	}
+
+	return &agent.Revision{
+		ConfigID:        storedRevision.ConfigID,
+		Version:         storedRevision.Version,
+		Timestamp:       storedRevision.Timestamp,
+		Source:          storedRevision.Source,
+		RestoredVersion: storedRevision.RestoredVersion,
+		Changes:         storedRevision.Changes,
+		Config:          &agentConfig,
+	}, nil
+}
+
+// Decrypts the credentials of the stored config into the given agent config.
+func (a AgentRepository) decryptCredentials(storedConfig *storedAgentConfig, agentConfig *agent.Config) error {
+	rawCredentials, err := a.encrypter.Decrypt(storedConfig.EncryptedCredentials)
 	if err != nil {
-		return fmt.Errorf("failed to remove agent config: %w", err)
+		return fmt.Errorf("failed to decrypt credentials of agent config %s: %w", agentConfig.ID, err)
+	}
+
+	var credentials user.Credentials
+	if err := json.Unmarshal(rawCredentials, &credentials); err != nil {
+		return fmt.Errorf("failed to decode credentials of agent config %s: %w", agentConfig.ID, err)
 	}
+
+	agentConfig.APIKey = credentials.APIKey
+	agentConfig.APISecret = credentials.APISecret
+
 	return nil
 }
 
-// Returns the collection of agent configs.
-func (a AgentRepository) configCollection() *mongo.Collection {
-	return a.db.Collection("configs")
+// Returns the key of the given revision of an agent config. The keys of the
+// revisions of a config sort by version.
+func revisionKey(id string, version int) string {
+	return fmt.Sprintf("%s/%010d", id, version)
 }
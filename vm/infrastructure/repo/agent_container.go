@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"akita/domain/agent"
+	"akita/domain/container"
+	"akita/domain/failure"
+	"akita/infrastructure/datasource"
+	"akita/infrastructure/datasource/docker"
+	"context"
+	"errors"
+	"fmt"
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const (
+	// The name of the container running the Akita agent for the default config.
+	// The containers of other configs are suffixed with their config ID.
+	agentContainerName = "akita-docker-extension-agent"
+	// The image of the Akita CLI that the agent container runs.
+	agentImage = "akitasoftware/cli:latest"
+	// Label used to mark containers that are managed by the extension.
+	agentManagedLabel = "com.akitasoftware.docker-extension.agent"
+	// Label holding the digest of the agent config that the container was created from.
+	agentConfigDigestLabel = "com.akitasoftware.docker-extension.config-digest"
+	// Label holding the ID of the agent config that the container runs.
+	agentConfigIDLabel = "com.akitasoftware.docker-extension.config-id"
+)
+
+// The purpose of the key of the config digests that agent containers are labelled with.
+const agentConfigDigestKeyPurpose = "agent-config-digest"
+
+type AgentContainerRepository struct {
+	dockerClient docker.Client
+	digestKey    []byte
+	logger       *logrus.Logger
+}
+
+func NewAgentContainerRepository(
+	dockerClient docker.Client,
+	encrypter datasource.Encrypter,
+	logger *logrus.Logger,
+) agent.ContainerRepository {
+	return &AgentContainerRepository{
+		dockerClient: dockerClient,
+		digestKey:    encrypter.DeriveKey(agentConfigDigestKeyPurpose),
+		logger:       logger,
+	}
+}
+
+func (a AgentContainerRepository) Start(ctx context.Context, agentConfig *agent.Config) (*agent.Status, error) {
+	if err := a.pullImage(ctx); err != nil {
+		return nil, err
+	}
+
+	// Remove any leftover agent container so that the new one picks up the latest config.
+	if err := a.Stop(ctx, agentConfig.ID); err != nil {
+		return nil, err
+	}
+
+	id, err := a.dockerClient.CreateContainer(ctx, agentContainerNameFor(agentConfig.ID), docker.ContainerCreateOptions{
+		Config: &dockercontainer.Config{
+			Image: agentImage,
+			Cmd:   agentCommand(agentConfig),
+			Env: []string{
+				"AKITA_API_KEY_ID=" + agentConfig.APIKey,
+				"AKITA_API_KEY_SECRET=" + agentConfig.APISecret,
+			},
+			Labels: map[string]string{
+				agentManagedLabel:      "true",
+				agentConfigDigestLabel: a.Digest(agentConfig),
+				agentConfigIDLabel:     agentConfig.ID,
+			},
+		},
+		HostConfig: &dockercontainer.HostConfig{
+			NetworkMode: dockercontainer.NetworkMode(agentNetworkMode(agentConfig)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.dockerClient.StartContainer(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to start agent container: %w", err)
+	}
+
+	return a.GetStatus(ctx, agentConfig.ID)
+}
+
+func (a AgentContainerRepository) Stop(ctx context.Context, configID string) error {
+	err := a.dockerClient.RemoveContainer(ctx, agentContainerNameFor(configID))
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return fmt.Errorf("failed to remove agent container: %w", err)
+	}
+
+	return nil
+}
+
+func (a AgentContainerRepository) GetStatus(ctx context.Context, configID string) (*agent.Status, error) {
+	result, err := a.dockerClient.InspectContainer(ctx, agentContainerNameFor(configID))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &agent.Status{ConfigID: configID, ContainerID: result.ID}
+	if result.State != nil {
+		status.State = container.Status(result.State.Status)
+		if startedAt, err := time.Parse(time.RFC3339Nano, result.State.StartedAt); err == nil {
+			status.StartedAt = &startedAt
+		}
+	}
+	if result.Config != nil {
+		status.ConfigDigest = result.Config.Labels[agentConfigDigestLabel]
+	}
+	if result.HostConfig != nil {
+		status.NetworkMode = string(result.HostConfig.NetworkMode)
+	}
+
+	return status, nil
+}
+
+func (a AgentContainerRepository) ListStatuses(ctx context.Context) (map[string]*agent.Status, error) {
+	args := filters.NewArgs()
+	args.Add("label", agentManagedLabel)
+
+	containers, err := a.dockerClient.ListContainers(ctx, dockertypes.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]*agent.Status{}
+	for _, agentContainer := range containers {
+		configID, ok := agentContainer.Labels[agentConfigIDLabel]
+		if !ok {
+			// Containers started before multiple configs were supported run the default config.
+			configID = agent.DefaultConfigID
+		}
+
+		result[configID] = &agent.Status{
+			ConfigID:     configID,
+			ContainerID:  agentContainer.ID,
+			State:        container.Status(agentContainer.State),
+			NetworkMode:  agentContainer.HostConfig.NetworkMode,
+			ConfigDigest: agentContainer.Labels[agentConfigDigestLabel],
+		}
+	}
+
+	return result, nil
+}
+
+func (a AgentContainerRepository) Digest(agentConfig *agent.Config) string {
+	return agentConfig.Digest(a.digestKey)
+}
+
+// Pulls the latest agent image. If the pull fails, e.g. because the host is
+// offline, the image already present on the host is used instead.
+func (a AgentContainerRepository) pullImage(ctx context.Context) error {
+	pullErr := a.dockerClient.PullImage(ctx, agentImage)
+	if pullErr == nil {
+		return nil
+	}
+
+	exists, err := a.dockerClient.ImageExists(ctx, agentImage)
+	if err != nil || !exists {
+		return pullErr
+	}
+
+	a.logger.WithContext(ctx).WithError(pullErr).Warnf("using the local %s image", agentImage)
+	return nil
+}
+
+// Returns the name of the agent container running the config with the given ID.
+func agentContainerNameFor(configID string) string {
+	if configID == agent.DefaultConfigID {
+		return agentContainerName
+	}
+	return agentContainerName + "-" + configID
+}
+
+// Returns the network mode of the agent container. The agent shares the
+// network namespace of the target container if there is one, and the host's otherwise.
+func agentNetworkMode(agentConfig *agent.Config) string {
+	if agentConfig.TargetContainer != nil {
+		return "container:" + *agentConfig.TargetContainer
+	}
+	return "host"
+}
+
+// Returns the Akita CLI command that the agent container runs.
+func agentCommand(agentConfig *agent.Config) []string {
+	cmd := []string{"apidump", "--project", agentConfig.ProjectName}
+	if agentConfig.TargetPort != nil {
+		cmd = append(cmd, "--filter", "port "+strconv.Itoa(*agentConfig.TargetPort))
+	}
+	return cmd
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/infrastructure/datasource"
+	"akita/infrastructure/datasource/docker"
+	"context"
+	"errors"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/sirupsen/logrus"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// A docker client that records the containers it creates. Methods that the
+// tests don't use panic through the embedded nil interface.
+type fakeDockerClient struct {
+	docker.Client
+	pullErr        error
+	imageExists    bool
+	imageExistsErr error
+	created        []string
+}
+
+func (f *fakeDockerClient) PullImage(context.Context, string) error {
+	return f.pullErr
+}
+
+func (f *fakeDockerClient) ImageExists(context.Context, string) (bool, error) {
+	return f.imageExists, f.imageExistsErr
+}
+
+func (f *fakeDockerClient) RemoveContainer(_ context.Context, id string) error {
+	return failure.NotFoundf("no container found with id %s", id)
+}
+
+func (f *fakeDockerClient) CreateContainer(_ context.Context, name string, _ docker.ContainerCreateOptions) (string, error) {
+	f.created = append(f.created, name)
+	return name, nil
+}
+
+func (f *fakeDockerClient) StartContainer(context.Context, string) error {
+	return nil
+}
+
+func (f *fakeDockerClient) InspectContainer(_ context.Context, id string) (*dockertypes.ContainerJSON, error) {
+	return &dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			ID:    id,
+			State: &dockertypes.ContainerState{Status: "running"},
+		},
+	}, nil
+}
+
+func newTestEncrypter(t *testing.T) datasource.Encrypter {
+	encrypter, err := datasource.ProvideEncrypter(filepath.Join(t.TempDir(), "master.key"))
+	if err != nil {
+		t.Fatalf("ProvideEncrypter() error = %v", err)
+	}
+	return encrypter
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestAgentContainerRepository_Start(t *testing.T) {
+	pullErr := errors.New("failed to pull image: network is unreachable")
+
+	tests := []struct {
+		name           string
+		pullErr        error
+		imageExists    bool
+		imageExistsErr error
+		wantErr        error
+	}{
+		{name: "pulled image"},
+		{name: "pull failed with local image", pullErr: pullErr, imageExists: true},
+		{name: "pull failed without local image", pullErr: pullErr, wantErr: pullErr},
+		{
+			name:           "pull failed and image lookup failed",
+			pullErr:        pullErr,
+			imageExistsErr: errors.New("daemon unavailable"),
+			wantErr:        pullErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDockerClient{pullErr: tt.pullErr, imageExists: tt.imageExists, imageExistsErr: tt.imageExistsErr}
+			repository := NewAgentContainerRepository(client, newTestEncrypter(t), newTestLogger())
+
+			status, err := repository.Start(context.Background(), &agent.Config{ID: agent.DefaultConfigID, ProjectName: "project"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Start() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(client.created) != 0 {
+					t.Fatalf("Start() created containers %v, want none", client.created)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			if !status.IsRunning() {
+				t.Errorf("Start() state = %s, want running", status.State)
+			}
+			if len(client.created) != 1 || client.created[0] != agentContainerName {
+				t.Errorf("Start() created containers %v, want [%s]", client.created, agentContainerName)
+			}
+		})
+	}
+}
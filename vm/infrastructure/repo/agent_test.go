@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/akitasoftware/go-utils/optionals"
+	"testing"
+)
+
+func TestAgentRepository_Revisions(t *testing.T) {
+	tests := []struct {
+		name string
+		// The project names of the configs saved in turn.
+		saves []string
+		// The versions of the listed revisions, newest first.
+		wantVersions []int
+	}{
+		{name: "first save", saves: []string{"v1"}, wantVersions: []int{1}},
+		{name: "consecutive versions", saves: []string{"v1", "v2", "v3"}, wantVersions: []int{3, 2, 1}},
+		{name: "unchanged save", saves: []string{"v1", "v1", "v2"}, wantVersions: []int{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := NewAgentRepository(newMemoryStore(), newTestEncrypter(t))
+			ctx := context.Background()
+
+			for _, projectName := range tt.saves {
+				config := &agent.Config{ID: agent.DefaultConfigID, APIKey: "key", APISecret: "secret", ProjectName: projectName}
+				if err := repository.SaveConfig(ctx, config, agent.SaveOptions{Source: agent.ChangeSourceUI}); err != nil {
+					t.Fatalf("SaveConfig() error = %v", err)
+				}
+			}
+
+			revisions, err := repository.ListRevisions(ctx, agent.DefaultConfigID)
+			if err != nil {
+				t.Fatalf("ListRevisions() error = %v", err)
+			}
+			versions := make([]int, 0, len(revisions))
+			for _, revision := range revisions {
+				versions = append(versions, revision.Version)
+			}
+			if fmt.Sprint(versions) != fmt.Sprint(tt.wantVersions) {
+				t.Fatalf("ListRevisions() versions = %v, want %v", versions, tt.wantVersions)
+			}
+
+			latest := revisions[0]
+			if latest.Config.ProjectName != tt.saves[len(tt.saves)-1] || latest.Config.APISecret != "secret" {
+				t.Errorf("latest revision config = %+v, want the last saved config", latest.Config)
+			}
+			if latest.Source != agent.ChangeSourceUI {
+				t.Errorf("latest revision source = %s, want %s", latest.Source, agent.ChangeSourceUI)
+			}
+		})
+	}
+}
+
+func TestAgentRepository_RevisionsArePruned(t *testing.T) {
+	repository := NewAgentRepository(newMemoryStore(), newTestEncrypter(t))
+	ctx := context.Background()
+
+	saves := maxStoredRevisions + 2
+	for i := 1; i <= saves; i++ {
+		config := &agent.Config{ID: agent.DefaultConfigID, ProjectName: fmt.Sprint("v", i)}
+		if err := repository.SaveConfig(ctx, config, agent.SaveOptions{}); err != nil {
+			t.Fatalf("SaveConfig() error = %v", err)
+		}
+	}
+
+	revisions, err := repository.ListRevisions(ctx, agent.DefaultConfigID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != maxStoredRevisions {
+		t.Fatalf("ListRevisions() returned %d revisions, want %d", len(revisions), maxStoredRevisions)
+	}
+	if newest, oldest := revisions[0].Version, revisions[len(revisions)-1].Version; newest != saves || oldest != 3 {
+		t.Errorf("ListRevisions() versions range from %d to %d, want %d to 3", newest, oldest, saves)
+	}
+}
+
+func TestAgentRepository_GetRevision(t *testing.T) {
+	repository := NewAgentRepository(newMemoryStore(), newTestEncrypter(t))
+	ctx := context.Background()
+
+	for _, projectName := range []string{"v1", "v2"} {
+		config := &agent.Config{ID: agent.DefaultConfigID, ProjectName: projectName}
+		if err := repository.SaveConfig(ctx, config, agent.SaveOptions{}); err != nil {
+			t.Fatalf("SaveConfig() error = %v", err)
+		}
+	}
+	restored := &agent.Config{ID: agent.DefaultConfigID, ProjectName: "v1"}
+	err := repository.SaveConfig(ctx, restored, agent.SaveOptions{RestoredVersion: optionals.Some(1)})
+	if err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		version         int
+		wantProjectName string
+		// Zero if the revision is not a rollback.
+		wantRestoredVersion int
+		wantErr             error
+	}{
+		{name: "first revision", version: 1, wantProjectName: "v1"},
+		{name: "rollback", version: 3, wantProjectName: "v1", wantRestoredVersion: 1},
+		{name: "unknown revision", version: 4, wantErr: failure.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, err := repository.GetRevision(ctx, agent.DefaultConfigID, tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || failure.From(err).Code != failure.CodeRevisionNotFound {
+					t.Fatalf("GetRevision() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRevision() error = %v", err)
+			}
+
+			if revision.Config.ProjectName != tt.wantProjectName {
+				t.Errorf("GetRevision() project name = %s, want %s", revision.Config.ProjectName, tt.wantProjectName)
+			}
+			restoredVersion := 0
+			if revision.RestoredVersion != nil {
+				restoredVersion = *revision.RestoredVersion
+			}
+			if restoredVersion != tt.wantRestoredVersion {
+				t.Errorf("GetRevision() restored version = %d, want %d", restoredVersion, tt.wantRestoredVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"akita/domain/container"
+	"akita/domain/event"
+	"akita/domain/failure"
+	"akita/infrastructure/datasource"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+const (
+	// The collection holding queued analytics events, keyed by their ID.
+	outboxCollection = "analytics_outbox"
+	// The collection holding the outcome of the last delivery run.
+	outboxStatusCollection = "analytics_outbox_status"
+	// The key of the last delivery run.
+	lastDeliveryKey = "last-delivery"
+	// The number of events that are kept. The oldest events are dropped beyond it.
+	maxQueuedEvents = 5000
+	// How many events below the maximum the outbox is pruned to, so that it
+	// isn't pruned again on every following enqueue.
+	pruneSlack = 500
+)
+
+type OutboxRepository struct {
+	store datasource.Store
+	// Decides which events and properties are queued. Every event is queued
+	// through it, whichever part of the extension raised it.
+	policy        event.Policy
+	containerRepo container.Repository
+	logger        *logrus.Logger
+
+	mu *sync.Mutex
+	// The number of queued events, or -1 until they are first counted. Kept in
+	// memory so that the outbox is only listed when it may need pruning.
+	count *int
+}
+
+func NewOutboxRepository(
+	store datasource.Store,
+	policy event.Policy,
+	containerRepo container.Repository,
+	logger *logrus.Logger,
+) event.OutboxRepository {
+	count := -1
+	return &OutboxRepository{
+		store:         store,
+		policy:        policy,
+		containerRepo: containerRepo,
+		logger:        logger,
+		mu:            &sync.Mutex{},
+		count:         &count,
+	}
+}
+
+func (o OutboxRepository) Enqueue(ctx context.Context, queuedEvent *event.QueuedEvent) error {
+	properties, err := o.policy.Scrub(queuedEvent.Name, queuedEvent.Properties, o.containerNames(ctx, queuedEvent))
+	if err != nil {
+		return err
+	}
+
+	scrubbedEvent := *queuedEvent
+	scrubbedEvent.Properties = properties
+	scrubbedEvent.DistinctID = o.policy.Pseudonymize(queuedEvent.DistinctID)
+	queuedEvent = &scrubbedEvent
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if *o.count < 0 {
+		queuedEvents, err := o.List(ctx)
+		if err != nil {
+			return err
+		}
+		*o.count = len(queuedEvents)
+	}
+
+	if err := o.store.Put(ctx, outboxCollection, queuedEvent.ID, queuedEvent); err != nil {
+		return fmt.Errorf("failed to queue analytics event: %w", err)
+	}
+	*o.count++
+
+	if *o.count <= maxQueuedEvents {
+		return nil
+	}
+	return o.prune(ctx)
+}
+
+func (o OutboxRepository) List(ctx context.Context) ([]*event.QueuedEvent, error) {
+	result := []*event.QueuedEvent{}
+	if err := o.store.List(ctx, outboxCollection, &result); err != nil {
+		return nil, fmt.Errorf("failed to list queued analytics events: %w", err)
+	}
+
+	return result, nil
+}
+
+func (o OutboxRepository) Update(ctx context.Context, queuedEvent *event.QueuedEvent) error {
+	return o.store.Put(ctx, outboxCollection, queuedEvent.ID, queuedEvent)
+}
+
+func (o OutboxRepository) Remove(ctx context.Context, id string) error {
+	if err := o.store.Delete(ctx, outboxCollection, id); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Removing an event that isn't queued leaves the count too low; the next
+	// prune corrects it.
+	if *o.count > 0 {
+		*o.count--
+	}
+	return nil
+}
+
+func (o OutboxRepository) Purge(ctx context.Context) (int, error) {
+	queuedEvents, err := o.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, queuedEvent := range queuedEvents {
+		if err := o.Remove(ctx, queuedEvent.ID); err != nil {
+			return i, fmt.Errorf("failed to purge queued analytics events: %w", err)
+		}
+	}
+
+	return len(queuedEvents), nil
+}
+
+func (o OutboxRepository) RecordDelivery(ctx context.Context, report *event.DeliveryReport) error {
+	return o.store.Put(ctx, outboxStatusCollection, lastDeliveryKey, report)
+}
+
+func (o OutboxRepository) LastDelivery(ctx context.Context) (*event.DeliveryReport, error) {
+	var report event.DeliveryReport
+	if err := o.store.Get(ctx, outboxStatusCollection, lastDeliveryKey, &report); err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// Removes the oldest events, leaving pruneSlack fewer than maxQueuedEvents,
+// and recounts the queued events. Must be called with the lock held.
+func (o OutboxRepository) prune(ctx context.Context) error {
+	queuedEvents, err := o.List(ctx)
+	if err != nil {
+		return err
+	}
+	*o.count = len(queuedEvents)
+
+	for i := 0; i < len(queuedEvents)-(maxQueuedEvents-pruneSlack); i++ {
+		if err := o.store.Delete(ctx, outboxCollection, queuedEvents[i].ID); err != nil {
+			return fmt.Errorf("failed to prune queued analytics events: %w", err)
+		}
+		*o.count--
+	}
+
+	return nil
+}
+
+// Returns the names of the containers on the host, which are scrubbed from the
+// event properties. Containers are only listed if the event has properties.
+func (o OutboxRepository) containerNames(ctx context.Context, queuedEvent *event.QueuedEvent) []string {
+	if len(queuedEvent.Properties) == 0 {
+		return nil
+	}
+
+	containers, err := o.containerRepo.List(ctx, container.Filter{})
+	if err != nil {
+		// Emails and IP addresses are still scrubbed.
+		o.logger.WithContext(ctx).Warnf("Failed to list containers to scrub from analytics event: %s", err)
+		return nil
+	}
+
+	var names []string
+	for _, listedContainer := range containers {
+		names = append(names, listedContainer.Names...)
+	}
+
+	return names
+}
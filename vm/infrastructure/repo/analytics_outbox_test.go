@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"akita/domain/container"
+	"akita/domain/event"
+	"akita/domain/failure"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testPolicy = event.Policy{
+	AllowedProperties: map[string][]string{
+		"Viewed Agent Page":            {},
+		"Agent Automatically Disabled": {"reason"},
+	},
+	CommonProperties:    []string{"target-os"},
+	SensitiveProperties: []string{"container_name"},
+	PIIAction:           event.PIIActionHash,
+}
+
+// A container repository that lists the given containers.
+type fakeContainerRepo struct {
+	container.Repository
+	containers []*container.Container
+	listErr    error
+}
+
+func (f *fakeContainerRepo) List(context.Context, container.Filter) ([]*container.Container, error) {
+	return f.containers, f.listErr
+}
+
+func TestOutboxRepository_Enqueue(t *testing.T) {
+	tests := []struct {
+		name string
+		// Events already in the store when the repository is created.
+		existing  int
+		enqueued  int
+		wantLists int
+		wantCount int
+	}{
+		{name: "counts the outbox once", enqueued: 10, wantLists: 1, wantCount: 10},
+		{name: "counts existing events", existing: 5, enqueued: 5, wantLists: 1, wantCount: 10},
+		{
+			name:      "prunes the oldest events once full",
+			existing:  maxQueuedEvents,
+			enqueued:  1,
+			wantLists: 2,
+			wantCount: maxQueuedEvents - pruneSlack,
+		},
+		{
+			name:      "doesn't list again after pruning",
+			existing:  maxQueuedEvents,
+			enqueued:  pruneSlack + 1,
+			wantLists: 2,
+			wantCount: maxQueuedEvents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newMemoryStore()
+			for i := 0; i < tt.existing; i++ {
+				queuedEvent := &event.QueuedEvent{ID: fmt.Sprintf("0-%05d", i)}
+				if err := store.Put(ctx, outboxCollection, queuedEvent.ID, queuedEvent); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			repository := NewOutboxRepository(store, testPolicy, &fakeContainerRepo{}, newTestLogger())
+			for i := 0; i < tt.enqueued; i++ {
+				queuedEvent := &event.QueuedEvent{ID: fmt.Sprintf("1-%05d", i), Name: "Viewed Agent Page"}
+				if err := repository.Enqueue(ctx, queuedEvent); err != nil {
+					t.Fatalf("Enqueue() error = %v", err)
+				}
+			}
+
+			if store.lists != tt.wantLists {
+				t.Errorf("listed the outbox %d times, want %d", store.lists, tt.wantLists)
+			}
+
+			queuedEvents, err := repository.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(queuedEvents) != tt.wantCount {
+				t.Errorf("outbox holds %d events, want %d", len(queuedEvents), tt.wantCount)
+			}
+			// The newest event is never pruned.
+			if tt.enqueued > 0 && queuedEvents[len(queuedEvents)-1].ID != fmt.Sprintf("1-%05d", tt.enqueued-1) {
+				t.Errorf("newest event was pruned")
+			}
+		})
+	}
+}
+
+func TestOutboxRepository_EnqueueScrubsEvents(t *testing.T) {
+	disabledEvent := func(properties map[string]any) *event.QueuedEvent {
+		return event.NewQueuedEvent("user@example.com", "Agent Automatically Disabled", properties)
+	}
+
+	tests := []struct {
+		name           string
+		queuedEvent    *event.QueuedEvent
+		listErr        error
+		wantProperties map[string]any
+		wantErr        error
+	}{
+		{
+			name:           "allowed properties",
+			queuedEvent:    disabledEvent(map[string]any{"reason": "stopped", "target-os": "linux", "other": 1}),
+			wantProperties: map[string]any{"reason": "stopped", "target-os": "linux"},
+		},
+		{
+			name:           "container names",
+			queuedEvent:    disabledEvent(map[string]any{"reason": "my-api stopped"}),
+			wantProperties: map[string]any{"reason": hashOf(t, "my-api") + " stopped"},
+		},
+		{
+			name:           "containers can't be listed",
+			queuedEvent:    disabledEvent(map[string]any{"reason": "my-api stopped"}),
+			listErr:        errors.New("daemon unavailable"),
+			wantProperties: map[string]any{"reason": "my-api stopped"},
+		},
+		{
+			name:        "unknown event",
+			queuedEvent: event.NewQueuedEvent("user@example.com", "Unknown", nil),
+			wantErr:     failure.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			containerRepo := &fakeContainerRepo{
+				containers: []*container.Container{{ID: "1", Names: []string{"/my-api"}}},
+				listErr:    tt.listErr,
+			}
+			repository := NewOutboxRepository(newMemoryStore(), testPolicy, containerRepo, newTestLogger())
+
+			err := repository.Enqueue(ctx, tt.queuedEvent)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Enqueue() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+
+			queuedEvents, err := repository.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(queuedEvents) != 1 {
+				t.Fatalf("outbox holds %d events, want 1", len(queuedEvents))
+			}
+			if got := queuedEvents[0].Properties; !reflect.DeepEqual(got, tt.wantProperties) {
+				t.Errorf("queued properties = %v, want %v", got, tt.wantProperties)
+			}
+			if got, want := queuedEvents[0].DistinctID, testPolicy.Pseudonymize("user@example.com"); got != want {
+				t.Errorf("queued distinct ID = %s, want the pseudonym %s", got, want)
+			}
+		})
+	}
+}
+
+// Returns the hash that the test policy replaces the given personal data with.
+func hashOf(t *testing.T, value string) string {
+	scrubbed, err := testPolicy.Scrub("Agent Automatically Disabled", map[string]any{"reason": value}, []string{value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scrubbed["reason"].(string)
+}
@@ -2,10 +2,15 @@ package repo
 
 import (
 	"akita/domain/container"
+	"akita/domain/failure"
 	"akita/infrastructure/datasource/docker"
 	"context"
 	"github.com/akitasoftware/go-utils/optionals"
+	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 type ContainerRepository struct {
@@ -30,3 +35,88 @@ func (c ContainerRepository) Exists(
 
 	return c.dockerClient.ContainerExists(ctx, docker.ContainerFilterOptions{Filters: args})
 }
+
+func (c ContainerRepository) List(ctx context.Context, filter container.Filter) ([]*container.Container, error) {
+	args := filters.NewArgs()
+
+	if status, ok := filter.Status.Get(); ok {
+		args.Add("status", string(status))
+	}
+	if name, ok := filter.Name.Get(); ok {
+		// Docker matches names as regular expressions against names that start with a slash.
+		args.Add("name", "^/"+regexp.QuoteMeta(strings.TrimPrefix(name, "/"))+"$")
+	}
+	if project, ok := filter.ComposeProject.Get(); ok {
+		args.Add("label", container.ComposeProjectLabel+"="+project)
+	}
+	for _, label := range filter.Labels {
+		args.Add("label", label)
+	}
+
+	containers, err := c.dockerClient.ListContainers(ctx, dockertypes.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*container.Container, 0, len(containers))
+	for _, dockerContainer := range containers {
+		result = append(result, toDomainContainer(dockerContainer))
+	}
+
+	return result, nil
+}
+
+func (c ContainerRepository) Resolve(ctx context.Context, selector container.Selector) (*container.Container, error) {
+	// Docker lists the most recently created containers first.
+	containers, err := c.List(ctx, selector.Filter())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containers) == 0 {
+		return nil, failure.NotFoundf("no running container matches %s", selector)
+	}
+
+	return containers[0], nil
+}
+
+func toDomainContainer(c dockertypes.Container) *container.Container {
+	ports := make([]container.Port, 0, len(c.Ports))
+	for _, port := range c.Ports {
+		domainPort := container.Port{
+			PrivatePort: int(port.PrivatePort),
+			IP:          port.IP,
+			Protocol:    port.Type,
+		}
+		if port.PublicPort != 0 {
+			publicPort := int(port.PublicPort)
+			domainPort.PublicPort = &publicPort
+		}
+		ports = append(ports, domainPort)
+	}
+
+	networks := []string{}
+	if c.NetworkSettings != nil {
+		for name := range c.NetworkSettings.Networks {
+			networks = append(networks, name)
+		}
+		sort.Strings(networks)
+	}
+
+	labels := c.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	return &container.Container{
+		ID:             c.ID,
+		Names:          c.Names,
+		Image:          c.Image,
+		State:          container.Status(c.State),
+		Ports:          ports,
+		Networks:       networks,
+		Labels:         labels,
+		ComposeProject: labels[container.ComposeProjectLabel],
+		ComposeService: labels[container.ComposeServiceLabel],
+	}
+}
@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"akita/domain/container"
+	"akita/infrastructure/datasource/docker"
+	"context"
+	"github.com/akitasoftware/go-utils/optionals"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// A docker client that returns fixed containers and records the filters it was listed with.
+type fakeListingClient struct {
+	docker.Client
+	containers []dockertypes.Container
+	filters    map[string][]string
+}
+
+func (f *fakeListingClient) ListContainers(
+	_ context.Context,
+	opts dockertypes.ContainerListOptions,
+) ([]dockertypes.Container, error) {
+	f.filters = map[string][]string{}
+	for _, key := range opts.Filters.Keys() {
+		values := opts.Filters.Get(key)
+		sort.Strings(values)
+		f.filters[key] = values
+	}
+	return f.containers, nil
+}
+
+func TestContainerRepository_ListFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter container.Filter
+		want   map[string][]string
+	}{
+		{name: "no filter", want: map[string][]string{}},
+		{
+			name:   "status",
+			filter: container.Filter{Status: optionals.Some(container.StatusRunning)},
+			want:   map[string][]string{"status": {"running"}},
+		},
+		{
+			name:   "exact name",
+			filter: container.Filter{Name: optionals.Some("/my.api")},
+			want:   map[string][]string{"name": {`^/my\.api$`}},
+		},
+		{
+			name: "compose project and labels",
+			filter: container.Filter{
+				ComposeProject: optionals.Some("shop"),
+				Labels:         []string{"tier=web"},
+			},
+			want: map[string][]string{"label": {container.ComposeProjectLabel + "=shop", "tier=web"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeListingClient{}
+			repository := NewContainerRepository(client)
+
+			if _, err := repository.List(context.Background(), tt.filter); err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if !reflect.DeepEqual(client.filters, tt.want) {
+				t.Errorf("List() filters = %v, want %v", client.filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerRepository_ListContainers(t *testing.T) {
+	publicPort := 8080
+	client := &fakeListingClient{containers: []dockertypes.Container{
+		{
+			ID:     "4f1c2b9e8d7a",
+			Names:  []string{"/shop-api-1"},
+			Image:  "shop/api",
+			State:  "running",
+			Ports:  []dockertypes.Port{{PrivatePort: 80, PublicPort: 8080, IP: "0.0.0.0", Type: "tcp"}, {PrivatePort: 443, Type: "tcp"}},
+			Labels: map[string]string{container.ComposeProjectLabel: "shop", container.ComposeServiceLabel: "api"},
+			NetworkSettings: &dockertypes.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{
+				"shop_default": {},
+				"bridge":       {},
+			}},
+		},
+		{ID: "9a8b7c6d5e4f", Names: []string{"/worker"}, Image: "worker", State: "exited"},
+	}}
+	repository := NewContainerRepository(client)
+
+	got, err := repository.List(context.Background(), container.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := []*container.Container{
+		{
+			ID:    "4f1c2b9e8d7a",
+			Names: []string{"/shop-api-1"},
+			Image: "shop/api",
+			State: container.StatusRunning,
+			Ports: []container.Port{
+				{PrivatePort: 80, PublicPort: &publicPort, IP: "0.0.0.0", Protocol: "tcp"},
+				{PrivatePort: 443, Protocol: "tcp"},
+			},
+			Networks:       []string{"bridge", "shop_default"},
+			Labels:         map[string]string{container.ComposeProjectLabel: "shop", container.ComposeServiceLabel: "api"},
+			ComposeProject: "shop",
+			ComposeService: "api",
+		},
+		{
+			ID:       "9a8b7c6d5e4f",
+			Names:    []string{"/worker"},
+			Image:    "worker",
+			State:    container.StatusExited,
+			Ports:    []container.Port{},
+			Networks: []string{},
+			Labels:   map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+}
+
+func TestContainerRepository_Resolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []dockertypes.Container
+		wantID     string
+		wantErr    bool
+	}{
+		{name: "no match", wantErr: true},
+		{
+			name: "most recent match",
+			containers: []dockertypes.Container{
+				{ID: "recreated", Names: []string{"/api"}, State: "running"},
+				{ID: "previous", Names: []string{"/api"}, State: "running"},
+			},
+			wantID: "recreated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := NewContainerRepository(&fakeListingClient{containers: tt.containers})
+
+			resolved, err := repository.Resolve(context.Background(), container.Selector{Name: "api"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resolved.ID != tt.wantID {
+				t.Errorf("Resolve() = %s, want %s", resolved.ID, tt.wantID)
+			}
+		})
+	}
+}
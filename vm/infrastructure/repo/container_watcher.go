@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"akita/domain/container"
+	"akita/infrastructure/datasource/docker"
+	"context"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"time"
+)
+
+type ContainerWatcher struct {
+	dockerClient docker.Client
+}
+
+func NewContainerWatcher(dockerClient docker.Client) container.Watcher {
+	return &ContainerWatcher{dockerClient: dockerClient}
+}
+
+func (c ContainerWatcher) Watch(ctx context.Context) <-chan container.Event {
+	args := filters.NewArgs()
+	args.Add("type", events.ContainerEventType)
+	for _, action := range []container.EventAction{
+		container.EventActionStart,
+		container.EventActionRestart,
+		container.EventActionStop,
+		container.EventActionDie,
+		container.EventActionPause,
+		container.EventActionUnpause,
+		container.EventActionDestroy,
+	} {
+		args.Add("event", string(action))
+	}
+
+	messages := c.dockerClient.SubscribeEvents(ctx, docker.EventOptions{Filters: args})
+
+	result := make(chan container.Event)
+	go func() {
+		defer close(result)
+
+		for message := range messages {
+			event := container.Event{
+				ContainerID:   message.Actor.ID,
+				ContainerName: message.Actor.Attributes["name"],
+				// Docker includes the container's labels in the event attributes.
+				Labels:    message.Actor.Attributes,
+				Action:    container.EventAction(message.Action),
+				Timestamp: time.Unix(0, message.TimeNano),
+			}
+
+			select {
+			case result <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return result
+}
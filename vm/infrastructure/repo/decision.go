@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"akita/domain/agent"
+	"akita/infrastructure/datasource"
+	"context"
+	"fmt"
+)
+
+const (
+	// The collection holding reconciliation decisions, keyed by time.
+	decisionCollection = "reconciliations"
+	// The number of decisions that are kept. Older decisions are removed.
+	maxStoredDecisions = 500
+)
+
+type DecisionRepository struct {
+	store datasource.Store
+}
+
+func NewDecisionRepository(store datasource.Store) agent.DecisionRepository {
+	return &DecisionRepository{store: store}
+}
+
+func (d DecisionRepository) RecordDecision(ctx context.Context, decision *agent.Decision) error {
+	if err := d.store.Put(ctx, decisionCollection, decisionKey(decision), decision); err != nil {
+		return fmt.Errorf("failed to record reconciliation decision: %w", err)
+	}
+
+	return d.prune(ctx)
+}
+
+func (d DecisionRepository) ListDecisions(ctx context.Context, limit int) ([]*agent.Decision, error) {
+	decisions, err := d.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*agent.Decision{}
+	for i := len(decisions) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, decisions[i])
+	}
+
+	return result, nil
+}
+
+// Returns all decisions, oldest first.
+func (d DecisionRepository) listAll(ctx context.Context) ([]*agent.Decision, error) {
+	result := []*agent.Decision{}
+	if err := d.store.List(ctx, decisionCollection, &result); err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation decisions: %w", err)
+	}
+
+	return result, nil
+}
+
+// Removes the oldest decisions beyond maxStoredDecisions.
+func (d DecisionRepository) prune(ctx context.Context) error {
+	decisions, err := d.listAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(decisions)-maxStoredDecisions; i++ {
+		if err := d.store.Delete(ctx, decisionCollection, decisionKey(decisions[i])); err != nil {
+			return fmt.Errorf("failed to prune reconciliation decisions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the key of the given decision. Keys sort in chronological order.
+// Timestamps are truncated to milliseconds, the precision Mongo stores them with.
+func decisionKey(decision *agent.Decision) string {
+	return decision.Timestamp.UTC().Format("20060102T150405.000Z") + "-" + decision.ConfigID
+}
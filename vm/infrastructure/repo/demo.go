@@ -3,27 +3,27 @@ package repo
 import (
 	"akita/domain/demo"
 	"akita/infrastructure/datasource"
+	"github.com/sirupsen/logrus"
 	"math/rand"
 	"time"
-
-	"github.com/golang/glog"
 )
 
 type (
 	demoRepositoryImpl struct {
 		demoServer datasource.DemoServer
+		logger     *logrus.Logger
 	}
 )
 
-func NewDemoRepository(demoServer datasource.DemoServer) demo.DemoRepository {
-	return &demoRepositoryImpl{demoServer: demoServer}
+func NewDemoRepository(demoServer datasource.DemoServer, logger *logrus.Logger) demo.DemoRepository {
+	return &demoRepositoryImpl{demoServer: demoServer, logger: logger}
 }
 
 func (d demoRepositoryImpl) SendMockTraffic() error {
 	// TODO: We should check if this is an error we don't expect and return it.
 	handleErr := func(apiName string, err error) {
 		if err != nil {
-			glog.Errorf("failed to send demo request to api '%s': %v", apiName, err)
+			d.logger.Errorf("failed to send demo request to api '%s': %v", apiName, err)
 		}
 	}
 
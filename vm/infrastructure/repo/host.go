@@ -2,26 +2,34 @@ package repo
 
 import (
 	"akita/domain/host"
+	"akita/infrastructure/datasource"
 	"context"
-	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// The collection holding information about the host.
+	hostCollection = "hosts"
+	// The key of the host's TargetPlatform document.
+	targetPlatformKey = "target-platform"
 )
 
 type HostRepository struct {
-	db *mongo.Database
+	store datasource.Store
 }
 
-func NewHostRepository(db *mongo.Database) HostRepository {
-	return HostRepository{db: db}
+func NewHostRepository(store datasource.Store) HostRepository {
+	return HostRepository{store: store}
 }
 
 func (h HostRepository) GetTargetPlatform(ctx context.Context) (*host.TargetPlatform, error) {
-	return getFirstDocument[host.TargetPlatform](ctx, h.hostCollection())
-}
+	var result host.TargetPlatform
+	if err := h.store.Get(ctx, hostCollection, targetPlatformKey, &result); err != nil {
+		return nil, err
+	}
 
-func (h HostRepository) SaveTargetPlatform(ctx context.Context, platform *host.TargetPlatform) error {
-	return upsertFirstDocument(ctx, h.hostCollection(), platform)
+	return &result, nil
 }
 
-func (h HostRepository) hostCollection() *mongo.Collection {
-	return h.db.Collection("hosts")
+func (h HostRepository) SaveTargetPlatform(ctx context.Context, platform *host.TargetPlatform) error {
+	return h.store.Put(ctx, hostCollection, targetPlatformKey, platform)
 }
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"akita/domain/agent"
+	"akita/domain/failure"
+	"akita/domain/host"
+	"akita/infrastructure/datasource"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const (
+	// The collection recording which migrations have been applied to a store.
+	migrationCollection = "migrations"
+	// The key of the migration of data written by versions that only supported Mongo.
+	legacyMongoMigrationKey = "legacy-mongo"
+)
+
+// Records that a migration has been applied.
+type migrationRecord struct {
+	CompletedAt time.Time `json:"completed_at" bson:"completed_at"`
+}
+
+// Connects to the database holding legacy data. Returns an error if the
+// database can't be reached.
+type LegacyMongoConnector func(ctx context.Context) (*mongo.Database, error)
+
+// Copies the data written by versions of the extension that stored documents
+// directly in Mongo into the given store, then removes the copied documents
+// from Mongo. The migration is only recorded as done once every collection has
+// been copied; if the legacy database can't be reached or copying fails, an
+// error is returned and the migration is attempted again on the next call.
+func MigrateLegacyMongoData(
+	ctx context.Context,
+	connect LegacyMongoConnector,
+	store datasource.Store,
+	agentRepo agent.Repository,
+) error {
+	var record migrationRecord
+	err := store.Get(ctx, migrationCollection, legacyMongoMigrationKey, &record)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, failure.ErrNotFound) {
+		return err
+	}
+
+	db, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the legacy database: %w", err)
+	}
+	defer db.Client().Disconnect(ctx)
+
+	err = migrateLegacyCollection(ctx, db, agentConfigCollection, func(agentConfig *agent.Config) error {
+		// Configs saved before multiple configs were supported have no ID.
+		if agentConfig.ID == "" {
+			agentConfig.ID = agent.DefaultConfigID
+		}
+		// Saving through the repository encrypts the credentials.
+		return agentRepo.SaveConfig(ctx, agentConfig, agent.SaveOptions{Source: agent.ChangeSourceMigration})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = migrateLegacyCollection(ctx, db, hostCollection, func(platform *host.TargetPlatform) error {
+		return store.Put(ctx, hostCollection, targetPlatformKey, platform)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = migrateLegacyCollection(ctx, db, decisionCollection, func(decision *agent.Decision) error {
+		return store.Put(ctx, decisionCollection, decisionKey(decision), decision)
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordLegacyMongoMigration(ctx, store)
+}
+
+func recordLegacyMongoMigration(ctx context.Context, store datasource.Store) error {
+	return store.Put(ctx, migrationCollection, legacyMongoMigrationKey, migrationRecord{CompletedAt: time.Now().UTC()})
+}
+
+// Passes every legacy document of the given collection to migrate and removes
+// it from Mongo once it has been migrated. Legacy documents are identified by
+// the ObjectID that Mongo generated for them.
+func migrateLegacyCollection[T any](
+	ctx context.Context,
+	db *mongo.Database,
+	collectionName string,
+	migrate func(*T) error,
+) error {
+	collection := db.Collection(collectionName)
+	legacyFilter := bson.M{"_id": bson.M{"$type": "objectId"}}
+
+	cursor, err := collection.Find(ctx, legacyFilter)
+	if err != nil {
+		return fmt.Errorf("failed to read legacy documents from collection %s: %w", collectionName, err)
+	}
+
+	var documents []bson.Raw
+	if err := cursor.All(ctx, &documents); err != nil {
+		return fmt.Errorf("failed to read legacy documents from collection %s: %w", collectionName, err)
+	}
+
+	for _, document := range documents {
+		var value T
+		if err := bson.Unmarshal(document, &value); err != nil {
+			return fmt.Errorf("failed to decode legacy document from collection %s: %w", collectionName, err)
+		}
+
+		if err := migrate(&value); err != nil {
+			return fmt.Errorf("failed to migrate legacy document from collection %s: %w", collectionName, err)
+		}
+
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": document.Lookup("_id")}); err != nil {
+			return fmt.Errorf("failed to remove legacy document from collection %s: %w", collectionName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"akita/infrastructure/datasource"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyMongoData(t *testing.T) {
+	unreachable := errors.New("server selection timeout")
+
+	tests := []struct {
+		name        string
+		alreadyDone bool
+		wantErr     error
+		// The number of connections after two starts.
+		wantConnected int
+	}{
+		{name: "unreachable database is retried", wantErr: unreachable, wantConnected: 2},
+		{name: "already migrated", alreadyDone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := datasource.ProvideFileStore(filepath.Join(t.TempDir(), "store.json"))
+			if err != nil {
+				t.Fatalf("ProvideFileStore() error = %v", err)
+			}
+			if tt.alreadyDone {
+				if err := recordLegacyMongoMigration(ctx, store); err != nil {
+					t.Fatalf("recordLegacyMongoMigration() error = %v", err)
+				}
+			}
+
+			connected := 0
+			connect := func(context.Context) (*mongo.Database, error) {
+				connected++
+				return nil, unreachable
+			}
+
+			for start := 0; start < 2; start++ {
+				err := MigrateLegacyMongoData(ctx, connect, store, nil)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("MigrateLegacyMongoData() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if connected != tt.wantConnected {
+				t.Errorf("connected %d times, want %d", connected, tt.wantConnected)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"akita/domain/event"
+	"akita/domain/failure"
+	"akita/infrastructure/datasource"
+	"context"
+	"github.com/akitasoftware/go-utils/optionals"
+)
+
+type RecordedEventRepository struct {
+	// The local analytics client, if one is used instead of Segment.
+	localClient optionals.Optional[datasource.LocalAnalyticsClient]
+}
+
+func NewRecordedEventRepository(
+	localClient optionals.Optional[datasource.LocalAnalyticsClient],
+) event.RecordedEventRepository {
+	return &RecordedEventRepository{localClient: localClient}
+}
+
+func (r RecordedEventRepository) ListRecordedEvents(_ context.Context, limit int) ([]*event.RecordedEvent, error) {
+	localClient, ok := r.localClient.Get()
+	if !ok {
+		return nil, failure.Unprocessablef("analytics events are only recorded by the file and memory analytics sinks").
+			WithCode(failure.CodeAnalyticsNotRecorded)
+	}
+
+	return localClient.RecentEvents(limit)
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"akita/domain/failure"
+	"akita/domain/service"
+	"akita/domain/user"
+	"akita/infrastructure/datasource"
+	"akita/metrics"
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// The path of the Akita API endpoint returning the services of a user.
+const servicesPath = "/v1/services"
+
+type ServiceRepository struct {
+	restyClient *resty.Client
+	// Returns the base URL of the Akita API, which can change at runtime.
+	baseURL func() string
+	// Guards the Akita API. It is shared with the other repositories calling the API.
+	breaker *datasource.CircuitBreaker
+	logger  *logrus.Logger
+}
+
+func NewServiceRepository(
+	httpClient *resty.Client,
+	baseURL func() string,
+	breaker *datasource.CircuitBreaker,
+	logger *logrus.Logger,
+) *ServiceRepository {
+	return &ServiceRepository{
+		restyClient: httpClient,
+		baseURL:     baseURL,
+		breaker:     breaker,
+		logger:      logger,
+	}
+}
+
+func (s ServiceRepository) ListServices(ctx context.Context, credentials user.Credentials) ([]*service.Service, error) {
+	if !s.breaker.Allow() {
+		snapshot := s.breaker.Snapshot()
+		return nil, failure.Unavailablef(
+			"the Akita API has been failing, calls are suspended until %s: %s",
+			snapshot.RetryAt.UTC().Format(time.RFC3339),
+			snapshot.LastError,
+		).WithCode(failure.CodeAkitaAPIUnavailable)
+	}
+
+	var result []*service.Service
+	start := time.Now()
+	response, err := s.restyClient.R().SetContext(ctx).SetBasicAuth(
+		credentials.APIKey,
+		credentials.APISecret,
+	).SetResult(&result).Get(s.baseURL() + servicesPath)
+
+	if ctx.Err() != nil {
+		s.breaker.Release()
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		outcome, message := classifyTransportError(err)
+		s.logger.WithContext(ctx).Debugf("Failed to call the Akita API: %v", err)
+		metrics.AkitaAPIRequestDuration.WithLabelValues(servicesPath, string(outcome)).
+			Observe(time.Since(start).Seconds())
+		s.breaker.RecordFailure(errors.New(message))
+		return nil, failure.Unavailablef("%s", message).WithCode(failure.CodeAkitaAPIUnavailable)
+	}
+
+	var outcome user.VerificationOutcome
+	switch status := response.StatusCode(); {
+	case status == 401, status == 403:
+		outcome = user.OutcomeUnauthorized
+		err = failure.Unauthorizedf("no user found with the given API key and secret").
+			WithCode(failure.CodeInvalidCredentials)
+	case status == 429:
+		outcome = user.OutcomeRateLimited
+		err = failure.Unavailablef("the Akita API is rate limiting requests").
+			WithCode(failure.CodeAkitaAPIUnavailable)
+	case status >= 500:
+		outcome = user.OutcomeServerError
+		err = failure.Unavailablef("the Akita API failed to handle the request: %s", response.Status()).
+			WithCode(failure.CodeAkitaAPIUnavailable)
+	case response.IsError() || status < 200 || status >= 300:
+		outcome = user.OutcomeUnexpected
+		err = failure.Unavailablef("unexpected response from the Akita API: %s", response.Status()).
+			WithCode(failure.CodeAkitaAPIUnavailable)
+	default:
+		outcome = user.OutcomeValid
+	}
+	metrics.AkitaAPIRequestDuration.WithLabelValues(servicesPath, string(outcome)).
+		Observe(time.Since(start).Seconds())
+
+	if outcome == user.OutcomeServerError || outcome == user.OutcomeRateLimited {
+		s.breaker.RecordFailure(err)
+	} else {
+		// The API is up, even if it rejected the request.
+		s.breaker.RecordSuccess()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
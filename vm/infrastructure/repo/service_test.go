@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"akita/domain/failure"
+	"akita/domain/user"
+	"akita/infrastructure/datasource"
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServiceRepository_ListServices(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantNames    []string
+		wantErr      error
+		wantFailures int
+	}{
+		{
+			name:      "listed services",
+			status:    http.StatusOK,
+			body:      `[{"id": "svc_1", "name": "project", "deployment_infos": []}]`,
+			wantNames: []string{"project"},
+		},
+		{name: "rejected credentials", status: http.StatusUnauthorized, body: `{}`, wantErr: failure.ErrUnauthorized},
+		{name: "forbidden", status: http.StatusForbidden, body: `{}`, wantErr: failure.ErrUnauthorized},
+		{
+			name:         "server error",
+			status:       http.StatusInternalServerError,
+			body:         `{}`,
+			wantErr:      failure.ErrUnavailable,
+			wantFailures: 1,
+		},
+		{
+			name:         "rate limited",
+			status:       http.StatusTooManyRequests,
+			body:         `{}`,
+			wantErr:      failure.ErrUnavailable,
+			wantFailures: 1,
+		},
+		{name: "unexpected response", status: http.StatusNotFound, body: `{}`, wantErr: failure.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != servicesPath {
+					t.Errorf("requested path = %s, want %s", r.URL.Path, servicesPath)
+				}
+				if apiKey, apiSecret, _ := r.BasicAuth(); apiKey != "key" || apiSecret != "secret" {
+					t.Errorf("requested with credentials %s:%s, want key:secret", apiKey, apiSecret)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			breaker := datasource.NewCircuitBreaker(5, time.Minute)
+			repository := NewServiceRepository(resty.New(), func() string { return server.URL }, breaker, newTestLogger())
+
+			services, err := repository.ListServices(context.Background(), user.Credentials{APIKey: "key", APISecret: "secret"})
+			if failures := breaker.Snapshot().ConsecutiveFailures; failures != tt.wantFailures {
+				t.Errorf("ListServices() recorded %d failures, want %d", failures, tt.wantFailures)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ListServices() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListServices() error = %v", err)
+			}
+
+			if len(services) != len(tt.wantNames) {
+				t.Fatalf("ListServices() returned %d services, want %d", len(services), len(tt.wantNames))
+			}
+			for i, listedService := range services {
+				if listedService.Name != tt.wantNames[i] {
+					t.Errorf("ListServices() service %d = %s, want %s", i, listedService.Name, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServiceRepository_ListServicesWhileBreakerIsOpen(t *testing.T) {
+	breaker := datasource.NewCircuitBreaker(1, time.Minute)
+	breaker.Allow()
+	breaker.RecordFailure(errors.New("connection refused"))
+
+	repository := NewServiceRepository(resty.New(), func() string { return "http://127.0.0.1:0" }, breaker, newTestLogger())
+	_, err := repository.ListServices(context.Background(), user.Credentials{APIKey: "key", APISecret: "secret"})
+	if !errors.Is(err, failure.ErrUnavailable) {
+		t.Fatalf("ListServices() error = %v, want %v", err, failure.ErrUnavailable)
+	}
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"akita/domain/failure"
+	"akita/domain/settings"
+	"akita/infrastructure/datasource"
+	"context"
+	"errors"
+)
+
+const (
+	// The collection holding the user's settings.
+	settingsCollection = "settings"
+	// The key of the user's telemetry settings.
+	telemetrySettingsKey = "telemetry"
+)
+
+type SettingsRepository struct {
+	store datasource.Store
+}
+
+func NewSettingsRepository(store datasource.Store) settings.Repository {
+	return &SettingsRepository{store: store}
+}
+
+func (s SettingsRepository) GetTelemetry(ctx context.Context) (*settings.Telemetry, error) {
+	var result settings.Telemetry
+	if err := s.store.Get(ctx, settingsCollection, telemetrySettingsKey, &result); err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return settings.NewUndecidedTelemetry(), nil
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s SettingsRepository) SaveTelemetry(ctx context.Context, telemetry *settings.Telemetry) error {
+	return s.store.Put(ctx, settingsCollection, telemetrySettingsKey, telemetry)
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"akita/domain/failure"
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// A Store that keeps documents in memory and counts how often collections are listed.
+type memoryStore struct {
+	documents map[string]map[string][]byte
+	lists     int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{documents: map[string]map[string][]byte{}}
+}
+
+func (m *memoryStore) Get(_ context.Context, collection, key string, target any) error {
+	raw, ok := m.documents[collection][key]
+	if !ok {
+		return failure.NotFoundf("no document %s found in collection %s", key, collection)
+	}
+	return json.Unmarshal(raw, target)
+}
+
+func (m *memoryStore) List(_ context.Context, collection string, target any) error {
+	m.lists++
+
+	keys := make([]string, 0, len(m.documents[collection]))
+	for key := range m.documents[collection] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rawDocuments := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		rawDocuments = append(rawDocuments, m.documents[collection][key])
+	}
+
+	raw, err := json.Marshal(rawDocuments)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+func (m *memoryStore) Put(_ context.Context, collection, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if m.documents[collection] == nil {
+		m.documents[collection] = map[string][]byte{}
+	}
+	m.documents[collection][key] = raw
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, collection, key string) error {
+	delete(m.documents[collection], key)
+	return nil
+}
+
+func (m *memoryStore) Ping(context.Context) error {
+	return nil
+}
+
+func (m *memoryStore) Close(context.Context) error {
+	return nil
+}
@@ -1,49 +1,335 @@
 package repo
 
 import (
+	"akita/domain/event"
 	"akita/domain/failure"
+	"akita/domain/settings"
 	"akita/domain/user"
+	"akita/infrastructure/datasource"
+	"akita/metrics"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"github.com/akitasoftware/akita-libs/analytics"
 	"github.com/go-resty/resty/v2"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// The path of the Akita API endpoint returning the user that credentials belong to.
+const userPath = "/v1/user"
+
+// Configures how calls to the Akita API are retried.
+type RetryPolicy struct {
+	// How many times a call that failed transiently is retried.
+	Count int
+	// The backoff before the first retry. It doubles with each retry and is jittered.
+	WaitTime time.Duration
+	// The longest backoff between retries.
+	MaxWaitTime time.Duration
+	// How long a single attempt may take. Attempts never outlive the context of the call.
+	AttemptTimeout time.Duration
+}
+
 type UserRepository struct {
-	restyClient     *resty.Client
-	analyticsClient analytics.Client
+	restyClient *resty.Client
+	// The base URL of the Akita API, which can change at runtime.
+	baseURL      *atomic.Pointer[string]
+	outboxRepo   event.OutboxRepository
+	settingsRepo settings.Repository
+	retryPolicy  RetryPolicy
+	// Guards the Akita API, so that calls fail fast while it is down.
+	breaker *datasource.CircuitBreaker
+	cache   *UserCache
+	logger  *logrus.Logger
 }
 
-func NewUserRepository(httpClient *resty.Client, analyticsClient analytics.Client) *UserRepository {
+func NewUserRepository(
+	httpClient *resty.Client,
+	outboxRepo event.OutboxRepository,
+	settingsRepo settings.Repository,
+	retryPolicy RetryPolicy,
+	breaker *datasource.CircuitBreaker,
+	cache *UserCache,
+	logger *logrus.Logger,
+) *UserRepository {
 	return &UserRepository{
-		restyClient:     httpClient,
-		analyticsClient: analyticsClient,
+		restyClient:  httpClient,
+		baseURL:      newBaseURL(httpClient.BaseURL),
+		outboxRepo:   outboxRepo,
+		settingsRepo: settingsRepo,
+		retryPolicy:  retryPolicy,
+		breaker:      breaker,
+		cache:        cache,
+		logger:       logger,
+	}
+}
+
+func (u UserRepository) GetUser(ctx context.Context, credentials user.Credentials) (*user.User, error) {
+	if cachedUser, ok := u.cache.Get(credentials); ok {
+		return cachedUser, nil
+	}
+
+	verification, err := u.VerifyCredentials(ctx, credentials)
+	if err != nil {
+		return nil, err
 	}
+
+	if err := verification.Err(); err != nil {
+		if errors.Is(err, failure.ErrUnavailable) {
+			if staleUser, ok := u.cache.GetStale(credentials); ok {
+				u.logger.WithContext(ctx).Warnf("Serving a stale cached user while the Akita API is unavailable: %v", err)
+				return staleUser, nil
+			}
+		}
+		return nil, err
+	}
+
+	return verification.User, nil
 }
 
-func (u UserRepository) GetUser(credentials user.Credentials) (*user.User, error) {
-	const path = "/v1/user"
+func (u UserRepository) BaseURL() string {
+	return *u.baseURL.Load()
+}
+
+// Switches to the Akita API at the given base URL. Users cached from the
+// previous API and its failures are forgotten.
+func (u UserRepository) SetBaseURL(baseURL string) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	u.baseURL.Store(&baseURL)
+	u.cache.Clear()
+	u.breaker.Reset()
+}
+
+func newBaseURL(baseURL string) *atomic.Pointer[string] {
+	result := &atomic.Pointer[string]{}
+	result.Store(&baseURL)
+	return result
+}
+
+func (u UserRepository) InvalidateUser(credentials user.Credentials) {
+	u.cache.Invalidate(credentials)
+}
+
+func (u UserRepository) VerifyCredentials(ctx context.Context, credentials user.Credentials) (*user.Verification, error) {
+	if !u.breaker.Allow() {
+		snapshot := u.breaker.Snapshot()
+		return &user.Verification{
+			Outcome: user.OutcomeCircuitOpen,
+			Message: fmt.Sprintf(
+				"the Akita API has been failing, calls are suspended until %s: %s",
+				snapshot.RetryAt.UTC().Format(time.RFC3339),
+				snapshot.LastError,
+			),
+			APIURL: u.BaseURL() + userPath,
+		}, nil
+	}
+
+	var verification *user.Verification
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		verification, retryAfter = u.verifyOnce(ctx, credentials)
+		verification.Attempts = attempt + 1
+
+		if ctx.Err() != nil {
+			u.breaker.Release()
+			return nil, ctx.Err()
+		}
+
+		if !verification.Outcome.IsTransient() || attempt >= u.retryPolicy.Count {
+			break
+		}
+
+		wait := u.backoff(attempt, retryAfter)
+		u.logger.WithContext(ctx).Debugf(
+			"Retrying call to the Akita API in %s after attempt %d failed: %s",
+			wait,
+			attempt+1,
+			verification.Message,
+		)
+		select {
+		case <-ctx.Done():
+			u.breaker.Release()
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	switch verification.Outcome {
+	case user.OutcomeValid:
+		u.breaker.RecordSuccess()
+		u.cache.Put(credentials, verification.User)
+	case user.OutcomeUnauthorized, user.OutcomeForbidden:
+		// The API is up, but the credentials are no longer valid.
+		u.breaker.RecordSuccess()
+		u.cache.Invalidate(credentials)
+	default:
+		u.breaker.RecordFailure(errors.New(verification.Message))
+	}
+
+	return verification, nil
+}
+
+// Enqueues the event unless the user hasn't consented to telemetry.
+func (u UserRepository) EnqueueUserEvent(ctx context.Context, userEvent *user.Event) error {
+	telemetry, err := u.settingsRepo.GetTelemetry(ctx)
+	if err != nil {
+		return err
+	}
+	if !telemetry.IsAllowed() {
+		return nil
+	}
+
+	fetchedUser, err := u.GetUser(ctx, userEvent.Credentials)
+	if err != nil {
+		return err
+	}
+
+	return u.outboxRepo.Enqueue(ctx, event.NewQueuedEvent(fetchedUser.Email, userEvent.Name, userEvent.Properties))
+}
+
+func (u UserRepository) Health() *user.APIHealth {
+	snapshot := u.breaker.Snapshot()
+
+	health := &user.APIHealth{
+		APIURL:              u.BaseURL(),
+		Healthy:             snapshot.State == datasource.BreakerClosed,
+		BreakerState:        string(snapshot.State),
+		ConsecutiveFailures: snapshot.ConsecutiveFailures,
+		LastError:           snapshot.LastError,
+		UserCache:           u.cache.Stats(),
+	}
+	if !snapshot.OpenedAt.IsZero() {
+		health.OpenedAt = &snapshot.OpenedAt
+	}
+	if !snapshot.RetryAt.IsZero() {
+		health.RetryAt = &snapshot.RetryAt
+	}
+
+	return health
+}
+
+// Makes a single attempt to verify the credentials. Also returns how long the
+// API asked to wait before retrying, if it did.
+func (u UserRepository) verifyOnce(ctx context.Context, credentials user.Credentials) (*user.Verification, time.Duration) {
+	attemptCtx := ctx
+	if u.retryPolicy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, u.retryPolicy.AttemptTimeout)
+		defer cancel()
+	}
 
 	var result user.User
+	apiURL := u.BaseURL() + userPath
 
-	response, err := u.restyClient.R().SetBasicAuth(
+	start := time.Now()
+	response, err := u.restyClient.R().SetContext(attemptCtx).SetBasicAuth(
 		credentials.APIKey,
 		credentials.APISecret,
-	).SetResult(&result).Get(path)
+	).SetResult(&result).Get(apiURL)
+
+	verification := &user.Verification{
+		APIURL:        apiURL,
+		LatencyMillis: time.Since(start).Milliseconds(),
+	}
+
 	if err != nil {
-		if response != nil && response.StatusCode() == 401 {
-			return nil, failure.Unauthorizedf("no user found with the given API key and secret")
+		verification.Outcome, verification.Message = classifyTransportError(err)
+		u.logger.WithContext(ctx).Debugf("Failed to call the Akita API: %v", err)
+		metrics.AkitaAPIRequestDuration.WithLabelValues(userPath, string(verification.Outcome)).
+			Observe(time.Since(start).Seconds())
+		return verification, 0
+	}
+
+	var retryAfter time.Duration
+	verification.StatusCode = response.StatusCode()
+	switch status := response.StatusCode(); {
+	case status == 401:
+		verification.Outcome = user.OutcomeUnauthorized
+		verification.Message = "no user found with the given API key and secret"
+	case status == 403:
+		verification.Outcome = user.OutcomeForbidden
+		verification.Message = "the user is not allowed to access the Akita API"
+	case status == 429:
+		verification.Outcome = user.OutcomeRateLimited
+		verification.Message = "the Akita API is rate limiting requests"
+		if seconds, err := strconv.Atoi(response.Header().Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+			verification.Message += fmt.Sprintf(", retry after %d seconds", seconds)
 		}
-		return nil, fmt.Errorf("failed to fetch Akita user: %w", err)
+	case status >= 500:
+		verification.Outcome = user.OutcomeServerError
+		verification.Message = fmt.Sprintf("the Akita API failed to handle the request: %s", response.Status())
+	case response.IsError() || status < 200 || status >= 300:
+		verification.Outcome = user.OutcomeUnexpected
+		verification.Message = fmt.Sprintf("unexpected response from the Akita API: %s", response.Status())
+	default:
+		verification.Outcome = user.OutcomeValid
+		verification.Message = "the credentials are valid"
+		verification.User = &result
 	}
+	metrics.AkitaAPIRequestDuration.WithLabelValues(userPath, string(verification.Outcome)).
+		Observe(time.Since(start).Seconds())
 
-	return &result, nil
+	return verification, retryAfter
 }
 
-func (u UserRepository) EnqueueUserEvent(event *user.Event) error {
-	fetchedUser, err := u.GetUser(event.Credentials)
-	if err != nil {
-		return err
+// Returns how long to wait before the retry following the given attempt. The
+// backoff grows exponentially with jitter, unless the API asked for a specific wait.
+func (u UserRepository) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > u.retryPolicy.MaxWaitTime {
+			return u.retryPolicy.MaxWaitTime
+		}
+		return retryAfter
+	}
+
+	wait := u.retryPolicy.WaitTime << attempt
+	if wait <= 0 || wait > u.retryPolicy.MaxWaitTime {
+		wait = u.retryPolicy.MaxWaitTime
+	}
+	if wait <= 0 {
+		return 0
 	}
 
-	return u.analyticsClient.Track(fetchedUser.Email, event.Name, event.Properties)
+	// Wait between half and all of the backoff, so that callers don't retry in lockstep.
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Classifies an error that prevented a response from being received.
+func classifyTransportError(err error) (user.VerificationOutcome, string) {
+	var (
+		dnsErr           *net.DNSError
+		unknownAuthority x509.UnknownAuthorityError
+		invalidCert      x509.CertificateInvalidError
+		hostnameErr      x509.HostnameError
+		recordHeaderErr  tls.RecordHeaderError
+		netErr           net.Error
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return user.OutcomeDNSFailure, fmt.Sprintf("failed to resolve %s: %s", dnsErr.Name, dnsErr.Err)
+	case errors.As(err, &unknownAuthority),
+		errors.As(err, &invalidCert),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &recordHeaderErr):
+		return user.OutcomeTLSError, fmt.Sprintf(
+			"failed to establish a secure connection to the Akita API, which may be caused by a proxy: %s",
+			err,
+		)
+	case errors.Is(err, context.DeadlineExceeded):
+		return user.OutcomeNetworkUnreachable, "the Akita API did not respond in time"
+	case errors.As(err, &netErr):
+		return user.OutcomeNetworkUnreachable, fmt.Sprintf("failed to reach the Akita API: %s", err)
+	default:
+		// No response was received, so the API is treated as unreachable.
+		return user.OutcomeNetworkUnreachable, fmt.Sprintf("failed to call the Akita API: %s", err)
+	}
 }
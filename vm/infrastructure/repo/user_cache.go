@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"akita/domain/user"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Caches the Akita users that credentials belong to, so that the Akita API
+// isn't called for every analytics event. Users are keyed by a hash of their
+// credentials, so that the credentials aren't kept in memory longer than needed.
+type UserCache struct {
+	// How long a cached user is served without calling the API.
+	ttl time.Duration
+	// How long past its TTL a cached user is served while the API is unavailable.
+	maxStale time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedUser
+	hits    int64
+	misses  int64
+}
+
+type cachedUser struct {
+	user      *user.User
+	fetchedAt time.Time
+}
+
+func NewUserCache(ttl time.Duration, maxStale time.Duration) *UserCache {
+	return &UserCache{
+		ttl:      ttl,
+		maxStale: maxStale,
+		entries:  map[string]cachedUser{},
+	}
+}
+
+// Returns the cached user of the given credentials if it is within its TTL,
+// and records a hit or miss.
+func (c *UserCache) Get(credentials user.Credentials) (*user.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[credentialsKey(credentials)]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	return entry.user, true
+}
+
+// Returns the cached user of the given credentials if it expired no longer
+// than the maximum staleness ago.
+func (c *UserCache) GetStale(credentials user.Credentials) (*user.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[credentialsKey(credentials)]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl+c.maxStale {
+		return nil, false
+	}
+
+	return entry.user, true
+}
+
+func (c *UserCache) Put(credentials user.Credentials, cachedUserValue *user.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop the entries that can no longer be served.
+	for key, entry := range c.entries {
+		if time.Since(entry.fetchedAt) > c.ttl+c.maxStale {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[credentialsKey(credentials)] = cachedUser{user: cachedUserValue, fetchedAt: time.Now()}
+}
+
+func (c *UserCache) Invalidate(credentials user.Credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, credentialsKey(credentials))
+}
+
+// Removes all cached users.
+func (c *UserCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]cachedUser{}
+}
+
+func (c *UserCache) Stats() user.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return user.CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: len(c.entries),
+	}
+}
+
+// Returns the cache key of the given credentials.
+func credentialsKey(credentials user.Credentials) string {
+	sum := sha256.Sum256([]byte(credentials.APIKey + ":" + credentials.APISecret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"akita/domain/user"
+	"testing"
+	"time"
+)
+
+func TestUserCache(t *testing.T) {
+	credentials := user.Credentials{APIKey: "key", APISecret: "secret"}
+	otherCredentials := user.Credentials{APIKey: "key", APISecret: "other secret"}
+	resolvedUser := &user.User{Email: "user@example.com"}
+
+	tests := []struct {
+		name string
+		// How long ago the user was cached. Nothing is cached if negative.
+		age         time.Duration
+		lookup      user.Credentials
+		invalidate  bool
+		wantFresh   bool
+		wantStale   bool
+		wantEntries int
+	}{
+		{name: "nothing cached", age: -1, lookup: credentials},
+		{name: "fresh", lookup: credentials, wantFresh: true, wantStale: true, wantEntries: 1},
+		{name: "other credentials", lookup: otherCredentials, wantEntries: 1},
+		{
+			name:        "expired but not too stale",
+			age:         2 * time.Minute,
+			lookup:      credentials,
+			wantStale:   true,
+			wantEntries: 1,
+		},
+		{name: "too stale", age: 2 * time.Hour, lookup: credentials, wantEntries: 1},
+		{name: "invalidated", lookup: credentials, invalidate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewUserCache(time.Minute, time.Hour)
+			if tt.age >= 0 {
+				cache.Put(credentials, resolvedUser)
+				cache.entries[credentialsKey(credentials)] = cachedUser{
+					user:      resolvedUser,
+					fetchedAt: time.Now().Add(-tt.age),
+				}
+			}
+			if tt.invalidate {
+				cache.Invalidate(credentials)
+			}
+
+			if _, ok := cache.Get(tt.lookup); ok != tt.wantFresh {
+				t.Errorf("Get() found = %t, want %t", ok, tt.wantFresh)
+			}
+			if _, ok := cache.GetStale(tt.lookup); ok != tt.wantStale {
+				t.Errorf("GetStale() found = %t, want %t", ok, tt.wantStale)
+			}
+
+			stats := cache.Stats()
+			wantHits := int64(0)
+			if tt.wantFresh {
+				wantHits = 1
+			}
+			if stats.Hits != wantHits || stats.Hits+stats.Misses != 1 || stats.Entries != tt.wantEntries {
+				t.Errorf("Stats() = %+v, want %d hits of 1 lookup and %d entries", stats, wantHits, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestUserCache_PutDropsEntriesThatCanNoLongerBeServed(t *testing.T) {
+	cache := NewUserCache(time.Minute, time.Hour)
+	oldCredentials := user.Credentials{APIKey: "old", APISecret: "secret"}
+	cache.entries[credentialsKey(oldCredentials)] = cachedUser{
+		user:      &user.User{Email: "old@example.com"},
+		fetchedAt: time.Now().Add(-2 * time.Hour),
+	}
+
+	cache.Put(user.Credentials{APIKey: "new", APISecret: "secret"}, &user.User{Email: "new@example.com"})
+
+	if entries := cache.Stats().Entries; entries != 1 {
+		t.Errorf("Stats() entries = %d, want 1", entries)
+	}
+}
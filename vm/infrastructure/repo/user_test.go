@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"akita/domain/user"
+	"akita/infrastructure/datasource"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyTransportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want user.VerificationOutcome
+	}{
+		{
+			name: "dns failure",
+			err:  &net.DNSError{Name: "api.akita.software", Err: "no such host"},
+			want: user.OutcomeDNSFailure,
+		},
+		{name: "untrusted certificate", err: x509.UnknownAuthorityError{}, want: user.OutcomeTLSError},
+		{name: "timeout", err: fmt.Errorf("get: %w", context.DeadlineExceeded), want: user.OutcomeNetworkUnreachable},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			want: user.OutcomeNetworkUnreachable,
+		},
+		{name: "unclassified", err: errors.New("unsupported protocol scheme"), want: user.OutcomeNetworkUnreachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := classifyTransportError(tt.err); got != tt.want {
+				t.Errorf("classifyTransportError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepository_VerifyCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retryCount   int
+		wantOutcome  user.VerificationOutcome
+		wantAttempts int
+	}{
+		{name: "valid", statuses: []int{http.StatusOK}, wantOutcome: user.OutcomeValid, wantAttempts: 1},
+		{
+			name:         "unauthorized",
+			statuses:     []int{http.StatusUnauthorized},
+			wantOutcome:  user.OutcomeUnauthorized,
+			wantAttempts: 1,
+		},
+		{name: "forbidden", statuses: []int{http.StatusForbidden}, wantOutcome: user.OutcomeForbidden, wantAttempts: 1},
+		{
+			name:         "unexpected response",
+			statuses:     []int{http.StatusNotFound},
+			retryCount:   2,
+			wantOutcome:  user.OutcomeUnexpected,
+			wantAttempts: 1,
+		},
+		{
+			name:         "server error retried until valid",
+			statuses:     []int{http.StatusBadGateway, http.StatusOK},
+			retryCount:   2,
+			wantOutcome:  user.OutcomeValid,
+			wantAttempts: 2,
+		},
+		{
+			name:         "server error retried until exhausted",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			retryCount:   2,
+			wantOutcome:  user.OutcomeServerError,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests]
+				requests++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"email": "user@example.com"}`))
+			}))
+			defer server.Close()
+
+			repository := newTestUserRepository(server.URL, tt.retryCount)
+			verification, err := repository.VerifyCredentials(
+				context.Background(),
+				user.Credentials{APIKey: "key", APISecret: "secret"},
+			)
+			if err != nil {
+				t.Fatalf("VerifyCredentials() error = %v", err)
+			}
+
+			if verification.Outcome != tt.wantOutcome || verification.Attempts != tt.wantAttempts {
+				t.Errorf(
+					"VerifyCredentials() outcome = %s after %d attempts, want %s after %d",
+					verification.Outcome,
+					verification.Attempts,
+					tt.wantOutcome,
+					tt.wantAttempts,
+				)
+			}
+			if tt.wantOutcome == user.OutcomeValid && verification.User.Email != "user@example.com" {
+				t.Errorf("VerifyCredentials() user = %+v, want user@example.com", verification.User)
+			}
+		})
+	}
+}
+
+func newTestUserRepository(baseURL string, retryCount int) *UserRepository {
+	return NewUserRepository(
+		resty.New().SetBaseURL(baseURL),
+		nil,
+		nil,
+		RetryPolicy{Count: retryCount, WaitTime: time.Millisecond, MaxWaitTime: time.Millisecond},
+		datasource.NewCircuitBreaker(5, time.Minute),
+		NewUserCache(time.Minute, time.Hour),
+		newTestLogger(),
+	)
+}
@@ -0,0 +1,157 @@
+package worker
+
+import (
+	"akita/domain/health"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A background loop. It runs until the context is cancelled; returning
+// before that, with or without an error, or panicking is a crash.
+type Run func(ctx context.Context) error
+
+// Runs background workers, restarting those that crash with exponential backoff.
+type Supervisor struct {
+	// The backoff before the first restart of a worker. It doubles with each
+	// crash, and is reset once the worker has run for longer than the maximum.
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	logger     *logrus.Logger
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	statuses map[string]*health.WorkerStatus
+}
+
+func NewSupervisor(minBackoff time.Duration, maxBackoff time.Duration, logger *logrus.Logger) *Supervisor {
+	return &Supervisor{
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		logger:     logger,
+		statuses:   map[string]*health.WorkerStatus{},
+	}
+}
+
+// Runs the worker with the given name in the background until the context is cancelled.
+func (s *Supervisor) Start(ctx context.Context, name string, run Run) {
+	s.mu.Lock()
+	s.statuses[name] = &health.WorkerStatus{Name: name, State: health.WorkerRunning, StartedAt: time.Now().UTC()}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.supervise(ctx, name, run)
+	}()
+}
+
+func (s *Supervisor) supervise(ctx context.Context, name string, run Run) {
+	backoff := s.minBackoff
+	for {
+		startedAt := time.Now()
+		err := s.runSafely(ctx, run)
+
+		if ctx.Err() != nil {
+			s.update(name, func(status *health.WorkerStatus) {
+				status.State = health.WorkerStopped
+				status.RestartAt = nil
+			})
+			return
+		}
+
+		if err == nil {
+			err = fmt.Errorf("worker exited unexpectedly")
+		}
+		if time.Since(startedAt) > s.maxBackoff {
+			backoff = s.minBackoff
+		}
+
+		crashedAt := time.Now().UTC()
+		restartAt := crashedAt.Add(backoff)
+		s.logger.WithField("worker", name).Errorf("Worker crashed, restarting in %s: %v", backoff, err)
+		s.update(name, func(status *health.WorkerStatus) {
+			status.State = health.WorkerRestarting
+			status.LastError = err.Error()
+			status.LastCrashAt = &crashedAt
+			status.RestartAt = &restartAt
+		})
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.update(name, func(status *health.WorkerStatus) {
+				status.State = health.WorkerStopped
+				status.RestartAt = nil
+			})
+			return
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+
+		s.update(name, func(status *health.WorkerStatus) {
+			status.State = health.WorkerRunning
+			status.Restarts++
+			status.StartedAt = time.Now().UTC()
+			status.RestartAt = nil
+		})
+	}
+}
+
+// Runs the worker, turning a panic into an error.
+func (s *Supervisor) runSafely(ctx context.Context, run Run) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Errorf("Worker panicked: %v\n%s", recovered, debug.Stack())
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	return run(ctx)
+}
+
+func (s *Supervisor) update(name string, apply func(status *health.WorkerStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apply(s.statuses[name])
+}
+
+// Waits until all workers have stopped, or the context is done.
+func (s *Supervisor) Wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers didn't stop in time: %w", ctx.Err())
+	}
+}
+
+func (s *Supervisor) WorkerStatuses() []*health.WorkerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*health.WorkerStatus, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statusCopy := *status
+		result = append(result, &statusCopy)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
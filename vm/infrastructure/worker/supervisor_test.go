@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"akita/domain/health"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSupervisor() *Supervisor {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewSupervisor(time.Millisecond, time.Minute, logger)
+}
+
+// Returns a worker that crashes with the given function on its first runs,
+// then runs until the context is cancelled.
+func crashingWorker(crashes int, crash func() error) (Run, *atomic.Int32) {
+	runs := &atomic.Int32{}
+	return func(ctx context.Context) error {
+		if runs.Add(1) <= int32(crashes) {
+			return crash()
+		}
+		<-ctx.Done()
+		return nil
+	}, runs
+}
+
+// Waits until the status of the only worker of the supervisor satisfies the condition.
+func waitForStatus(t *testing.T, supervisor *Supervisor, condition func(status *health.WorkerStatus) bool) *health.WorkerStatus {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := supervisor.WorkerStatuses()
+		if len(statuses) == 1 && condition(statuses[0]) {
+			return statuses[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("worker statuses = %+v, timed out waiting for the expected status", statuses)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisor_Restarts(t *testing.T) {
+	tests := []struct {
+		name          string
+		crashes       int
+		crash         func() error
+		wantLastError string
+	}{
+		{name: "runs until stopped"},
+		{
+			name:          "restarts after errors",
+			crashes:       2,
+			crash:         func() error { return errors.New("connection reset") },
+			wantLastError: "connection reset",
+		},
+		{
+			name:          "restarts after returning early",
+			crashes:       1,
+			crash:         func() error { return nil },
+			wantLastError: "worker exited unexpectedly",
+		},
+		{
+			name:          "restarts after panics",
+			crashes:       3,
+			crash:         func() error { panic("boom") },
+			wantLastError: "panic: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supervisor := newTestSupervisor()
+			run, runs := crashingWorker(tt.crashes, tt.crash)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			supervisor.Start(ctx, "test", run)
+
+			status := waitForStatus(t, supervisor, func(status *health.WorkerStatus) bool {
+				return status.State == health.WorkerRunning && runs.Load() > int32(tt.crashes)
+			})
+			if status.Restarts != tt.crashes {
+				t.Errorf("Restarts = %d, want %d", status.Restarts, tt.crashes)
+			}
+			if status.LastError != tt.wantLastError {
+				t.Errorf("LastError = %q, want %q", status.LastError, tt.wantLastError)
+			}
+			if (status.LastCrashAt != nil) != (tt.crashes > 0) {
+				t.Errorf("LastCrashAt = %v, want it set only after a crash", status.LastCrashAt)
+			}
+
+			cancel()
+			if err := supervisor.Wait(context.Background()); err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+			status = waitForStatus(t, supervisor, func(status *health.WorkerStatus) bool { return true })
+			if status.State != health.WorkerStopped || status.RestartAt != nil {
+				t.Errorf("status after stopping = %+v, want stopped without a pending restart", status)
+			}
+		})
+	}
+}
+
+func TestSupervisor_StopsWhileWaitingToRestart(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	supervisor := NewSupervisor(time.Hour, time.Hour, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	supervisor.Start(ctx, "test", func(context.Context) error { return errors.New("failed") })
+
+	status := waitForStatus(t, supervisor, func(status *health.WorkerStatus) bool {
+		return status.State == health.WorkerRestarting
+	})
+	if status.RestartAt == nil || time.Until(*status.RestartAt) < 59*time.Minute {
+		t.Errorf("RestartAt = %v, want it an hour away", status.RestartAt)
+	}
+
+	cancel()
+	if err := supervisor.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if status := supervisor.WorkerStatuses()[0]; status.State != health.WorkerStopped {
+		t.Errorf("State = %s, want %s", status.State, health.WorkerStopped)
+	}
+}
+
+func TestSupervisor_WaitTimesOut(t *testing.T) {
+	supervisor := newTestSupervisor()
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The worker ignores the cancellation of its context.
+	supervisor.Start(ctx, "stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+	cancel()
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWait()
+	if err := supervisor.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
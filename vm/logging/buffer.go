@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// A log entry kept in memory.
+type Record struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// A logrus hook that keeps the most recent entries in memory, so that they can
+// be attached to support tickets.
+type Buffer struct {
+	mu sync.Mutex
+	// A ring buffer of entries. Once it is full, the oldest entry is at next.
+	records []Record
+	next    int
+	full    bool
+}
+
+// Creates a buffer holding up to the given number of entries. A buffer
+// without capacity keeps nothing.
+func NewBuffer(capacity int) *Buffer {
+	return &Buffer{records: make([]Record, capacity)}
+}
+
+func (b *Buffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (b *Buffer) Fire(entry *logrus.Entry) error {
+	if len(b.records) == 0 {
+		return nil
+	}
+
+	record := Record{Time: entry.Time.UTC(), Level: entry.Level.String(), Message: entry.Message}
+	if len(entry.Data) > 0 {
+		record.Fields = make(map[string]any, len(entry.Data))
+		for key, value := range entry.Data {
+			// Errors and other values that don't encode to JSON are kept as text.
+			switch value.(type) {
+			case string, bool, int, int64, float64:
+				record.Fields[key] = value
+			default:
+				record.Fields[key] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	b.full = b.full || b.next == 0
+
+	return nil
+}
+
+// Returns up to limit of the most recent entries at or above the given level,
+// oldest first.
+func (b *Buffer) Records(limit int, minLevel logrus.Level) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ordered := b.records[:b.next]
+	if b.full {
+		ordered = append(append([]Record{}, b.records[b.next:]...), b.records[:b.next]...)
+	}
+
+	result := []Record{}
+	for i := len(ordered) - 1; i >= 0 && len(result) < limit; i-- {
+		level, err := logrus.ParseLevel(ordered[i].Level)
+		if err != nil || level > minLevel {
+			continue
+		}
+		result = append(result, ordered[i])
+	}
+
+	// The entries were collected newest first.
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result
+}
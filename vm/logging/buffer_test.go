@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestBuffer_Records(t *testing.T) {
+	type entry struct {
+		level   logrus.Level
+		message string
+	}
+	entries := []entry{
+		{logrus.InfoLevel, "first"},
+		{logrus.WarnLevel, "second"},
+		{logrus.DebugLevel, "third"},
+		{logrus.ErrorLevel, "fourth"},
+		{logrus.InfoLevel, "fifth"},
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		// How many of the entries are logged.
+		logged   int
+		limit    int
+		minLevel logrus.Level
+		want     []string
+	}{
+		{name: "empty", capacity: 3, limit: 10, minLevel: logrus.DebugLevel, want: []string{}},
+		{
+			name:     "not full",
+			capacity: 3,
+			logged:   2,
+			limit:    10,
+			minLevel: logrus.DebugLevel,
+			want:     []string{"first", "second"},
+		},
+		{
+			name:     "full",
+			capacity: 3,
+			logged:   3,
+			limit:    10,
+			minLevel: logrus.DebugLevel,
+			want:     []string{"first", "second", "third"},
+		},
+		{
+			name:     "oldest entries overwritten",
+			capacity: 3,
+			logged:   5,
+			limit:    10,
+			minLevel: logrus.DebugLevel,
+			want:     []string{"third", "fourth", "fifth"},
+		},
+		{
+			name:     "most recent entries up to the limit",
+			capacity: 5,
+			logged:   5,
+			limit:    2,
+			minLevel: logrus.DebugLevel,
+			want:     []string{"fourth", "fifth"},
+		},
+		{
+			name:     "entries below the level skipped",
+			capacity: 5,
+			logged:   5,
+			limit:    10,
+			minLevel: logrus.WarnLevel,
+			want:     []string{"second", "fourth"},
+		},
+		{
+			name:     "limit applied after the level",
+			capacity: 5,
+			logged:   5,
+			limit:    1,
+			minLevel: logrus.WarnLevel,
+			want:     []string{"fourth"},
+		},
+		{
+			name:     "disabled",
+			capacity: 0,
+			logged:   5,
+			limit:    10,
+			minLevel: logrus.DebugLevel,
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := NewBuffer(tt.capacity)
+			logger := newTestLogger(buffer)
+			for _, entry := range entries[:tt.logged] {
+				logger.Log(entry.level, entry.message)
+			}
+
+			got := []string{}
+			for _, record := range buffer.Records(tt.limit, tt.minLevel) {
+				got = append(got, record.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Records() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuffer_Fields(t *testing.T) {
+	buffer := NewBuffer(1)
+	logger := newTestLogger(buffer)
+
+	logger.WithFields(logrus.Fields{
+		"worker":   "reconciliation",
+		"attempts": 3,
+		"enabled":  true,
+		"error":    errors.New("connection refused"),
+		"tags":     []string{"a", "b"},
+	}).Error("Worker crashed")
+
+	records := buffer.Records(1, logrus.DebugLevel)
+	if len(records) != 1 {
+		t.Fatalf("Records() returned %d records, want 1", len(records))
+	}
+	want := map[string]any{
+		"worker":   "reconciliation",
+		"attempts": 3,
+		"enabled":  true,
+		// Values that don't encode to JSON are kept as text.
+		"error": "connection refused",
+		"tags":  "[a b]",
+	}
+	if record := records[0]; record.Level != "error" || !reflect.DeepEqual(record.Fields, want) {
+		t.Errorf("record = %+v, want an error record with fields %v", record, want)
+	}
+}
+
+// Returns a logger writing nothing but the entries kept by the buffer.
+func newTestLogger(buffer *Buffer) *logrus.Logger {
+	logger := New(buffer)
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	return logger
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+// The field holding the correlation ID of the request that an entry was logged for.
+const CorrelationIDField = "correlation_id"
+
+type correlationIDKey struct{}
+
+// Returns a copy of the context carrying the given correlation ID, which is
+// added to the entries logged with the context.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// Returns the correlation ID carried by the context, if any.
+func CorrelationID(ctx context.Context) (string, bool) {
+	correlationID, ok := ctx.Value(correlationIDKey{}).(string)
+	return correlationID, ok && correlationID != ""
+}
+
+// Creates the logger of the backend. Entries logged with a context, through
+// logger.WithContext, are tagged with its correlation ID, and all entries are
+// kept in the given buffer.
+func New(buffer *Buffer) *logrus.Logger {
+	logger := logrus.New()
+	// The correlation ID is added before the entry is buffered.
+	logger.AddHook(correlationIDHook{})
+	logger.AddHook(buffer)
+
+	return logger
+}
+
+// Sets the level and format of the logger. The level is one of debug, info,
+// warn or error, and the format is either text or json.
+func Configure(logger *logrus.Logger, level string, format string) error {
+	parsedLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	logger.SetLevel(parsedLevel)
+
+	return nil
+}
+
+// Adds the correlation ID of the context of an entry to its fields.
+type correlationIDHook struct{}
+
+func (correlationIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (correlationIDHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if correlationID, ok := CorrelationID(entry.Context); ok {
+		entry.Data[CorrelationIDField] = correlationID
+	}
+
+	return nil
+}
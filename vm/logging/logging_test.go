@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+)
+
+func TestNew_CorrelationID(t *testing.T) {
+	tests := []struct {
+		name              string
+		ctx               context.Context
+		wantCorrelationID any
+	}{
+		{
+			name:              "context with a correlation ID",
+			ctx:               WithCorrelationID(context.Background(), "abc123"),
+			wantCorrelationID: "abc123",
+		},
+		{name: "context without a correlation ID", ctx: context.Background()},
+		{name: "empty correlation ID", ctx: WithCorrelationID(context.Background(), "")},
+		{name: "no context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := NewBuffer(1)
+			logger := newTestLogger(buffer)
+
+			entry := logrus.NewEntry(logger)
+			if tt.ctx != nil {
+				entry = entry.WithContext(tt.ctx)
+			}
+			entry.Info("Handled request")
+
+			records := buffer.Records(1, logrus.DebugLevel)
+			if len(records) != 1 {
+				t.Fatalf("Records() returned %d records, want 1", len(records))
+			}
+			if got := records[0].Fields[CorrelationIDField]; got != tt.wantCorrelationID {
+				t.Errorf("correlation ID = %v, want %v", got, tt.wantCorrelationID)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		format    string
+		wantLevel logrus.Level
+		wantJSON  bool
+		wantErr   bool
+	}{
+		{name: "text", level: "info", format: "text", wantLevel: logrus.InfoLevel},
+		{name: "json", level: "debug", format: "json", wantLevel: logrus.DebugLevel, wantJSON: true},
+		{name: "warn", level: "warn", format: "text", wantLevel: logrus.WarnLevel},
+		{name: "unknown level", level: "verbose", format: "text", wantErr: true},
+		{name: "unknown format", level: "info", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := New(NewBuffer(0))
+			var output bytes.Buffer
+			logger.SetOutput(&output)
+
+			err := Configure(logger, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if logger.GetLevel() != tt.wantLevel {
+				t.Errorf("level = %s, want %s", logger.GetLevel(), tt.wantLevel)
+			}
+			logger.WithField("worker", "reconciliation").Error("Worker crashed")
+			var decoded map[string]any
+			isJSON := json.Unmarshal(output.Bytes(), &decoded) == nil
+			if isJSON != tt.wantJSON {
+				t.Errorf("output %q is JSON = %v, want %v", output.String(), isJSON, tt.wantJSON)
+			}
+			if !strings.Contains(output.String(), "reconciliation") {
+				t.Errorf("output %q doesn't contain the fields of the entry", output.String())
+			}
+		})
+	}
+}
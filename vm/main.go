@@ -2,17 +2,31 @@ package main
 
 import (
 	"akita/app"
+	"akita/app/interactor"
 	"akita/config"
+	"akita/domain/agent"
+	"akita/domain/event"
+	"akita/domain/health"
 	"akita/infrastructure/datasource"
 	"akita/infrastructure/datasource/docker"
 	"akita/infrastructure/repo"
+	"akita/infrastructure/worker"
+	"akita/logging"
 	"akita/ports"
 	"context"
 	_ "embed"
+	"fmt"
+	"github.com/akitasoftware/go-utils/optionals"
 	"github.com/go-resty/resty/v2"
+	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
-	"log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -22,92 +36,531 @@ var applicationYML []byte
 //go:embed stubs.json
 var demoServerStubs []byte
 
+// How long the app waits for requests, workers and clients to finish when shutting down.
+const shutdownTimeout = 15 * time.Second
+
+// How long sending a batch of analytics events may take.
+const analyticsTimeout = 10 * time.Second
+
 func main() {
 	appConfig, err := config.Parse(applicationYML)
 	if err != nil {
-		log.Fatalf("failed to parse config: %v", err)
+		// The logger is configured by the config, so it doesn't exist yet.
+		logrus.Fatalf("failed to parse config: %v", err)
+	}
+
+	if appConfig.IsHealthCheck() {
+		os.Exit(checkReadiness(appConfig.SocketPath()))
 	}
 
-	logrus.New().Infof("Starting listening on %s\n", appConfig.SocketPath())
+	logBuffer := logging.NewBuffer(appConfig.LogConfig().BufferSize)
+	logger := logging.New(logBuffer)
+	if err := logging.Configure(logger, appConfig.LogConfig().Level, appConfig.LogConfig().Format); err != nil {
+		logger.Fatalf("failed to configure logging: %v", err)
+	}
+
+	settingsManager := config.NewManager(appConfig, logger)
+	settingsManager.Subscribe(func(previous *config.Config, current *config.Config) {
+		if current.LogConfig() == previous.LogConfig() {
+			return
+		}
+		if err := logging.Configure(logger, current.LogConfig().Level, current.LogConfig().Format); err != nil {
+			logger.Errorf("failed to configure logging: %v", err)
+		}
+	})
+
+	logger.WithFields(logrus.Fields{
+		"target_os":   appConfig.TargetPlatform().OS,
+		"target_arch": appConfig.TargetPlatform().Arch,
+	}).Infof("Starting listening on %s", appConfig.SocketPath())
+
+	// The app is stopped on SIGTERM or SIGINT, e.g. when the extension is removed or updated.
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignals()
+	// The workers outlive the signal until the in-flight requests that may rely on them are drained.
+	appCtx, cancelApp := context.WithCancel(context.Background())
+	defer cancelApp()
 
-	appCtx := context.Background()
+	store, err := provideStore(appCtx, appConfig.StorageConfig())
+	if err != nil {
+		logger.Fatalf("failed to initialize storage: %v", err)
+	}
+	store = datasource.NewInstrumentedStore(store)
 
-	database, err := datasource.ProvideMongoDB(appCtx)
+	encrypter, err := datasource.ProvideEncrypter(appConfig.StorageConfig().EncryptionKeyPath)
 	if err != nil {
-		log.Fatalf("failed to connect to mongo: %v", err)
+		logger.Fatalf("failed to initialize encryption: %v", err)
 	}
 
-	dockerClient, err := docker.NewClient()
+	dockerClient, err := docker.NewClient(logger)
 	if err != nil {
-		log.Fatalf("failed to initialize docker client: %v", err)
+		logger.Fatalf("failed to initialize docker client: %v", err)
 	}
-	defer dockerClient.Close()
 
-	analyticsClient, err := datasource.ProvideAnalyticsClient(appConfig.AnalyticsConfig())
+	analyticsClient, localAnalyticsClient, err := provideAnalyticsClient(appConfig, settingsManager, logger)
 	if err != nil {
-		log.Fatalf("Failed to create analytics client: %v", err)
+		logger.Fatalf("Failed to create analytics client: %v", err)
 	}
-	defer analyticsClient.Close()
 
-	mockServer, err := datasource.ProvideDemoServer(8080, demoServerStubs)
+	mockServer, err := datasource.ProvideDemoServer(appConfig.DemoServerConfig().Port, demoServerStubs)
 	if err != nil {
-		log.Fatalf("Failed to create mock server: %v", err)
+		logger.Fatalf("Failed to create mock server: %v", err)
 	}
 
-	akitaAPIClient := resty.New().SetBaseURL("https://api.akita.software")
+	akitaAPIClient, err := provideAkitaAPIClient(appConfig.AkitaAPIConfig())
+	if err != nil {
+		logger.Fatalf("failed to create Akita API client: %v", err)
+	}
 
-	agentRepo := repo.NewAgentRepository(database)
+	agentRepo := repo.NewAgentRepository(store, encrypter)
+	agentContainerRepo := repo.NewAgentContainerRepository(dockerClient, encrypter, logger)
+	decisionRepo := repo.NewDecisionRepository(store)
 	containerRepo := repo.NewContainerRepository(dockerClient)
-	userRepo := repo.NewUserRepository(akitaAPIClient, analyticsClient)
-	hostRepo := repo.NewHostRepository(database)
-	demoRepo := repo.NewDemoRepository(mockServer)
+	containerWatcher := repo.NewContainerWatcher(dockerClient)
+	outboxRepo := repo.NewOutboxRepository(
+		store,
+		analyticsPolicy(appConfig.AnalyticsPolicyConfig()),
+		containerRepo,
+		logger,
+	)
+	settingsRepo := repo.NewSettingsRepository(store)
+	recordedEventRepo := repo.NewRecordedEventRepository(localAnalyticsClient)
+	akitaAPIBreaker := datasource.NewCircuitBreaker(
+		appConfig.AkitaAPIConfig().CircuitBreaker.FailureThreshold,
+		appConfig.AkitaAPIConfig().CircuitBreaker.OpenDuration,
+	)
+	userRepo := repo.NewUserRepository(
+		akitaAPIClient,
+		outboxRepo,
+		settingsRepo,
+		akitaAPIRetryPolicy(appConfig.AkitaAPIConfig()),
+		akitaAPIBreaker,
+		repo.NewUserCache(appConfig.AkitaAPIConfig().UserCache.TTL, appConfig.AkitaAPIConfig().UserCache.MaxStale),
+		logger,
+	)
+	serviceRepo := repo.NewServiceRepository(akitaAPIClient, userRepo.BaseURL, akitaAPIBreaker, logger)
+	settingsManager.Subscribe(func(previous *config.Config, current *config.Config) {
+		if current.AkitaAPIConfig().BaseURL != previous.AkitaAPIConfig().BaseURL {
+			userRepo.SetBaseURL(current.AkitaAPIConfig().BaseURL)
+		}
+	})
+	hostRepo := repo.NewHostRepository(store)
+	demoRepo := repo.NewDemoRepository(mockServer, logger)
+
+	migrateLegacyData(appCtx, appConfig.StorageConfig(), store, agentRepo, logger)
 
-	appInstance := app.New(agentRepo, hostRepo, containerRepo, userRepo, demoRepo, analyticsClient)
+	supervisor := worker.NewSupervisor(time.Second, time.Minute, logger)
+
+	appInstance := app.New(
+		agentRepo,
+		agentContainerRepo,
+		decisionRepo,
+		hostRepo,
+		containerRepo,
+		containerWatcher,
+		userRepo,
+		serviceRepo,
+		demoRepo,
+		settingsRepo,
+		outboxRepo,
+		recordedEventRepo,
+		analyticsClient,
+		analyticsDeliveryPolicy(appConfig.AnalyticsOutboxConfig()),
+		supervisor,
+		[]health.Checker{
+			datasource.NewStoreChecker(store),
+			datasource.NewDockerChecker(dockerClient),
+			datasource.NewDemoServerChecker(mockServer),
+			datasource.NewAkitaAPIChecker(akitaAPIClient, userRepo.BaseURL),
+		},
+		logger,
+	)
 
 	err = appInstance.SaveHostDetails.Handle(appCtx, appConfig.TargetPlatform())
 	if err != nil {
-		log.Fatalf("failed to save host details: %v", err)
+		logger.Fatalf("failed to save host details: %v", err)
 	}
 
-	router := ports.NewRouter(appInstance)
+	router := ports.NewRouter(appInstance, settingsManager, logger, logBuffer)
 
 	startURL := ""
 
 	ln, err := listen(appConfig.SocketPath())
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 	router.Listener = ln
 
-	handleBackgroundDemoTasks(appCtx, appInstance)
+	supervisor.Start(appCtx, "demo-traffic", handleBackgroundDemoTasks(appInstance, settingsManager, logger))
+	supervisor.Start(appCtx, "reconciliation", handleBackgroundReconciliation(appInstance, logger))
+	supervisor.Start(appCtx, "container-events", handleContainerEvents(appInstance))
+	supervisor.Start(
+		appCtx,
+		"analytics-delivery",
+		handleAnalyticsDelivery(appInstance, appConfig.AnalyticsOutboxConfig().DeliveryInterval, logger),
+	)
+	if appConfig.ConfigPath() != "" {
+		supervisor.Start(appCtx, "config-watch", handleConfigFileChanges(settingsManager))
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- router.Start(startURL)
+	}()
+
+	exitCode := 0
+	select {
+	case <-signalCtx.Done():
+		logger.Info("Shutting down")
+	case err := <-serverErr:
+		logger.Errorf("server stopped: %v", err)
+		exitCode = 1
+	}
+
+	shutdown(router, supervisor, cancelApp, appInstance, analyticsClient, dockerClient, store, logger)
+	os.Exit(exitCode)
+}
+
+// Creates the store selected in the storage config.
+func provideStore(ctx context.Context, storageConfig config.StorageConfig) (datasource.Store, error) {
+	if storageConfig.Backend == config.StorageBackendMongo {
+		database, err := datasource.ProvideMongoDB(ctx, storageConfig.Mongo.URI, storageConfig.Mongo.Database)
+		if err != nil {
+			return nil, err
+		}
+
+		return datasource.NewMongoStore(database), nil
+	}
+
+	return datasource.ProvideFileStore(storageConfig.File.Path)
+}
+
+// Creates the client of the Akita API configured by the given config.
+func provideAkitaAPIClient(akitaAPIConfig config.AkitaAPIConfig) (*resty.Client, error) {
+	httpClient, err := datasource.ProvideHTTPClient(
+		akitaAPIConfig.Timeout,
+		akitaAPIConfig.ProxyURL,
+		akitaAPIConfig.CABundlePath,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries are made by the repositories, which know which failures are transient.
+	return resty.NewWithClient(httpClient).SetBaseURL(akitaAPIConfig.BaseURL), nil
+}
+
+// Returns the policy for retrying calls to the Akita API configured by the given config.
+func akitaAPIRetryPolicy(akitaAPIConfig config.AkitaAPIConfig) repo.RetryPolicy {
+	return repo.RetryPolicy{
+		Count:          akitaAPIConfig.Retry.Count,
+		WaitTime:       akitaAPIConfig.Retry.WaitTime,
+		MaxWaitTime:    akitaAPIConfig.Retry.MaxWaitTime,
+		AttemptTimeout: akitaAPIConfig.Timeout,
+	}
+}
+
+// Returns the policy for scrubbing analytics events configured by the given config.
+func analyticsPolicy(policyConfig config.AnalyticsPolicyConfig) event.Policy {
+	return event.Policy{
+		AllowedProperties:   policyConfig.Events,
+		CommonProperties:    policyConfig.CommonProperties,
+		SensitiveProperties: policyConfig.SensitiveProperties,
+		PIIAction:           event.PIIAction(policyConfig.PII),
+		MaxPayloadBytes:     policyConfig.MaxPayloadBytes,
+	}
+}
+
+// Returns the analytics client of the configured sink. If the sink records
+// events locally, its client is also returned as a local client. The Segment
+// client follows the analytics toggle as it changes at runtime.
+func provideAnalyticsClient(
+	appConfig *config.Config,
+	settingsManager *config.Manager,
+	logger *logrus.Logger,
+) (datasource.AnalyticsClient, optionals.Optional[datasource.LocalAnalyticsClient], error) {
+	sinkConfig := appConfig.AnalyticsSinkConfig()
+
+	var localClient datasource.LocalAnalyticsClient
+	switch sinkConfig.Type {
+	case config.AnalyticsSinkFile:
+		fileClient, err := datasource.ProvideFileAnalyticsClient(
+			sinkConfig.File.Path,
+			sinkConfig.File.MaxSizeBytes,
+			sinkConfig.File.MaxFiles,
+		)
+		if err != nil {
+			return nil, optionals.None[datasource.LocalAnalyticsClient](), err
+		}
+		localClient = fileClient
+	case config.AnalyticsSinkMemory:
+		localClient = datasource.ProvideMemoryAnalyticsClient(sinkConfig.Memory.Capacity)
+	default:
+		analyticsConfig, analyticsEnabled := appConfig.AnalyticsConfig()
+		// Segment is reached through the same proxy as the Akita API.
+		httpClient, err := datasource.ProvideHTTPClient(
+			analyticsTimeout,
+			appConfig.AkitaAPIConfig().ProxyURL,
+			appConfig.AkitaAPIConfig().CABundlePath,
+		)
+		if err != nil {
+			return nil, optionals.None[datasource.LocalAnalyticsClient](), err
+		}
+		client, err := datasource.ProvideSegmentClient(analyticsConfig, analyticsEnabled, httpClient, logger)
+		if err != nil {
+			return nil, optionals.None[datasource.LocalAnalyticsClient](), err
+		}
+
+		settingsManager.Subscribe(func(previous *config.Config, current *config.Config) {
+			_, wasEnabled := previous.AnalyticsConfig()
+			_, enabled := current.AnalyticsConfig()
+			if enabled == wasEnabled {
+				return
+			}
+			if err := client.SetEnabled(enabled); err != nil {
+				logger.Errorf("Failed to enable analytics: %v", err)
+			}
+		})
+
+		return client, optionals.None[datasource.LocalAnalyticsClient](), nil
+	}
+
+	return localClient, optionals.Some(localClient), nil
+}
+
+// Returns the policy for delivering queued analytics events configured by the given config.
+func analyticsDeliveryPolicy(outboxConfig config.AnalyticsOutboxConfig) interactor.AnalyticsDeliveryPolicy {
+	return interactor.AnalyticsDeliveryPolicy{
+		BatchSize:        outboxConfig.BatchSize,
+		MaxAge:           outboxConfig.MaxAge,
+		RetryWaitTime:    outboxConfig.Retry.WaitTime,
+		RetryMaxWaitTime: outboxConfig.Retry.MaxWaitTime,
+	}
+}
+
+// Migrates the data of versions of the extension that stored documents
+// directly in Mongo into the store.
+func migrateLegacyData(
+	ctx context.Context,
+	storageConfig config.StorageConfig,
+	store datasource.Store,
+	agentRepo agent.Repository,
+	logger *logrus.Logger,
+) {
+	if storageConfig.Mongo.URI == "" {
+		return
+	}
+
+	connect := func(ctx context.Context) (*mongo.Database, error) {
+		legacyDatabase, err := datasource.ProvideMongoDB(ctx, storageConfig.Mongo.URI, storageConfig.Mongo.Database)
+		if err == nil {
+			err = legacyDatabase.Client().Ping(ctx, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return legacyDatabase, nil
+	}
 
-	log.Fatal(router.Start(startURL))
+	// Failing to migrate is not fatal, e.g. if the database is still starting;
+	// the migration is retried on the next start until it completes.
+	if err := repo.MigrateLegacyMongoData(ctx, connect, store, agentRepo); err != nil {
+		logger.Warnf("failed to migrate legacy data from mongo: %v", err)
+	}
 }
 
 func listen(path string) (net.Listener, error) {
+	// Remove the socket left behind by a previous run.
+	_ = os.RemoveAll(path)
+
 	return net.Listen("unix", path)
 }
 
+// Requests the readiness of the backend listening on the socket at the given
+// path, for use as a container healthcheck. Returns the exit code.
+func checkReadiness(socketPath string) int {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	response, err := client.Get("http://backend/readyz")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to reach backend: %v\n", err)
+		return 1
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	fmt.Println(string(body))
+
+	if response.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
+
 // TODO: This doesn't belong here, but it's a convenient place to put it for now.
 // This is a worker that will send traffic to the Akita demo server in the background.
-func handleBackgroundDemoTasks(ctx context.Context, app *app.App) {
-	// Demo traffic is sent every `interval` seconds.
-	interval := time.Second * 1
+func handleBackgroundDemoTasks(app *app.App, settingsManager *config.Manager, logger *logrus.Logger) worker.Run {
+	// Demo traffic is sent every `interval`, which can change at runtime.
+	intervalChanges := make(chan time.Duration, 1)
+	settingsManager.Subscribe(func(previous *config.Config, current *config.Config) {
+		if current.DemoServerConfig().TrafficInterval != previous.DemoServerConfig().TrafficInterval {
+			// Only the latest change matters if the worker hasn't caught up yet.
+			select {
+			case <-intervalChanges:
+			default:
+			}
+			intervalChanges <- current.DemoServerConfig().TrafficInterval
+		}
+	})
 
-	// Create a channel that sends a value every 10 seconds.
-	ticker := time.NewTicker(interval)
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(settingsManager.Current().DemoServerConfig().TrafficInterval)
+		defer ticker.Stop()
 
-	// Run the demo traffic loop in the background.
-	go func() {
 		for {
 			// Wait for the next tick.
-			<-ticker.C
+			select {
+			case <-ctx.Done():
+				return nil
+			case interval := <-intervalChanges:
+				ticker.Reset(interval)
+				continue
+			case <-ticker.C:
+			}
 
 			// Send a random breed request to the demo server.
 			err := app.Interactors.SendDemoTraffic.Handle(ctx)
 			if err != nil {
-				logrus.New().Errorf("failed to send demo traffic: %v", err)
+				logger.WithContext(ctx).Errorf("failed to send demo traffic: %v", err)
 			}
 		}
-	}()
+	}
+}
+
+// This is a worker that keeps the agent container in sync with the saved agent config.
+func handleBackgroundReconciliation(app *app.App, logger *logrus.Logger) worker.Run {
+	// The agent is reconciled every `interval` seconds.
+	interval := time.Second * 10
+
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			decisions, err := app.Interactors.ReconcileAgent.Handle(ctx, interactor.ReconcileAgentOptions{})
+			if err != nil && ctx.Err() == nil {
+				logger.WithContext(ctx).Errorf("failed to reconcile agents: %v", err)
+			}
+			for _, decision := range decisions {
+				if decision.Action != agent.ActionNone {
+					logger.WithContext(ctx).Infof("reconciled agent %s: %s (%s)", decision.ConfigID, decision.Action, decision.Reason)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// This is a worker that reconciles the agent as soon as its target container changes state.
+func handleContainerEvents(app *app.App) worker.Run {
+	return func(ctx context.Context) error {
+		err := app.Interactors.WatchTargetContainer.Handle(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			return fmt.Errorf("stopped watching container events")
+		}
+		return fmt.Errorf("stopped watching container events: %w", err)
+	}
+}
+
+// This is a worker that reloads the config when the config file changes.
+func handleConfigFileChanges(settingsManager *config.Manager) worker.Run {
+	return func(ctx context.Context) error {
+		settingsManager.Watch(ctx, 2*time.Second)
+		return nil
+	}
+}
+
+// This is a worker that delivers the queued analytics events.
+func handleAnalyticsDelivery(app *app.App, interval time.Duration, logger *logrus.Logger) worker.Run {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			deliverAnalyticsEvents(ctx, app, logger)
+		}
+	}
+}
+
+func deliverAnalyticsEvents(ctx context.Context, app *app.App, logger *logrus.Logger) {
+	report, err := app.Interactors.DeliverAnalyticsEvents.Handle(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("failed to deliver analytics events: %v", err)
+		return
+	}
+	if report.Failed > 0 {
+		logger.WithContext(ctx).Warnf("failed to deliver %d analytics events: %s", report.Failed, report.LastError)
+	}
+}
+
+// Stops serving requests, stops the workers, flushes the queued analytics
+// events and releases the clients, giving up on whatever doesn't finish by the
+// timeout.
+func shutdown(
+	router *echo.Echo,
+	supervisor *worker.Supervisor,
+	stopWorkers context.CancelFunc,
+	app *app.App,
+	analyticsClient datasource.AnalyticsClient,
+	dockerClient docker.Client,
+	store datasource.Store,
+	logger *logrus.Logger,
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// In-flight requests are drained before the workers stop, as they may rely on them.
+	if err := router.Shutdown(ctx); err != nil {
+		logger.Errorf("failed to drain requests: %v", err)
+	}
+
+	stopWorkers()
+	if err := supervisor.Wait(ctx); err != nil {
+		logger.Errorf("failed to stop workers: %v", err)
+	}
+
+	deliverAnalyticsEvents(ctx, app, logger)
+	if err := analyticsClient.Close(); err != nil {
+		logger.Errorf("failed to close analytics client: %v", err)
+	}
+
+	if err := dockerClient.Close(); err != nil {
+		logger.Errorf("failed to close docker client: %v", err)
+	}
+
+	if err := store.Close(ctx); err != nil {
+		logger.Errorf("failed to close storage: %v", err)
+	}
 }
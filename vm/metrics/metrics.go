@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+// The values of the outcome label for operations that either succeed or fail.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Returns the outcome label of an operation that returned the given error.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+// Serves all metrics of the backend, along with those of the Go runtime and
+// the process, in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// The metrics of the backend. Their names are prefixed with akita_ext_ and
+// durations are in seconds. The outcome label is either success or error,
+// unless a more specific outcome is known, as documented for each metric.
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "akita_ext_http_requests_total",
+		Help: "HTTP requests handled by the backend, by route, method and status.",
+	}, []string{"endpoint", "method", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "akita_ext_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by route, method and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "outcome"})
+	HTTPErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "akita_ext_http_errors_total",
+		Help: "Error responses of the backend, by route and error code.",
+	}, []string{"endpoint", "code"})
+
+	DemoTrafficRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "akita_ext_demo_traffic_requests_total",
+		Help: "Requests sent to the demo server while demo mode is enabled, by outcome.",
+	}, []string{"outcome"})
+	AnalyticsEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "akita_ext_analytics_events_total",
+		Help: "Queued analytics events processed by the delivery worker, by outcome: delivered, failed or dropped.",
+	}, []string{"outcome"})
+	ReconcilerActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "akita_ext_reconciler_actions_total",
+		Help: "Actions taken by the agent reconciler, by action and outcome. " +
+			"Agents disabled because their target container is gone are counted with the disable action.",
+	}, []string{"action", "outcome"})
+
+	StoreOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "akita_ext_store_operation_duration_seconds",
+		Help:    "Time taken by operations of the document store, by operation, collection and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "collection", "outcome"})
+	AkitaAPIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "akita_ext_akita_api_request_duration_seconds",
+		Help: "Time taken by attempts of requests to the Akita API, by endpoint and outcome, " +
+			"e.g. valid, unauthorized, rate_limited or server_error.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "outcome"})
+)
@@ -2,12 +2,17 @@ package ports
 
 import (
 	"akita/app"
+	"akita/app/interactor"
 	"akita/domain/agent"
 	"akita/domain/failure"
-	"errors"
+	"github.com/akitasoftware/go-utils/optionals"
 	"github.com/labstack/echo"
+	"strconv"
 )
 
+// The request header identifying the client that makes a change.
+const changeSourceHeader = "X-Akita-Change-Source"
+
 type agentHandler struct {
 	app *app.App
 }
@@ -16,12 +21,31 @@ func newAgentHandler(app *app.App) *agentHandler {
 	return &agentHandler{app: app}
 }
 
+func (a agentHandler) listAgentConfigs(ctx echo.Context) error {
+	configs, err := a.app.ListAgentConfigs.Handle(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	redactedConfigs := make([]*agent.Config, 0, len(configs))
+	for _, config := range configs {
+		redactedConfigs = append(redactedConfigs, config.Redacted())
+	}
+
+	return ctx.JSON(200, redactedConfigs)
+}
+
 // getAgentConfig is called every time the UI comes into focus; it may have the
 // side effect of stopping the agent if the monitored container is no longer
 // present or running.
 func (a agentHandler) getAgentConfig(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
 	requestContext := ctx.Request().Context()
-	config, err := a.app.RetrieveAgentConfig.Handle(requestContext)
+	config, err := a.app.RetrieveAgentConfig.Handle(requestContext, configID)
 	if err != nil {
 		return err
 	}
@@ -30,48 +54,261 @@ func (a agentHandler) getAgentConfig(ctx echo.Context) error {
 		return ctx.NoContent(404)
 	}
 
+	return ctx.JSON(200, config.Redacted())
+}
+
+// Returns the agent config including its credentials, which are redacted by all other endpoints.
+func (a agentHandler) revealAgentConfig(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.app.RevealAgentConfig.Handle(ctx.Request().Context(), configID)
+	if err != nil {
+		return err
+	}
+
 	return ctx.JSON(200, config)
 }
 
 func (a agentHandler) createAgentConfig(ctx echo.Context) error {
-	config, err := agent.DecodeConfig(ctx.Request().Body)
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := agent.DecodeConfig(ctx.Request().Body, configID)
 	if err != nil {
 		return err
 	}
 
 	requestContext := ctx.Request().Context()
 
-	if err := a.app.SaveAgentConfig.Handle(requestContext, config); err != nil {
+	source, err := changeSource(ctx)
+	if err != nil {
 		return err
 	}
 
-	return ctx.JSON(201, config)
+	if err := a.app.SaveAgentConfig.Handle(requestContext, config, agent.SaveOptions{Source: source}); err != nil {
+		return err
+	}
+
+	return ctx.JSON(201, config.Redacted())
+}
+
+// Reports every problem with the agent config in the request body without saving it.
+func (a agentHandler) validateAgentConfig(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := agent.DecodeConfig(ctx.Request().Body, configID)
+	if err != nil {
+		return err
+	}
+
+	report, err := a.app.ValidateAgentConfig.Handle(ctx.Request().Context(), config)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, report)
 }
 
 func (a agentHandler) removeAgentConfig(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
 	requestContext := ctx.Request().Context()
 
-	if err := a.app.RemoveAgentConfig.Handle(requestContext); err != nil {
+	if err := a.app.RemoveAgentConfig.Handle(requestContext, configID); err != nil {
 		return err
 	}
 
 	return ctx.NoContent(200)
 }
 
-func handleError(err error, ctx echo.Context) {
-	body := map[string]string{
-		"errorMessage": err.Error(),
+func (a agentHandler) getAgentConfigHistory(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := a.app.RetrieveAgentConfigHistory.Handle(ctx.Request().Context(), configID)
+	if err != nil {
+		return err
+	}
+
+	redactedRevisions := make([]*agent.Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		redactedRevisions = append(redactedRevisions, revision.Redacted())
+	}
+
+	return ctx.JSON(200, redactedRevisions)
+}
+
+func (a agentHandler) rollbackAgentConfig(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	rawVersion := ctx.Param("version")
+	version, err := strconv.Atoi(rawVersion)
+	if err != nil || version < 1 {
+		return failure.Invalidf("invalid version %q", rawVersion)
 	}
 
-	if errors.Is(err, failure.ErrInvalid) {
-		_ = ctx.JSON(400, body)
-	} else if errors.Is(err, failure.ErrNotFound) {
-		_ = ctx.JSON(404, body)
-	} else if errors.Is(err, failure.ErrUnprocessable) {
-		_ = ctx.JSON(422, body)
-	} else if errors.Is(err, failure.ErrUnauthorized) {
-		_ = ctx.JSON(401, body)
-	} else {
-		_ = ctx.JSON(500, body)
+	source, err := changeSource(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.app.RollbackAgentConfig.Handle(ctx.Request().Context(), configID, version, source)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, config.Redacted())
+}
+
+func (a agentHandler) startAgent(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
 	}
+
+	source, err := changeSource(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := a.app.StartAgent.Handle(ctx.Request().Context(), configID, source)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, status)
+}
+
+func (a agentHandler) stopAgent(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	source, err := changeSource(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.app.StopAgent.Handle(ctx.Request().Context(), configID, source); err != nil {
+		return err
+	}
+
+	return ctx.NoContent(204)
+}
+
+func (a agentHandler) getAgentStatus(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := a.app.RetrieveAgentStatus.Handle(ctx.Request().Context(), configID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, status)
+}
+
+// Returns the Akita user that the credentials of the agent config belong to.
+func (a agentHandler) getAkitaUser(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	akitaUser, err := a.app.RetrieveAkitaUser.Handle(ctx.Request().Context(), configID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, akitaUser)
+}
+
+// Returns the Akita services that the credentials of the agent config can access.
+func (a agentHandler) listAkitaServices(ctx echo.Context) error {
+	configID, err := agentConfigID(ctx)
+	if err != nil {
+		return err
+	}
+
+	services, err := a.app.ListAkitaServices.Handle(
+		ctx.Request().Context(),
+		interactor.ListAkitaServicesOptions{ConfigID: optionals.Some(configID)},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, services)
+}
+
+func (a agentHandler) reconcileAgent(ctx echo.Context) error {
+	decisions, err := a.app.ReconcileAgent.Handle(ctx.Request().Context(), interactor.ReconcileAgentOptions{})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, decisions)
+}
+
+func (a agentHandler) getAgentDecisions(ctx echo.Context) error {
+	limit := 20
+	if rawLimit := ctx.QueryParam("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return failure.Invalidf("invalid limit %q", rawLimit)
+		}
+		limit = parsedLimit
+	}
+
+	decisions, err := a.app.RetrieveAgentDecisions.Handle(ctx.Request().Context(), limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, decisions)
+}
+
+// Returns the agent config ID from the request path. Endpoints without an ID
+// in their path operate on the default config.
+func agentConfigID(ctx echo.Context) (string, error) {
+	configID := ctx.Param("id")
+	if configID == "" {
+		return agent.DefaultConfigID, nil
+	}
+
+	if err := agent.ValidateConfigID(configID); err != nil {
+		return "", err
+	}
+
+	return configID, nil
+}
+
+// Returns the source of the change made by the request. The UI identifies
+// itself with the change source header; all other clients are API clients.
+func changeSource(ctx echo.Context) (agent.ChangeSource, error) {
+	rawSource := ctx.Request().Header.Get(changeSourceHeader)
+	if rawSource == "" {
+		return agent.ChangeSourceAPI, nil
+	}
+
+	return agent.ParseChangeSource(rawSource)
 }
@@ -0,0 +1,47 @@
+package ports
+
+import (
+	"akita/app"
+	"akita/domain/container"
+	"github.com/akitasoftware/go-utils/optionals"
+	"github.com/labstack/echo"
+)
+
+type containerHandler struct {
+	app *app.App
+}
+
+func newContainerHandler(app *app.App) *containerHandler {
+	return &containerHandler{app: app}
+}
+
+// Lists containers that the agent can target. Supports the optional `status`,
+// `name`, `compose_project` and repeated `label` query parameters.
+func (c containerHandler) listContainers(ctx echo.Context) error {
+	filter := container.Filter{
+		Labels: ctx.QueryParams()["label"],
+	}
+
+	if rawStatus := ctx.QueryParam("status"); rawStatus != "" {
+		status, err := container.ParseStatus(rawStatus)
+		if err != nil {
+			return err
+		}
+		filter.Status = optionals.Some(status)
+	}
+
+	if name := ctx.QueryParam("name"); name != "" {
+		filter.Name = optionals.Some(name)
+	}
+
+	if project := ctx.QueryParam("compose_project"); project != "" {
+		filter.ComposeProject = optionals.Some(project)
+	}
+
+	containers, err := c.app.ListContainers.Handle(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, containers)
+}
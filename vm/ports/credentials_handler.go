@@ -0,0 +1,52 @@
+package ports
+
+import (
+	"akita/app"
+	"akita/app/interactor"
+	"akita/domain/user"
+	"github.com/akitasoftware/go-utils/optionals"
+	"github.com/labstack/echo"
+)
+
+type credentialsHandler struct {
+	app *app.App
+}
+
+func newCredentialsHandler(app *app.App) *credentialsHandler {
+	return &credentialsHandler{app: app}
+}
+
+// Verifies the credentials in the request body. The outcome is reported in the
+// response body, so failed verifications are not treated as errors.
+func (c credentialsHandler) verifyCredentials(ctx echo.Context) error {
+	credentials, err := user.DecodeCredentials(ctx.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	verification, err := c.app.VerifyCredentials.Handle(ctx.Request().Context(), credentials)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, verification)
+}
+
+// Returns the Akita services that the credentials in the request body can
+// access, so that credentials can be checked before they are saved.
+func (c credentialsHandler) listServices(ctx echo.Context) error {
+	credentials, err := user.DecodeCredentials(ctx.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	services, err := c.app.ListAkitaServices.Handle(
+		ctx.Request().Context(),
+		interactor.ListAkitaServicesOptions{Credentials: optionals.Some(credentials)},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, services)
+}
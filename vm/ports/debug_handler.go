@@ -0,0 +1,49 @@
+package ports
+
+import (
+	"akita/config"
+	"akita/domain/failure"
+	"akita/logging"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"strconv"
+)
+
+type debugHandler struct {
+	settingsManager *config.Manager
+	logBuffer       *logging.Buffer
+}
+
+func newDebugHandler(settingsManager *config.Manager, logBuffer *logging.Buffer) *debugHandler {
+	return &debugHandler{settingsManager: settingsManager, logBuffer: logBuffer}
+}
+
+// Returns the effective config and the layers it was loaded from, with secrets redacted.
+func (d debugHandler) getConfig(ctx echo.Context) error {
+	return ctx.JSON(200, d.settingsManager.Current().Effective())
+}
+
+// Returns the most recent log entries, oldest first, to be attached to support
+// tickets. The entries can be limited in number and filtered by minimum level.
+func (d debugHandler) getLogs(ctx echo.Context) error {
+	limit := 200
+	if rawLimit := ctx.QueryParam("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil || parsedLimit < 1 {
+			return failure.Invalidf("invalid limit %q", rawLimit).WithField("limit", "must be a positive integer")
+		}
+		limit = parsedLimit
+	}
+
+	level := logrus.TraceLevel
+	if rawLevel := ctx.QueryParam("level"); rawLevel != "" {
+		parsedLevel, err := logrus.ParseLevel(rawLevel)
+		if err != nil {
+			return failure.Invalidf("invalid level %q", rawLevel).
+				WithField("level", "must be one of debug, info, warn or error")
+		}
+		level = parsedLevel
+	}
+
+	return ctx.JSON(200, d.logBuffer.Records(limit, level))
+}
@@ -0,0 +1,168 @@
+package ports
+
+import (
+	"akita/domain/failure"
+	"akita/logging"
+	"akita/metrics"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"time"
+)
+
+const (
+	// The request and response header holding the correlation ID of a request.
+	correlationIDHeader = "X-Correlation-ID"
+	// The key of the correlation ID in the echo context.
+	correlationIDKey = "correlation_id"
+)
+
+// The correlation IDs accepted from clients. They are echoed back and logged,
+// so their length and characters are restricted.
+var correlationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// The body of every error response.
+type errorResponse struct {
+	// The message of the error. Kept for clients that predate the error envelope.
+	ErrorMessage string    `json:"errorMessage"`
+	Error        errorBody `json:"error"`
+}
+
+type errorBody struct {
+	// A stable, machine-readable code identifying the error.
+	Code failure.Code `json:"code"`
+	// A human-readable description of the error.
+	Message string `json:"message"`
+	// The fields of the request that caused the error, if any.
+	Fields []failure.FieldViolation `json:"fields,omitempty"`
+	// Whether the request may succeed if it is retried unchanged.
+	Retryable bool `json:"retryable"`
+	// Identifies the request in the logs of the extension.
+	CorrelationID string `json:"correlation_id"`
+}
+
+// Assigns each request a correlation ID, which is returned in a response header
+// and in error responses. Clients may supply their own correlation ID, which
+// is replaced by a new one unless it matches correlationIDPattern. It is also
+// carried by the request context, so that entries logged while handling the
+// request are tagged with it.
+func correlationIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		correlationID := ctx.Request().Header.Get(correlationIDHeader)
+		if !correlationIDPattern.MatchString(correlationID) {
+			correlationID = newCorrelationID()
+		}
+
+		ctx.Set(correlationIDKey, correlationID)
+		ctx.Response().Header().Set(correlationIDHeader, correlationID)
+		ctx.SetRequest(ctx.Request().WithContext(logging.WithCorrelationID(ctx.Request().Context(), correlationID)))
+
+		return next(ctx)
+	}
+}
+
+// Turns the errors of handlers into error responses. It must be the innermost
+// middleware, so that the middleware around it sees the status of every response.
+func errorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if err := next(ctx); err != nil {
+			ctx.Error(err)
+		}
+		return nil
+	}
+}
+
+// Logs every request at the debug level once it has been handled, as the UI
+// polls some endpoints. Server errors are logged by the error handler.
+func requestLogMiddleware(routes routeSet, logger *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+
+			err := next(ctx)
+
+			logger.WithContext(ctx.Request().Context()).WithFields(logrus.Fields{
+				"method":     ctx.Request().Method,
+				"route":      routes.of(ctx),
+				"status":     ctx.Response().Status,
+				"latency_ms": time.Since(start).Milliseconds(),
+			}).Debugf("%s %s", ctx.Request().Method, ctx.Request().URL.Path)
+
+			return err
+		}
+	}
+}
+
+// Returns the handler that turns errors into error responses. Server errors
+// are logged along with the underlying error.
+func newErrorHandler(routes routeSet, logger *logrus.Logger) echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		if ctx.Response().Committed {
+			return
+		}
+
+		status, fail := describeError(err)
+		correlationID, _ := ctx.Get(correlationIDKey).(string)
+		metrics.HTTPErrors.WithLabelValues(routes.of(ctx), string(fail.Code)).Inc()
+		if status >= 500 {
+			logger.WithContext(ctx.Request().Context()).
+				WithField("code", fail.Code).
+				Errorf("Failed to handle request: %v", err)
+		}
+
+		_ = ctx.JSON(status, errorResponse{
+			ErrorMessage: err.Error(),
+			Error: errorBody{
+				Code:          fail.Code,
+				Message:       fail.Message,
+				Fields:        fail.Fields,
+				Retryable:     fail.Retryable,
+				CorrelationID: correlationID,
+			},
+		})
+	}
+}
+
+// Returns the HTTP status and the failure describing the given error.
+func describeError(err error) (int, *failure.Error) {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		// Errors raised by echo itself, such as unknown routes.
+		message := fmt.Sprint(httpErr.Message)
+		switch {
+		case httpErr.Code == 404:
+			return httpErr.Code, failure.NotFoundf("%s", message)
+		case httpErr.Code == 401:
+			return httpErr.Code, failure.Unauthorizedf("%s", message)
+		case httpErr.Code >= 400 && httpErr.Code < 500:
+			return httpErr.Code, failure.Invalidf("%s", message)
+		}
+	}
+
+	fail := failure.From(err)
+	switch fail.Kind() {
+	case failure.ErrInvalid:
+		return 400, fail
+	case failure.ErrNotFound:
+		return 404, fail
+	case failure.ErrUnprocessable:
+		return 422, fail
+	case failure.ErrUnauthorized:
+		return 401, fail
+	case failure.ErrUnavailable:
+		return 503, fail
+	default:
+		return 500, fail
+	}
+}
+
+// Returns a random correlation ID.
+func newCorrelationID() string {
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,173 @@
+package ports
+
+import (
+	"akita/domain/failure"
+	"akita/logging"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		handlerErr error
+		wantStatus int
+		wantCode   failure.Code
+	}{
+		{name: "success", path: "/things", wantStatus: 200},
+		{
+			name:       "invalid request",
+			path:       "/things",
+			handlerErr: failure.Invalidf("name is required").WithCode(failure.CodeInvalidAgentConfig),
+			wantStatus: 400,
+			wantCode:   failure.CodeInvalidAgentConfig,
+		},
+		{
+			name:       "unavailable dependency",
+			path:       "/things",
+			handlerErr: failure.Unavailablef("the Akita API is down").WithCode(failure.CodeAkitaAPIUnavailable),
+			wantStatus: 503,
+			wantCode:   failure.CodeAkitaAPIUnavailable,
+		},
+		{
+			name:       "unexpected error",
+			path:       "/things",
+			handlerErr: errors.New("boom"),
+			wantStatus: 500,
+			wantCode:   failure.CodeInternal,
+		},
+		{name: "unknown route", path: "/unknown", wantStatus: 404, wantCode: failure.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			router := echo.New()
+			router.GET("/things", func(ctx echo.Context) error {
+				if tt.handlerErr != nil {
+					return tt.handlerErr
+				}
+				return ctx.NoContent(200)
+			})
+			routes := newRouteSet(router.Routes())
+			router.HTTPErrorHandler = newErrorHandler(routes, logger)
+			// Errors must be handled once, by the innermost middleware.
+			router.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(ctx echo.Context) error {
+					if err := next(ctx); err != nil {
+						t.Errorf("error escaped the middleware: %v", err)
+					}
+					return nil
+				}
+			})
+			router.Use(correlationIDMiddleware)
+			router.Use(requestLogMiddleware(routes, logger))
+			router.Use(metricsMiddleware(routes))
+			router.Use(errorMiddleware)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var response errorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode error response %q: %v", recorder.Body.String(), err)
+			}
+			if response.Error.Code != tt.wantCode {
+				t.Errorf("error code = %s, want %s", response.Error.Code, tt.wantCode)
+			}
+			if response.Error.CorrelationID == "" {
+				t.Error("error response has no correlation ID")
+			}
+		})
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   failure.Code
+	}{
+		{name: "invalid", err: failure.Invalidf("bad"), wantStatus: 400, wantCode: failure.CodeInvalid},
+		{name: "not found", err: failure.NotFoundf("missing"), wantStatus: 404, wantCode: failure.CodeNotFound},
+		{
+			name:       "unprocessable",
+			err:        failure.Unprocessablef("not running").WithCode(failure.CodeTargetNotRunning),
+			wantStatus: 422,
+			wantCode:   failure.CodeTargetNotRunning,
+		},
+		{name: "unauthorized", err: failure.Unauthorizedf("denied"), wantStatus: 401, wantCode: failure.CodeUnauthorized},
+		{name: "unavailable", err: failure.Unavailablef("down"), wantStatus: 503, wantCode: failure.CodeUnavailable},
+		{name: "internal", err: errors.New("boom"), wantStatus: 500, wantCode: failure.CodeInternal},
+		{name: "echo not found", err: echo.ErrNotFound, wantStatus: 404, wantCode: failure.CodeNotFound},
+		{name: "echo bad request", err: echo.NewHTTPError(400, "malformed"), wantStatus: 400, wantCode: failure.CodeInvalid},
+		{name: "echo unauthorized", err: echo.ErrUnauthorized, wantStatus: 401, wantCode: failure.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, fail := describeError(tt.err)
+			if status != tt.wantStatus || fail.Code != tt.wantCode {
+				t.Errorf("describeError() = %d %s, want %d %s", status, fail.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCorrelationIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "supplied", header: "req-42.retry_1", wantKept: true},
+		{name: "longest accepted", header: strings.Repeat("a", 128), wantKept: true},
+		{name: "missing"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+		{name: "invalid characters", header: "id\twith spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loggedID string
+			handler := correlationIDMiddleware(func(ctx echo.Context) error {
+				loggedID, _ = logging.CorrelationID(ctx.Request().Context())
+				return ctx.NoContent(200)
+			})
+
+			request := httptest.NewRequest("GET", "/things", nil)
+			request.Header.Set(correlationIDHeader, tt.header)
+			recorder := httptest.NewRecorder()
+			if err := handler(echo.New().NewContext(request, recorder)); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			correlationID := recorder.Header().Get(correlationIDHeader)
+			if kept := correlationID == tt.header; kept != tt.wantKept {
+				t.Errorf("correlation ID = %q, want supplied ID kept %v", correlationID, tt.wantKept)
+			}
+			if !correlationIDPattern.MatchString(correlationID) {
+				t.Errorf("correlation ID = %q doesn't match %s", correlationID, correlationIDPattern)
+			}
+			if loggedID != correlationID {
+				t.Errorf("logged correlation ID = %q, want %q", loggedID, correlationID)
+			}
+		})
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"akita/app"
 	"akita/app/interactor"
 	"akita/domain/event"
+	"akita/domain/failure"
 	"github.com/akitasoftware/go-utils/optionals"
 	"github.com/labstack/echo"
+	"strconv"
 )
 
 type eventHandler struct {
@@ -33,3 +35,34 @@ func (e eventHandler) postEvent(ctx echo.Context) error {
 
 	return ctx.NoContent(204)
 }
+
+// Lists the analytics events recorded by the local analytics sink. Supports
+// the optional `limit` query parameter.
+func (e eventHandler) listRecordedEvents(ctx echo.Context) error {
+	limit := 100
+	if rawLimit := ctx.QueryParam("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return failure.Invalidf("invalid limit %q", rawLimit)
+		}
+		limit = parsedLimit
+	}
+
+	events, err := e.app.ListRecordedAnalyticsEvents.Handle(ctx.Request().Context(), limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, events)
+}
+
+// Returns the number of analytics events waiting to be delivered and the
+// outcome of the last delivery.
+func (e eventHandler) getQueueStatus(ctx echo.Context) error {
+	status, err := e.app.RetrieveAnalyticsQueueStatus.Handle(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, status)
+}
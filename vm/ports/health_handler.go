@@ -0,0 +1,54 @@
+package ports
+
+import (
+	"akita/app"
+	"akita/domain/health"
+	"github.com/labstack/echo"
+)
+
+type healthHandler struct {
+	app *app.App
+}
+
+func newHealthHandler(app *app.App) *healthHandler {
+	return &healthHandler{app: app}
+}
+
+// Reports whether calls to the Akita API currently fail fast. Responds with
+// 503 while they do, so that the endpoint can be polled by probes.
+func (h healthHandler) getAkitaAPIHealth(ctx echo.Context) error {
+	health := h.app.RetrieveAkitaAPIHealth.Handle()
+	if !health.Healthy {
+		return ctx.JSON(503, health)
+	}
+
+	return ctx.JSON(200, health)
+}
+
+// Reports the status of the background workers. Responds with 503 if any of
+// them isn't running.
+func (h healthHandler) getWorkerHealth(ctx echo.Context) error {
+	health := h.app.RetrieveWorkerHealth.Handle()
+	if !health.Healthy {
+		return ctx.JSON(503, health)
+	}
+
+	return ctx.JSON(200, health)
+}
+
+// Reports that the backend is alive, without checking its dependencies.
+func (h healthHandler) getLiveness(ctx echo.Context) error {
+	return ctx.JSON(200, h.app.RetrieveLiveness.Handle())
+}
+
+// Checks the dependencies of the backend and reports the latency and last
+// error of each. Responds with 503 if a required dependency is unreachable,
+// so that the endpoint can be used as a container healthcheck.
+func (h healthHandler) getReadiness(ctx echo.Context) error {
+	readiness := h.app.RetrieveReadiness.Handle(ctx.Request().Context())
+	if readiness.Status == health.StatusDown {
+		return ctx.JSON(503, readiness)
+	}
+
+	return ctx.JSON(200, readiness)
+}
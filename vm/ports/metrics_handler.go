@@ -0,0 +1,57 @@
+package ports
+
+import (
+	"akita/metrics"
+	"github.com/labstack/echo"
+	"strconv"
+	"time"
+)
+
+// The paths of the routes of the router, e.g. /agents/:id/config.
+type routeSet map[string]bool
+
+func newRouteSet(routes []*echo.Route) routeSet {
+	result := routeSet{}
+	for _, route := range routes {
+		result[route.Path] = true
+	}
+	return result
+}
+
+// Returns the route that handled the request, so that requests for different
+// IDs are counted together. Requests that matched no route are counted
+// together as well, as echo reports their raw path.
+func (r routeSet) of(ctx echo.Context) string {
+	if path := ctx.Path(); r[path] {
+		return path
+	}
+	return "unmatched"
+}
+
+// Records the latency and status of every request. Requests answered with an
+// error status are counted with the error outcome.
+func metricsMiddleware(routes routeSet) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+
+			err := next(ctx)
+
+			route := routes.of(ctx)
+			method := ctx.Request().Method
+			status := ctx.Response().Status
+			outcome := metrics.OutcomeSuccess
+			if err != nil || status >= 400 {
+				outcome = metrics.OutcomeError
+			}
+			metrics.HTTPRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(route, method, outcome).
+				Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// Serves the metrics of the backend in the Prometheus exposition format.
+var getMetrics = echo.WrapHandler(metrics.Handler())
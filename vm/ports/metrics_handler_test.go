@@ -0,0 +1,122 @@
+package ports
+
+import (
+	"akita/domain/failure"
+	"akita/metrics"
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Returns a router labelling requests by route like the one of the backend,
+// with a route that succeeds and one that fails.
+func newTestMetricsRouter() *echo.Echo {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	router := echo.New()
+	router.GET("/things/:id", func(ctx echo.Context) error {
+		return ctx.NoContent(200)
+	})
+	router.POST("/things/:id", func(ctx echo.Context) error {
+		return failure.NotFoundf("no thing found with id %s", ctx.Param("id"))
+	})
+	router.GET("/metrics", getMetrics)
+
+	routes := newRouteSet(router.Routes())
+	router.HTTPErrorHandler = newErrorHandler(routes, logger)
+	router.Use(metricsMiddleware(routes))
+	router.Use(errorMiddleware)
+	return router
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantRoute   string
+		wantStatus  string
+		wantOutcome string
+	}{
+		{
+			name:        "matched route",
+			method:      "GET",
+			path:        "/things/1",
+			wantRoute:   "/things/:id",
+			wantStatus:  "200",
+			wantOutcome: metrics.OutcomeSuccess,
+		},
+		{
+			name:        "failed request",
+			method:      "POST",
+			path:        "/things/2",
+			wantRoute:   "/things/:id",
+			wantStatus:  "404",
+			wantOutcome: metrics.OutcomeError,
+		},
+		{
+			name:        "unmatched route",
+			method:      "GET",
+			path:        "/unknown/3",
+			wantRoute:   "unmatched",
+			wantStatus:  "404",
+			wantOutcome: metrics.OutcomeError,
+		},
+	}
+
+	router := newTestMetricsRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := metrics.HTTPRequests.WithLabelValues(tt.wantRoute, tt.method, tt.wantStatus)
+			durations := metrics.HTTPRequestDuration.WithLabelValues(tt.wantRoute, tt.method, tt.wantOutcome)
+			requestsBefore, durationsBefore := testutil.ToFloat64(requests), sampleCount(t, durations)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if got := testutil.ToFloat64(requests) - requestsBefore; got != 1 {
+				t.Errorf("requests counted with route %s and status %s = %v, want 1", tt.wantRoute, tt.wantStatus, got)
+			}
+			if got := sampleCount(t, durations) - durationsBefore; got != 1 {
+				t.Errorf("durations observed with route %s and outcome %s = %d, want 1", tt.wantRoute, tt.wantOutcome, got)
+			}
+		})
+	}
+}
+
+// Returns the number of observations of the given histogram.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	if err := observer.(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestGetMetrics(t *testing.T) {
+	router := newTestMetricsRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/things/1", nil))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	for _, want := range []string{
+		`akita_ext_http_requests_total{endpoint="/things/:id",method="GET",status="200"}`,
+		"# TYPE akita_ext_http_request_duration_seconds histogram",
+		"# TYPE go_goroutines gauge",
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("GET /metrics body doesn't contain %s", want)
+		}
+	}
+}
@@ -2,28 +2,124 @@ package ports
 
 import (
 	"akita/app"
+	"akita/config"
+	"akita/logging"
 	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
 )
 
-func NewRouter(app *app.App) *echo.Echo {
+func NewRouter(
+	app *app.App,
+	settingsManager *config.Manager,
+	logger *logrus.Logger,
+	logBuffer *logging.Buffer,
+) *echo.Echo {
 	agentHandler := newAgentHandler(app)
 	eventHandler := newEventHandler(app)
+	containerHandler := newContainerHandler(app)
+	credentialsHandler := newCredentialsHandler(app)
+	healthHandler := newHealthHandler(app)
+	settingsHandler := newSettingsHandler(app, settingsManager)
+	debugHandler := newDebugHandler(settingsManager, logBuffer)
 
 	router := echo.New()
 	router.HideBanner = true
-	router.HTTPErrorHandler = handleError
 
 	// Config Endpoints
 	{
 		router.GET("/agents/config", agentHandler.getAgentConfig)
 		router.POST("/agents/config", agentHandler.createAgentConfig)
 		router.DELETE("/agents/config", agentHandler.removeAgentConfig)
+		router.POST("/agents/config/reveal", agentHandler.revealAgentConfig)
+		router.POST("/agents/config/validate", agentHandler.validateAgentConfig)
+		router.GET("/agents/config/history", agentHandler.getAgentConfigHistory)
+		router.POST("/agents/config/rollback/:version", agentHandler.rollbackAgentConfig)
+	}
+
+	// Agent Lifecycle Endpoints
+	{
+		router.POST("/agents/start", agentHandler.startAgent)
+		router.POST("/agents/stop", agentHandler.stopAgent)
+		router.GET("/agents/status", agentHandler.getAgentStatus)
+		router.POST("/agents/reconcile", agentHandler.reconcileAgent)
+		router.GET("/agents/reconciliations", agentHandler.getAgentDecisions)
+	}
+
+	// Akita Account Endpoints
+	{
+		router.GET("/agents/user", agentHandler.getAkitaUser)
+		router.GET("/agents/services", agentHandler.listAkitaServices)
+		router.GET("/agents/:id/user", agentHandler.getAkitaUser)
+		router.GET("/agents/:id/services", agentHandler.listAkitaServices)
+	}
+
+	// Per-Config Agent Endpoints
+	{
+		router.GET("/agents", agentHandler.listAgentConfigs)
+		router.GET("/agents/:id/config", agentHandler.getAgentConfig)
+		router.POST("/agents/:id/config", agentHandler.createAgentConfig)
+		router.DELETE("/agents/:id/config", agentHandler.removeAgentConfig)
+		router.POST("/agents/:id/config/reveal", agentHandler.revealAgentConfig)
+		router.POST("/agents/:id/config/validate", agentHandler.validateAgentConfig)
+		router.GET("/agents/:id/config/history", agentHandler.getAgentConfigHistory)
+		router.POST("/agents/:id/config/rollback/:version", agentHandler.rollbackAgentConfig)
+		router.POST("/agents/:id/start", agentHandler.startAgent)
+		router.POST("/agents/:id/stop", agentHandler.stopAgent)
+		router.GET("/agents/:id/status", agentHandler.getAgentStatus)
+	}
+
+	// Container Endpoints
+	{
+		router.GET("/containers", containerHandler.listContainers)
+	}
+
+	// Credentials Endpoints
+	{
+		router.POST("/credentials/verify", credentialsHandler.verifyCredentials)
+		router.POST("/credentials/services", credentialsHandler.listServices)
+	}
+
+	// Health Endpoints
+	{
+		router.GET("/health/akita-api", healthHandler.getAkitaAPIHealth)
+		router.GET("/health/workers", healthHandler.getWorkerHealth)
+		router.GET("/healthz", healthHandler.getLiveness)
+		router.GET("/readyz", healthHandler.getReadiness)
 	}
 
 	// Analytics Endpoints
 	{
 		router.POST("/analytics/event", eventHandler.postEvent)
+		router.GET("/analytics/queue", eventHandler.getQueueStatus)
+		router.GET("/analytics/events", eventHandler.listRecordedEvents)
+	}
+
+	// Settings Endpoints
+	{
+		router.GET("/settings", settingsHandler.getSettings)
+		router.PUT("/settings", settingsHandler.updateSettings)
+		router.GET("/settings/telemetry", settingsHandler.getTelemetrySettings)
+		router.PUT("/settings/telemetry", settingsHandler.saveTelemetrySettings)
 	}
 
+	// Debug Endpoints
+	{
+		router.GET("/debug/config", debugHandler.getConfig)
+		router.GET("/debug/logs", debugHandler.getLogs)
+	}
+
+	// Metrics Endpoints
+	{
+		router.GET("/metrics", getMetrics)
+	}
+
+	// Requests are labelled by route, so the routes must all be registered by now.
+	routes := newRouteSet(router.Routes())
+	router.HTTPErrorHandler = newErrorHandler(routes, logger)
+	router.Use(correlationIDMiddleware)
+	router.Use(requestLogMiddleware(routes, logger))
+	router.Use(metricsMiddleware(routes))
+	router.Use(errorMiddleware)
+
 	return router
 }
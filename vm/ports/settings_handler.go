@@ -0,0 +1,62 @@
+package ports
+
+import (
+	"akita/app"
+	"akita/config"
+	"akita/domain/failure"
+	"akita/domain/settings"
+	"github.com/labstack/echo"
+)
+
+type settingsHandler struct {
+	app             *app.App
+	settingsManager *config.Manager
+}
+
+func newSettingsHandler(app *app.App, settingsManager *config.Manager) *settingsHandler {
+	return &settingsHandler{app: app, settingsManager: settingsManager}
+}
+
+// Returns the settings that can be changed at runtime.
+func (s settingsHandler) getSettings(ctx echo.Context) error {
+	return ctx.JSON(200, s.settingsManager.Current().ReloadableSettings())
+}
+
+// Changes the given settings at runtime, without restarting the extension.
+// The changes are kept until the extension restarts.
+func (s settingsHandler) updateSettings(ctx echo.Context) error {
+	settings, err := config.DecodeSettings(ctx.Request().Body)
+	if err != nil {
+		return failure.Invalidf("failed to decode settings: %v", err)
+	}
+
+	updatedConfig, err := s.settingsManager.Update(settings)
+	if err != nil {
+		return failure.Invalidf("invalid settings: %v", err)
+	}
+
+	return ctx.JSON(200, updatedConfig.ReloadableSettings())
+}
+
+func (s settingsHandler) getTelemetrySettings(ctx echo.Context) error {
+	telemetry, err := s.app.RetrieveTelemetrySettings.Handle(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, telemetry)
+}
+
+// Records whether the user consents to sending analytics.
+func (s settingsHandler) saveTelemetrySettings(ctx echo.Context) error {
+	telemetry, err := settings.DecodeTelemetry(ctx.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	if err := s.app.SaveTelemetrySettings.Handle(ctx.Request().Context(), telemetry); err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, telemetry)
+}